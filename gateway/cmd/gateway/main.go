@@ -12,6 +12,8 @@ import (
 	"github.com/Skotchmaster/online_shop/gateway/internal/config"
 	"github.com/Skotchmaster/online_shop/gateway/internal/httpserver"
 	"github.com/Skotchmaster/online_shop/pkg/middleware/csrf"
+	"github.com/Skotchmaster/online_shop/pkg/revocation"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
 	"github.com/labstack/echo/v4"
 )
 
@@ -23,7 +25,17 @@ func main() {
 	e.Server.WriteTimeout = 15 * time.Second
 	e.Server.ReadHeaderTimeout = 3 * time.Second
 	csrf := csrf.DefaultConfig()
-	csrf.SkipPaths = []string{"/health/live", "/health/ready", "/api/v1/auth/login", "/api/v1/auth/register", "/api/v1/auth/refresh"}
+	csrf.SkipPaths = []string{"/health/live", "/health/ready", "/api/v1/auth/login", "/api/v1/auth/login/2fa", "/api/v1/auth/register", "/api/v1/auth/refresh"}
+
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+
+	verifier := tokens.NewVerifier(tokens.NewJWKSClient(cfg.JWKSURL, cfg.JWKSCacheTTL), cfg.JWTSecret)
+	follower, err := revocation.Attach(eventsCtx, verifier, cfg.KafkaBrokers)
+	if err != nil {
+		log.Fatalf("token revocation: %v", err)
+	}
+	defer follower.Close()
 
 	if err := httpserver.Register(e, &httpserver.Deps{
 		AuthURL:    cfg.AuthURL,
@@ -31,7 +43,7 @@ func main() {
 		CartURL:    cfg.CartURL,
 		OrderURL:   cfg.OrderURL,
 		CSRFConfig: csrf,
-		JWTSecret:  cfg.JWTSecret,
+		Verifier:   verifier,
 	}); err != nil {
 		log.Fatal(err)
 	}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"time"
+
+	pkgconfig "github.com/Skotchmaster/online_shop/pkg/config"
 )
 
 type Config struct {
@@ -12,7 +15,12 @@ type Config struct {
 	CartURL    string
 	OrderURL   string
 	SearchURL  string
-	JWTSecret  []byte
+	// JWTSecret still accepts HS256 access tokens; empty rejects them.
+	JWTSecret    []byte
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	// KafkaBrokers delivers access token revocations from auth.
+	KafkaBrokers []string
 }
 
 func getenv(k, def string) string {
@@ -36,7 +44,10 @@ func Load() *Config {
 		CatalogURL: must(os.Getenv("CATALOG_URL"), "CATALOG_URL"),
 		CartURL:    must(os.Getenv("CART_URL"), "CART_URL"),
 		OrderURL:   must(os.Getenv("ORDER_URL"), "ORDER_URL"),
-		JWTSecret:  []byte(must(os.Getenv("JWT_SECRET"), "JWT_SECRET")),
+		JWTSecret:  []byte(os.Getenv("JWT_SECRET")),
 	}
+	cfg.JWKSURL = getenv("JWKS_URL", pkgconfig.JWKSURL(cfg.AuthURL))
+	cfg.JWKSCacheTTL = pkgconfig.EnvDurationDefault("JWKS_CACHE_TTL", 5*time.Minute)
+	cfg.KafkaBrokers = pkgconfig.CSV(os.Getenv("KAFKA_BROKERS"))
 	return cfg
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Skotchmaster/online_shop/gateway/internal/middleware"
 	"github.com/Skotchmaster/online_shop/pkg/middleware/csrf"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
 	"github.com/labstack/echo/v4"
 )
 
@@ -15,7 +16,7 @@ type Deps struct {
 	OrderURL   string
 	CSRFConfig csrf.Config
 
-	JWTSecret []byte
+	Verifier *tokens.Verifier
 }
 
 func Register(e *echo.Echo, d *Deps) error {
@@ -51,13 +52,19 @@ func Register(e *echo.Echo, d *Deps) error {
 	e.Any("/api/v1/auth/*", authProxy)
 	e.Match([]string{http.MethodGet}, "/api/v1/catalog/*", catalogProxy)
 
+	// Guests may use the cart; the cart service tells them apart by cookie.
+	optionalAuth := middleware.Optional(d.Verifier)
+	e.Any("/api/v1/cart", cartProxy, optionalAuth)
+	e.Any("/api/v1/cart/*", cartProxy, optionalAuth)
+	e.GET("/api/v1/wishlists/shared/:token", cartProxy)
+
 	api := e.Group("/api/v1")
-	api.Use(middleware.Middleware(d.JWTSecret))
+	api.Use(middleware.Middleware(d.Verifier))
 
 	api.Match([]string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}, "/catalog", catalogProxy)
 	api.Match([]string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}, "/catalog/*", catalogProxy)
-	api.Any("/cart", cartProxy)
-	api.Any("/cart/*", cartProxy)
+	api.Any("/wishlists", cartProxy)
+	api.Any("/wishlists/*", cartProxy)
 	api.Any("/orders", orderProxy)
 	api.Any("/orders/*", orderProxy)
 
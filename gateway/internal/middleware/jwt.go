@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"net/http"
-	"slices"
 
 	"github.com/Skotchmaster/online_shop/pkg/tokens"
 	"github.com/labstack/echo/v4"
@@ -13,14 +12,14 @@ const (
 	CtxRole   = "role"
 )
 
-func Middleware(secret []byte) echo.MiddlewareFunc {
+func Middleware(verifier *tokens.Verifier) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			accessCookie, err := c.Cookie("accessToken")
 			if err != nil || accessCookie.Value == "" {
 				return echo.NewHTTPError(http.StatusUnauthorized, "missing access token")
 			}
-			claims, err := tokens.AccessClaimsFromToken(accessCookie.Value, secret)
+			claims, err := verifier.AccessClaims(c.Request().Context(), accessCookie.Value)
 			if err != nil || claims == nil {
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired token")
 			}
@@ -36,17 +35,17 @@ func Middleware(secret []byte) echo.MiddlewareFunc {
 	}
 }
 
-func RequireRole(required []string) echo.MiddlewareFunc {
+// Optional passes anonymous requests through and validates the token like
+// Middleware when one is sent.
+func Optional(verifier *tokens.Verifier) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withAuth := Middleware(verifier)(next)
 		return func(c echo.Context) error {
-			role, _ := c.Get(CtxRole).(string)
-			if role == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or missing role")
-			}
-			if !slices.Contains(required, role) {
-				return echo.NewHTTPError(http.StatusForbidden, "you don't have enough rights to see this page")
+			accessCookie, err := c.Cookie("accessToken")
+			if err != nil || accessCookie.Value == "" {
+				return next(c)
 			}
-			return next(c)
+			return withAuth(c)
 		}
 	}
-}
\ No newline at end of file
+}
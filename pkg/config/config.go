@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -18,11 +19,17 @@ type Config struct {
 
 	AuthHTTPURL  string
 	AuthGRPCAddr string
+	// JWKSURL serves the keys access tokens are signed with.
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
 
 	KafkaBrokers []string
+
+	InternalAPIToken string
 }
 
 func Load() Config {
+	authURL := os.Getenv("AUTH_URL")
 	return Config{
 		ServiceName: EnvDefault("SERVICE_NAME", ""),
 
@@ -33,11 +40,23 @@ func Load() Config {
 		JWTAccessSecret:  []byte(os.Getenv("JWT_SECRET")),
 		JWTRefreshSecret: []byte(os.Getenv("JWT_REFRESH_SECRET")),
 
-		AuthHTTPURL:  os.Getenv("AUTH_URL"),
+		AuthHTTPURL:  authURL,
 		AuthGRPCAddr: os.Getenv("AUTH_GRPC_ADDR"),
+		JWKSURL:      EnvDefault("JWKS_URL", JWKSURL(authURL)),
+		JWKSCacheTTL: EnvDurationDefault("JWKS_CACHE_TTL", 5*time.Minute),
 
 		KafkaBrokers: CSV(os.Getenv("KAFKA_BROKERS")),
+
+		InternalAPIToken: os.Getenv("INTERNAL_API_TOKEN"),
+	}
+}
+
+// JWKSURL is where the auth service at authURL publishes its signing keys.
+func JWKSURL(authURL string) string {
+	if authURL == "" {
+		return ""
 	}
+	return strings.TrimRight(authURL, "/") + "/.well-known/jwks.json"
 }
 
 func CSV(v string) []string {
@@ -73,3 +92,11 @@ func EnvIntDefault(key string, def int) int {
 	}
 	return n
 }
+
+func EnvDurationDefault(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
@@ -24,15 +24,29 @@ func configurePool(sqlDB *sql.DB) {
 	sqlDB.SetConnMaxIdleTime(connMaxIdleTime)
 }
 
-func Open(ctx context.Context, dsn string) (*gorm.DB, error) {
+// Option adjusts the gorm config for a single service.
+type Option func(*gorm.Config)
+
+// TranslateErrors makes gorm report driver errors as gorm.ErrDuplicatedKey
+// and friends.
+func TranslateErrors(c *gorm.Config) {
+	c.TranslateError = true
+}
+
+func Open(ctx context.Context, dsn string, opts ...Option) (*gorm.DB, error) {
 	if dsn == "" {
 		return nil, fmt.Errorf("DATABASE_URL is empty")
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	cfg := &gorm.Config{
 		PrepareStmt: true,
 		NowFunc:     func() time.Time { return time.Now().UTC() },
-	})
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), cfg)
 	if err != nil {
 		return nil, fmt.Errorf("подключение к БД: %w", err)
 	}
@@ -0,0 +1,29 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuthEventsTopic carries AuthEvent messages keyed by user id.
+const AuthEventsTopic = "auth.events"
+
+const (
+	AccessTokenRevoked = "access_token.revoked"
+	UserTokensRevoked  = "user.tokens_revoked"
+)
+
+// AuthEvent tells other services that access tokens must no longer be
+// accepted. AccessTokenRevoked denies the token with JTI until ExpiresAt;
+// UserTokensRevoked denies every token of UserID issued before NotBefore.
+type AuthEvent struct {
+	EventID    uuid.UUID `json:"event_id"`
+	Type       string    `json:"type"`
+	UserID     uuid.UUID `json:"user_id"`
+	JTI        string    `json:"jti,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`
+	NotBefore  time.Time `json:"not_before,omitzero"`
+	Reason     string    `json:"reason,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
@@ -0,0 +1,28 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductsTopic carries ProductEvent messages keyed by product id.
+const ProductsTopic = "catalog.products"
+
+const (
+	ProductUpdated = "product.updated"
+	ProductDeleted = "product.deleted"
+)
+
+// ProductEvent is the state of a product after a catalog write. Available is
+// false when the product is hidden from the storefront (draft, archived or
+// outside its publish window); Price is the effective current price.
+type ProductEvent struct {
+	EventID    uuid.UUID `json:"event_id"`
+	Type       string    `json:"type"`
+	ProductID  uuid.UUID `json:"product_id"`
+	Price      int64     `json:"price"`
+	Count      uint      `json:"count"`
+	Available  bool      `json:"available"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
@@ -13,13 +13,13 @@ import (
 )
 
 type AutoRefreshMiddleware struct {
-	JWTSecret  []byte
+	Verifier   *tokens.Verifier
 	AuthClient *authclient.Client
 }
 
-func NewAutoRefreshMiddleware(secret []byte, authClient *authclient.Client) *AutoRefreshMiddleware {
+func NewAutoRefreshMiddleware(verifier *tokens.Verifier, authClient *authclient.Client) *AutoRefreshMiddleware {
 	return &AutoRefreshMiddleware{
-		JWTSecret:  secret,
+		Verifier:   verifier,
 		AuthClient: authClient,
 	}
 }
@@ -30,13 +30,29 @@ func (m *AutoRefreshMiddleware) RequireAuth(next echo.HandlerFunc) echo.HandlerF
 	return m.requireAuthWithValidator(next, nil)
 }
 
-func (m *AutoRefreshMiddleware) RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
-	return m.requireAuthWithValidator(next, func(claims *tokens.AccessClaims) error {
-		if claims.Role != "admin" {
-			return echo.NewHTTPError(http.StatusForbidden, "admin access required")
+// OptionalAuth lets anonymous requests through without a user in the context.
+// Once an access cookie is present it is handled exactly like RequireAuth.
+func (m *AutoRefreshMiddleware) OptionalAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	withAuth := m.requireAuthWithValidator(next, nil)
+	return func(c echo.Context) error {
+		accessCookie, err := c.Cookie("accessToken")
+		if err != nil || accessCookie.Value == "" {
+			return next(c)
 		}
-		return nil
-	})
+		return withAuth(c)
+	}
+}
+
+// RequirePermission lets through users whose role grants permission.
+func (m *AutoRefreshMiddleware) RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return m.requireAuthWithValidator(next, func(claims *tokens.AccessClaims) error {
+			if !claims.HasScope(permission) {
+				return echo.NewHTTPError(http.StatusForbidden, "permission "+permission+" required")
+			}
+			return nil
+		})
+	}
 }
 
 func (m *AutoRefreshMiddleware) requireAuthWithValidator(next echo.HandlerFunc, validator ValidatorFunc) echo.HandlerFunc {
@@ -46,7 +62,7 @@ func (m *AutoRefreshMiddleware) requireAuthWithValidator(next echo.HandlerFunc,
 			return echo.NewHTTPError(http.StatusUnauthorized, "missing access token")
 		}
 
-		claims, err := tokens.AccessClaimsFromToken(accessCookie.Value, m.JWTSecret)
+		claims, err := m.Verifier.AccessClaims(c.Request().Context(), accessCookie.Value)
 
 		if err == nil && claims != nil {
 			if validator != nil {
@@ -55,11 +71,13 @@ func (m *AutoRefreshMiddleware) requireAuthWithValidator(next echo.HandlerFunc,
 				}
 			}
 			
-			setUserContext(c, claims)
+			setUserContext(c, claims, accessCookie.Value)
 			return next(c)
 		}
 
-		if !errors.Is(err, jwt.ErrTokenExpired) {
+		// A revoked token may belong to a session that is still valid, e.g.
+		// after a role change, so it is refreshed like an expired one.
+		if !errors.Is(err, jwt.ErrTokenExpired) && !errors.Is(err, tokens.ErrTokenRevoked) {
 			clearAuthCookies(c)
 			return echo.NewHTTPError(http.StatusUnauthorized, "invalid access token")
 		}
@@ -94,7 +112,7 @@ func (m *AutoRefreshMiddleware) requireAuthWithValidator(next echo.HandlerFunc,
 			time.Unix(refreshResp.RefreshExp, 0),
 		))
 
-		newClaims, pErr := tokens.AccessClaimsFromToken(refreshResp.AccessToken, m.JWTSecret)
+		newClaims, pErr := m.Verifier.AccessClaims(ctx, refreshResp.AccessToken)
 		if pErr != nil || newClaims == nil {
 			clearAuthCookies(c)
 			return echo.NewHTTPError(http.StatusUnauthorized, "new access token invalid")
@@ -107,7 +125,7 @@ func (m *AutoRefreshMiddleware) requireAuthWithValidator(next echo.HandlerFunc,
 			}
 		}
 		
-		setUserContext(c, newClaims)
+		setUserContext(c, newClaims, refreshResp.AccessToken)
 
 		return next(c)
 	}
@@ -118,7 +136,11 @@ func clearAuthCookies(c echo.Context) {
 	c.SetCookie(jwthelp.DeleteCookie("refreshToken", "/"))
 }
 
-func setUserContext(c echo.Context, claims *tokens.AccessClaims) {
+func setUserContext(c echo.Context, claims *tokens.AccessClaims, accessToken string) {
 	c.Set("user_id", claims.Subject)
 	c.Set("role", claims.Role)
+	c.Set("permissions", claims.Scopes)
+	// Handlers calling other services on behalf of the user forward this token,
+	// which is the refreshed one if the cookie had expired.
+	c.Set("access_token", accessToken)
 }
\ No newline at end of file
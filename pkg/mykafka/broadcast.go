@@ -0,0 +1,160 @@
+package mykafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// BroadcastConsumer reads every partition of a topic without a consumer
+// group, so each instance of a service sees every message. On start it
+// rewinds to the messages of the last Since, which lets a fresh instance
+// rebuild short-lived state such as a token denylist.
+type BroadcastConsumer struct {
+	brokers []string
+	topic   string
+	since   time.Duration
+
+	mu      sync.Mutex
+	readers []*kafka.Reader
+}
+
+func NewBroadcastConsumer(brokers []string, topic string, since time.Duration) (*BroadcastConsumer, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no brokers provided")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+	return &BroadcastConsumer{brokers: brokers, topic: topic, since: since}, nil
+}
+
+// Run passes messages to handle until ctx is cancelled. Failed messages are
+// logged and skipped. While the topic cannot be found, or a partition cannot
+// be read, Run keeps retrying with backoff.
+func (c *BroadcastConsumer) Run(ctx context.Context, handle Handler) error {
+	partitions, err := c.partitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   c.brokers,
+			Topic:     c.topic,
+			Partition: p.ID,
+		})
+		c.mu.Lock()
+		c.readers = append(c.readers, reader)
+		c.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.follow(ctx, reader, handle)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// follow reads one partition until ctx is cancelled or the reader is closed.
+// Read errors, such as a broker restart, are retried with backoff.
+func (c *BroadcastConsumer) follow(ctx context.Context, reader *kafka.Reader, handle Handler) {
+	l := slog.With("topic", c.topic, "partition", reader.Config().Partition)
+	backoff := retryBackoff
+	retry := func(event string, err error) bool {
+		if ctx.Err() != nil || errors.Is(err, io.EOF) {
+			return false
+		}
+		l.Warn(event, "error", err, "retry_in", backoff.String())
+		if !sleep(ctx, backoff) {
+			return false
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+		return true
+	}
+
+	for {
+		err := reader.SetOffsetAt(ctx, time.Now().Add(-c.since))
+		if err == nil {
+			break
+		}
+		if !retry("kafka_set_offset_failed", err) {
+			return
+		}
+	}
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			if !retry("kafka_read_failed", err) {
+				return
+			}
+			continue
+		}
+		backoff = retryBackoff
+
+		if err := handle(ctx, msg); err != nil {
+			l.Error("kafka_message_failed", "offset", msg.Offset, "error", err)
+		}
+	}
+}
+
+func (c *BroadcastConsumer) partitions(ctx context.Context) ([]kafka.Partition, error) {
+	for {
+		partitions, err := c.readPartitions(ctx)
+		if err == nil && len(partitions) > 0 {
+			return partitions, nil
+		}
+		slog.Warn("kafka_topic_unavailable", "topic", c.topic, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (c *BroadcastConsumer) readPartitions(ctx context.Context) ([]kafka.Partition, error) {
+	var lastErr error
+	for _, broker := range c.brokers {
+		conn, err := (&kafka.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, "tcp", broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		partitions, err := conn.ReadPartitions(c.topic)
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return partitions, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no partitions")
+	}
+	return nil, lastErr
+}
+
+func (c *BroadcastConsumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for _, r := range c.readers {
+		if err := r.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
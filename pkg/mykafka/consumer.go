@@ -0,0 +1,169 @@
+package mykafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// handleAttempts is how many times a message is handled before it is
+	// moved to the dead-letter topic.
+	handleAttempts  = 5
+	retryBackoff    = 200 * time.Millisecond
+	maxRetryBackoff = 30 * time.Second
+
+	// DeadLetterSuffix is appended to the topic name to get the topic that
+	// receives messages no handler attempt could process.
+	DeadLetterSuffix = ".dlq"
+)
+
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+type Consumer struct {
+	reader     *kafka.Reader
+	deadLetter *kafka.Writer
+}
+
+func NewConsumer(brokers []string, topic, groupID string) (*Consumer, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no brokers provided")
+	}
+	if topic == "" || groupID == "" {
+		return nil, fmt.Errorf("topic and group id are required")
+	}
+	return &Consumer{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			Topic:   topic,
+			GroupID: groupID,
+		}),
+		deadLetter: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic + DeadLetterSuffix,
+			Balancer:               &kafka.Hash{},
+			AllowAutoTopicCreation: true,
+		},
+	}, nil
+}
+
+// Run passes messages to handle until ctx is cancelled or the consumer is
+// closed. A failing message is retried with backoff and, after
+// handleAttempts, written to the dead-letter topic. Its offset is committed
+// only once it was handled or dead-lettered, so delivery is at-least-once
+// and a failure never drops a message. Fetch and commit errors, such as a
+// broker restart, are retried with backoff as well.
+func (c *Consumer) Run(ctx context.Context, handle Handler) error {
+	backoff := retryBackoff
+	retry := func(event string, err error) bool {
+		if ctx.Err() != nil || errors.Is(err, io.EOF) {
+			return false
+		}
+		slog.Warn(event, "topic", c.reader.Config().Topic, "error", err, "retry_in", backoff.String())
+		if !sleep(ctx, backoff) {
+			return false
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+		return true
+	}
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if !retry("kafka_fetch_failed", err) {
+				return nil
+			}
+			continue
+		}
+		backoff = retryBackoff
+
+		if err := c.process(ctx, handle, msg); err != nil {
+			// Only cancellation stops processing; the message stays
+			// uncommitted and is delivered again.
+			return nil
+		}
+
+		for {
+			err := c.reader.CommitMessages(ctx, msg)
+			if err == nil {
+				break
+			}
+			if !retry("kafka_commit_failed", err) {
+				return nil
+			}
+		}
+		backoff = retryBackoff
+	}
+}
+
+// process handles msg, falling back to the dead-letter topic. It returns an
+// error only when ctx is cancelled before either succeeds.
+func (c *Consumer) process(ctx context.Context, handle Handler, msg kafka.Message) error {
+	l := slog.With("topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+	backoff := retryBackoff
+
+	var err error
+	for attempt := 1; attempt <= handleAttempts; attempt++ {
+		if err = handle(ctx, msg); err == nil {
+			return nil
+		}
+		l.Warn("kafka_message_failed", "attempt", attempt, "error", err)
+		if attempt < handleAttempts {
+			if !sleep(ctx, backoff) {
+				return ctx.Err()
+			}
+			backoff = min(2*backoff, maxRetryBackoff)
+		}
+	}
+
+	dead := kafka.Message{
+		Key:   msg.Key,
+		Value: msg.Value,
+		Headers: append(msg.Headers,
+			kafka.Header{Key: "dlq-error", Value: []byte(err.Error())},
+			kafka.Header{Key: "dlq-topic", Value: []byte(msg.Topic)},
+			kafka.Header{Key: "dlq-partition", Value: []byte(strconv.Itoa(msg.Partition))},
+			kafka.Header{Key: "dlq-offset", Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		),
+	}
+	for {
+		werr := c.writeDeadLetter(ctx, dead)
+		if werr == nil {
+			l.Error("kafka_message_dead_lettered", "dlq", c.deadLetter.Topic, "error", err)
+			return nil
+		}
+		l.Error("kafka_dead_letter_failed", "dlq", c.deadLetter.Topic, "error", werr)
+		if !sleep(ctx, backoff) {
+			return ctx.Err()
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+func (c *Consumer) writeDeadLetter(ctx context.Context, msg kafka.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	return c.deadLetter.WriteMessages(ctx, msg)
+}
+
+// sleep waits for d and reports false when ctx is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (c *Consumer) Close() error {
+	return errors.Join(c.reader.Close(), c.deadLetter.Close())
+}
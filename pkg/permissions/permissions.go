@@ -0,0 +1,20 @@
+// Package permissions names the permissions auth grants to roles. Access
+// tokens carry the permissions of the user's role in the scp claim.
+package permissions
+
+const (
+	CatalogWrite       = "catalog:write"
+	ReviewsModerate    = "reviews:moderate"
+	OrdersUpdateStatus = "orders:update_status"
+	UsersManage        = "users:manage"
+	AuditRead          = "audit:read"
+)
+
+// All lists every known permission; the admin role always has all of them.
+var All = []string{CatalogWrite, ReviewsModerate, OrdersUpdateStatus, UsersManage, AuditRead}
+
+// Privileged permissions change shop data, manage accounts or read their
+// personal data; roles granting any of them must use two-factor
+// authentication. A permission added later is privileged unless it only
+// reads public data.
+var Privileged = []string{CatalogWrite, ReviewsModerate, OrdersUpdateStatus, UsersManage, AuditRead}
@@ -0,0 +1,170 @@
+// Package revocation keeps the access tokens revoked by the auth service in
+// memory so that every request can be checked without a network call.
+package revocation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/pkg/events"
+	"github.com/Skotchmaster/online_shop/pkg/mykafka"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/segmentio/kafka-go"
+)
+
+// Window is the lifetime of an access token. Revocations older than that
+// cannot affect a live token and are dropped.
+const Window = 15 * time.Minute
+
+// List is a denylist of token ids plus a per-user watermark: tokens of a user
+// issued before the watermark are revoked. It is safe for concurrent use.
+type List struct {
+	mu        sync.RWMutex
+	tokens    map[string]time.Time
+	notBefore map[string]time.Time
+}
+
+func New() *List {
+	return &List{
+		tokens:    make(map[string]time.Time),
+		notBefore: make(map[string]time.Time),
+	}
+}
+
+// RevokeToken denies jti until exp.
+func (l *List) RevokeToken(jti string, exp time.Time) {
+	if jti == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens[jti] = exp
+}
+
+// RevokeUser denies every token of userID issued before notBefore. An older
+// watermark never replaces a newer one.
+func (l *List) RevokeUser(userID string, notBefore time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cur, ok := l.notBefore[userID]; !ok || notBefore.After(cur) {
+		l.notBefore[userID] = notBefore
+	}
+}
+
+// Revoked implements tokens.RevocationChecker. Tokens issued at or after the
+// watermark are kept, so the tokens auth issues right after a revocation stay
+// valid. Auth writes iat with microsecond precision, the precision the
+// watermark is stored with; a legacy whole-second iat in the watermark's
+// second counts as issued before it.
+func (l *List) Revoked(claims *tokens.AccessClaims) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if claims.ID != "" {
+		if _, ok := l.tokens[claims.ID]; ok {
+			return true
+		}
+	}
+	nb, ok := l.notBefore[claims.Subject]
+	if !ok {
+		return false
+	}
+	if claims.IssuedAt == nil {
+		return true
+	}
+	return claims.IssuedAt.Before(nb.Truncate(time.Microsecond))
+}
+
+func (l *List) Apply(e events.AuthEvent) {
+	switch e.Type {
+	case events.AccessTokenRevoked:
+		l.RevokeToken(e.JTI, e.ExpiresAt)
+	case events.UserTokensRevoked:
+		l.RevokeUser(e.UserID.String(), e.NotBefore)
+	}
+}
+
+// HandleMessage is a mykafka.Handler for events.AuthEventsTopic.
+func (l *List) HandleMessage(_ context.Context, msg kafka.Message) error {
+	var e events.AuthEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %w", err)
+	}
+	l.Apply(e)
+	return nil
+}
+
+// Prune drops denied tokens that have expired and watermarks older than
+// Window.
+func (l *List) Prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for jti, exp := range l.tokens {
+		if now.After(exp) {
+			delete(l.tokens, jti)
+		}
+	}
+	for user, nb := range l.notBefore {
+		if now.Sub(nb) > Window {
+			delete(l.notBefore, user)
+		}
+	}
+}
+
+// Follow keeps l in sync with events.AuthEventsTopic until ctx is
+// cancelled. It replays the last Window of events first, so a freshly
+// started instance knows about revocations made before it came up.
+func (l *List) Follow(ctx context.Context, brokers []string) (io.Closer, error) {
+	consumer, err := mykafka.NewBroadcastConsumer(brokers, events.AuthEventsTopic, Window)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := consumer.Run(ctx, l.HandleMessage); err != nil {
+			slog.Error("revocation_consumer_stopped", "error", err)
+		}
+	}()
+	go l.pruneLoop(ctx)
+	return consumer, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// Attach makes v reject the tokens auth revokes, following the events in
+// brokers until ctx is cancelled. Without brokers revocations are not seen
+// and revoked tokens stay valid until they expire. The returned closer stops
+// the consumer and is never nil.
+func Attach(ctx context.Context, v *tokens.Verifier, brokers []string) (io.Closer, error) {
+	if len(brokers) == 0 {
+		slog.Warn("token_revocation_disabled", "reason", "KAFKA_BROKERS is not set")
+		return nopCloser{}, nil
+	}
+	list := New()
+	follower, err := list.Follow(ctx, brokers)
+	if err != nil {
+		return nil, fmt.Errorf("revocation consumer: %w", err)
+	}
+	v.Revocations = list
+	return follower, nil
+}
+
+func (l *List) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.Prune(now)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package revocation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+)
+
+func claimsAt(sub, jti string, iat time.Time) *tokens.AccessClaims {
+	c := &tokens.AccessClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: sub, ID: jti}}
+	if !iat.IsZero() {
+		c.IssuedAt = jwt.NewNumericDate(iat)
+	}
+	return c
+}
+
+func TestList_Revoked(t *testing.T) {
+	t.Parallel()
+
+	watermark := time.Date(2025, 3, 1, 12, 0, 0, 700_000_000, time.UTC)
+	l := New()
+	l.RevokeUser("user-1", watermark)
+	l.RevokeToken("jti-1", watermark.Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		claims *tokens.AccessClaims
+		want   bool
+	}{
+		{name: "denied jti", claims: claimsAt("user-2", "jti-1", watermark.Add(time.Minute)), want: true},
+		{name: "other user", claims: claimsAt("user-2", "jti-2", watermark.Add(-time.Minute))},
+		{name: "issued a minute before", claims: claimsAt("user-1", "", watermark.Add(-time.Minute)), want: true},
+		{name: "issued earlier in the same second", claims: claimsAt("user-1", "", watermark.Add(-300*time.Millisecond)), want: true},
+		{name: "whole-second iat in the same second", claims: claimsAt("user-1", "", watermark.Truncate(time.Second)), want: true},
+		{name: "issued at the watermark", claims: claimsAt("user-1", "", watermark)},
+		{name: "issued later in the same second", claims: claimsAt("user-1", "", watermark.Add(200*time.Millisecond))},
+		{name: "issued after", claims: claimsAt("user-1", "", watermark.Add(time.Second))},
+		{name: "no iat", claims: claimsAt("user-1", "", time.Time{}), want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, l.Revoked(tt.claims))
+		})
+	}
+}
+
+func TestList_RevokeUser(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		watermarks []time.Time
+		want       time.Time
+	}{
+		{name: "first", watermarks: []time.Time{base}, want: base},
+		{name: "newer replaces", watermarks: []time.Time{base, base.Add(time.Minute)}, want: base.Add(time.Minute)},
+		{name: "older is ignored", watermarks: []time.Time{base.Add(time.Minute), base}, want: base.Add(time.Minute)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := New()
+			for _, nb := range tt.watermarks {
+				l.RevokeUser("user-1", nb)
+			}
+			assert.Equal(t, tt.want, l.notBefore["user-1"])
+			assert.True(t, l.Revoked(claimsAt("user-1", "", tt.want.Add(-time.Microsecond))))
+			assert.False(t, l.Revoked(claimsAt("user-1", "", tt.want)))
+		})
+	}
+}
+
+func TestList_Prune(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := New()
+	l.RevokeToken("expired", now.Add(-time.Second))
+	l.RevokeToken("live", now.Add(time.Minute))
+	l.RevokeUser("old", now.Add(-Window-time.Second))
+	l.RevokeUser("recent", now.Add(-Window+time.Second))
+
+	l.Prune(now)
+
+	assert.NotContains(t, l.tokens, "expired")
+	assert.Contains(t, l.tokens, "live")
+	assert.NotContains(t, l.notBefore, "old")
+	assert.Contains(t, l.notBefore, "recent")
+
+	assert.False(t, l.Revoked(claimsAt("x", "expired", now)))
+	assert.True(t, l.Revoked(claimsAt("x", "live", now)))
+}
@@ -1,12 +1,29 @@
 package tokens
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func init() {
+	// Revocation watermarks have microsecond precision; iat needs the same to
+	// tell a token issued just before a revocation from one issued just after.
+	jwt.TimePrecision = time.Microsecond
+}
 
 type AccessClaims struct {
 	Role string `json:"role"`
+	// Scopes are the permissions of Role when the token was issued.
+	Scopes []string `json:"scp,omitempty"`
 	jwt.RegisteredClaims
 }
 
+func (c *AccessClaims) HasScope(permission string) bool {
+	return slices.Contains(c.Scopes, permission)
+}
+
 type RefreshClaims struct {
 	jwt.RegisteredClaims
-}
\ No newline at end of file
+}
@@ -0,0 +1,146 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySource resolves the public key a token names in its kid header.
+type KeySource interface {
+	PublicKey(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// fetchTimeout bounds a JWKS fetch. The fetch is shared by every caller
+// waiting for it, so it does not run on any caller's context.
+const fetchTimeout = 5 * time.Second
+
+// JWKSClient caches the key set published by the auth service. An unknown
+// kid triggers a refetch, at most once per MinRefresh, so a freshly rotated
+// key is picked up without waiting for the TTL. If auth cannot be reached the
+// cached keys keep being used. Concurrent misses share one fetch, and the
+// cache stays readable while it runs.
+type JWKSClient struct {
+	URL        string
+	TTL        time.Duration
+	MinRefresh time.Duration
+	HTTP       *http.Client
+
+	group singleflight.Group
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func NewJWKSClient(url string, ttl time.Duration) *JWKSClient {
+	return &JWKSClient{
+		URL:        url,
+		TTL:        ttl,
+		MinRefresh: 10 * time.Second,
+		HTTP:       &http.Client{Timeout: fetchTimeout},
+	}
+}
+
+func (c *JWKSClient) PublicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok, fresh, throttled := c.cached(kid)
+	if fresh {
+		return key, nil
+	}
+	if throttled {
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("kid %q: %w", kid, ErrUnknownKey)
+	}
+
+	var fetchErr error
+	select {
+	case res := <-c.group.DoChan("jwks", func() (any, error) { return nil, c.refresh() }):
+		fetchErr = res.Err
+	case <-ctx.Done():
+		if ok {
+			return key, nil
+		}
+		return nil, ctx.Err()
+	}
+
+	if key, ok, _, _ := c.cached(kid); ok {
+		return key, nil
+	}
+	if fetchErr != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", fetchErr)
+	}
+	return nil, fmt.Errorf("kid %q: %w", kid, ErrUnknownKey)
+}
+
+// cached looks kid up and reports whether the set is still fresh and whether
+// a refetch is due to wait for MinRefresh.
+func (c *JWKSClient) cached(kid string) (key crypto.PublicKey, ok, fresh, throttled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok = c.keys[kid]
+	fresh = ok && time.Since(c.fetchedAt) < c.TTL
+	throttled = time.Since(c.lastAttempt) < c.MinRefresh
+	return key, ok, fresh, throttled
+}
+
+// refresh replaces the cached set. A failed fetch keeps the old one.
+func (c *JWKSClient) refresh() error {
+	c.mu.Lock()
+	c.lastAttempt = time.Now()
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	keys, err := c.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *JWKSClient) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = pub
+	}
+	return keys, nil
+}
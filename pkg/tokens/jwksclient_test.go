@@ -0,0 +1,211 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksServer publishes keys and counts the requests it serves. While down is
+// set it answers 503; release, when set, holds every response until closed.
+type jwksServer struct {
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	down    bool
+	release chan struct{}
+	hits    atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys map[string]crypto.PublicKey) (*jwksServer, *JWKSClient) {
+	t.Helper()
+
+	s := &jwksServer{keys: keys}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		s.mu.Lock()
+		down, release := s.down, s.release
+		set := JWKS{}
+		for kid, pub := range s.keys {
+			jwk, err := NewJWK(kid, pub)
+			require.NoError(t, err)
+			set.Keys = append(set.Keys, jwk)
+		}
+		s.mu.Unlock()
+
+		if release != nil {
+			<-release
+		}
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewJWKSClient(srv.URL, time.Hour)
+	c.MinRefresh = 0
+	return s, c
+}
+
+func (s *jwksServer) set(fn func(s *jwksServer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+func newEd25519(t *testing.T) ed25519.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return pub
+}
+
+func TestJWKSClient_PublicKey(t *testing.T) {
+	t.Parallel()
+
+	first, second := newEd25519(t), newEd25519(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		run      func(t *testing.T, s *jwksServer, c *JWKSClient)
+		wantHits int32
+	}{
+		{
+			name: "cached within ttl",
+			run: func(t *testing.T, s *jwksServer, c *JWKSClient) {
+				for range 3 {
+					key, err := c.PublicKey(ctx, "k1")
+					require.NoError(t, err)
+					assert.Equal(t, first, key)
+				}
+			},
+			wantHits: 1,
+		},
+		{
+			name: "unknown kid refetches",
+			run: func(t *testing.T, s *jwksServer, c *JWKSClient) {
+				_, err := c.PublicKey(ctx, "k1")
+				require.NoError(t, err)
+				s.set(func(s *jwksServer) { s.keys["k2"] = second })
+
+				key, err := c.PublicKey(ctx, "k2")
+				require.NoError(t, err)
+				assert.Equal(t, second, key)
+			},
+			wantHits: 2,
+		},
+		{
+			name: "refetch waits for min refresh",
+			run: func(t *testing.T, s *jwksServer, c *JWKSClient) {
+				c.MinRefresh = time.Hour
+				_, err := c.PublicKey(ctx, "k1")
+				require.NoError(t, err)
+
+				_, err = c.PublicKey(ctx, "k2")
+				require.ErrorIs(t, err, ErrUnknownKey)
+			},
+			wantHits: 1,
+		},
+		{
+			name: "stale key used while auth is down",
+			run: func(t *testing.T, s *jwksServer, c *JWKSClient) {
+				_, err := c.PublicKey(ctx, "k1")
+				require.NoError(t, err)
+				c.TTL = 0
+				s.set(func(s *jwksServer) { s.down = true })
+
+				key, err := c.PublicKey(ctx, "k1")
+				require.NoError(t, err)
+				assert.Equal(t, first, key)
+
+				_, err = c.PublicKey(ctx, "k2")
+				require.Error(t, err)
+				assert.NotErrorIs(t, err, ErrUnknownKey, "a failed fetch is not an unknown key")
+			},
+			wantHits: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, c := newJWKSServer(t, map[string]crypto.PublicKey{"k1": first})
+			tt.run(t, s, c)
+			assert.Equal(t, tt.wantHits, s.hits.Load())
+		})
+	}
+}
+
+func TestJWKSClient_ConcurrentMissesShareOneFetch(t *testing.T) {
+	t.Parallel()
+
+	key := newEd25519(t)
+	s, c := newJWKSServer(t, map[string]crypto.PublicKey{"k1": key})
+	release := make(chan struct{})
+	s.set(func(s *jwksServer) { s.release = release })
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.PublicKey(context.Background(), "k1")
+			errs <- err
+		}()
+	}
+
+	require.Eventually(t, func() bool { return s.hits.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, s.hits.Load())
+}
+
+func TestJWKSClient_FetchOutlivesCallerContext(t *testing.T) {
+	t.Parallel()
+
+	key := newEd25519(t)
+	s, c := newJWKSServer(t, map[string]crypto.PublicKey{"k1": key})
+	release := make(chan struct{})
+	s.set(func(s *jwksServer) { s.release = release })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.PublicKey(ctx, "k1")
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool { return s.hits.Load() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled, "the caller stops waiting")
+
+	close(release)
+	c.MinRefresh = time.Hour
+	require.Eventually(t, func() bool {
+		got, err := c.PublicKey(context.Background(), "k1")
+		return err == nil && key.Equal(got)
+	}, time.Second, time.Millisecond, "the shared fetch still fills the cache")
+	assert.EqualValues(t, 1, s.hits.Load())
+}
@@ -0,0 +1,75 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrTokenRevoked is returned for a validly signed token that auth has
+// revoked before its expiry.
+var ErrTokenRevoked = errors.New("token revoked")
+
+// RevocationChecker reports whether auth revoked an access token.
+type RevocationChecker interface {
+	Revoked(claims *AccessClaims) bool
+}
+
+// Verifier checks access tokens signed by the auth service with one of its
+// published keys. Secret, when set, also accepts legacy HS256 tokens while
+// services move off the shared secret. Revocations, when set, rejects tokens
+// revoked by logout, session revocation or a role change.
+type Verifier struct {
+	Keys        KeySource
+	Secret      []byte
+	Revocations RevocationChecker
+}
+
+func NewVerifier(keys KeySource, secret []byte) *Verifier {
+	return &Verifier{Keys: keys, Secret: secret}
+}
+
+func (v *Verifier) AccessClaims(ctx context.Context, token string) (*AccessClaims, error) {
+	methods := []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+	if len(v.Secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	var claims AccessClaims
+	tkn, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		if t.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+			return v.Secret, nil
+		}
+		if v.Keys == nil {
+			return nil, ErrUnknownKey
+		}
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid")
+		}
+		pub, err := v.Keys.PublicKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		method, err := SigningMethodFor(pub)
+		if err != nil {
+			return nil, err
+		}
+		if method.Alg() != t.Method.Alg() {
+			return nil, fmt.Errorf("kid %q is not a %s key", kid, t.Method.Alg())
+		}
+		return pub, nil
+	}, jwt.WithValidMethods(methods))
+	if err != nil {
+		return nil, err
+	}
+	if !tkn.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if v.Revocations != nil && v.Revocations.Revoked(&claims) {
+		return nil, ErrTokenRevoked
+	}
+	return &claims, nil
+}
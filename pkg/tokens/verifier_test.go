@@ -0,0 +1,108 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticKeys is a KeySource over a fixed set of public keys.
+type staticKeys map[string]crypto.PublicKey
+
+func (k staticKeys) PublicKey(_ context.Context, kid string) (crypto.PublicKey, error) {
+	if pub, ok := k[kid]; ok {
+		return pub, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+type revokeAll struct{}
+
+func (revokeAll) Revoked(*AccessClaims) bool { return true }
+
+func testClaims(sub string) AccessClaims {
+	return AccessClaims{
+		Role: "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sub,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims AccessClaims) string {
+	t.Helper()
+
+	tkn := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tkn.Header["kid"] = kid
+	}
+	s, err := tkn.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestVerifier_AccessClaims(t *testing.T) {
+	t.Parallel()
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, foreignPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keys := staticKeys{"ed": edPub, "rsa": &rsaPriv.PublicKey}
+	secret := []byte("secret")
+
+	expired := testClaims("user-1")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  []byte
+		revoked bool
+		wantErr error
+	}{
+		{name: "EdDSA", token: sign(t, jwt.SigningMethodEdDSA, "ed", edPriv, testClaims("user-1"))},
+		{name: "RS256", token: sign(t, jwt.SigningMethodRS256, "rsa", rsaPriv, testClaims("user-1"))},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodEdDSA, "other", foreignPriv, testClaims("user-1")), wantErr: ErrUnknownKey},
+		{name: "foreign key under known kid", token: sign(t, jwt.SigningMethodEdDSA, "ed", foreignPriv, testClaims("user-1")), wantErr: jwt.ErrTokenSignatureInvalid},
+		{name: "no kid", token: sign(t, jwt.SigningMethodEdDSA, "", edPriv, testClaims("user-1")), wantErr: jwt.ErrTokenUnverifiable},
+		{name: "alg does not match key", token: sign(t, jwt.SigningMethodRS256, "ed", rsaPriv, testClaims("user-1")), wantErr: jwt.ErrTokenUnverifiable},
+		{name: "expired", token: sign(t, jwt.SigningMethodEdDSA, "ed", edPriv, expired), wantErr: jwt.ErrTokenExpired},
+		{name: "HS256 without secret", token: sign(t, jwt.SigningMethodHS256, "", secret, testClaims("user-1")), wantErr: jwt.ErrTokenSignatureInvalid},
+		{name: "HS256 with secret", token: sign(t, jwt.SigningMethodHS256, "", secret, testClaims("user-1")), secret: secret},
+		{name: "HS256 other secret", token: sign(t, jwt.SigningMethodHS256, "", []byte("other"), testClaims("user-1")), secret: secret, wantErr: jwt.ErrTokenSignatureInvalid},
+		{name: "revoked", token: sign(t, jwt.SigningMethodEdDSA, "ed", edPriv, testClaims("user-1")), revoked: true, wantErr: ErrTokenRevoked},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := NewVerifier(keys, tt.secret)
+			if tt.revoked {
+				v.Revocations = revokeAll{}
+			}
+			claims, err := v.AccessClaims(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, claims)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims.Subject)
+		})
+	}
+}
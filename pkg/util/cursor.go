@@ -0,0 +1,134 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the keyset position of a single row. It is handed to clients as
+// an opaque base64 string, so the fields may change without breaking the API.
+type Cursor struct {
+	Dir   string     `json:"d"`
+	ID    uuid.UUID  `json:"id"`
+	Score *float64   `json:"s,omitempty"`
+	Time  *time.Time `json:"t,omitempty"`
+	Mode  string     `json:"m,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (c *Cursor) Backward() bool {
+	return c != nil && c.Dir == CursorPrev
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Dir != CursorNext && c.Dir != CursorPrev {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func CursorLimit(size int) int {
+	_, limit := Calculate(1, size)
+	return limit
+}
+
+// KeysetPage trims the lookahead row fetched by a keyset query (limit+1) and
+// puts backward pages back into natural order.
+func KeysetPage[T any](items []T, limit int, cur *Cursor) (page []T, hasNext, hasPrev bool) {
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	if cur.Backward() {
+		slices.Reverse(items)
+		return items, true, hasMore
+	}
+	return items, hasMore, cur != nil
+}
+
+func PageCursors[T any](items []T, hasNext, hasPrev bool, key func(T) Cursor) (next, prev string) {
+	if len(items) == 0 {
+		return "", ""
+	}
+	if hasNext {
+		c := key(items[len(items)-1])
+		c.Dir = CursorNext
+		next = c.Encode()
+	}
+	if hasPrev {
+		c := key(items[0])
+		c.Dir = CursorPrev
+		prev = c.Encode()
+	}
+	return next, prev
+}
+
+type CursorPage[T any] struct {
+	Items         []T
+	NextCursor    string
+	PrevCursor    string
+	HasNext       bool
+	HasPrev       bool
+	Total         *int64
+	TotalEstimate *int64
+}
+
+func (p *CursorPage[T]) Meta(size int) map[string]any {
+	meta := map[string]any{
+		"size":        size,
+		"next_cursor": p.NextCursor,
+		"prev_cursor": p.PrevCursor,
+		"has_next":    p.HasNext,
+		"has_prev":    p.HasPrev,
+	}
+	if p.Total != nil {
+		meta["total"] = *p.Total
+	}
+	if p.TotalEstimate != nil {
+		meta["total_estimate"] = *p.TotalEstimate
+	}
+	return meta
+}
+
+func NewCursorPage[T any](rows []T, limit int, cur *Cursor, key func(T) Cursor) *CursorPage[T] {
+	items, hasNext, hasPrev := KeysetPage(rows, limit, cur)
+	next, prev := PageCursors(items, hasNext, hasPrev, key)
+	return &CursorPage[T]{
+		Items:      items,
+		NextCursor: next,
+		PrevCursor: prev,
+		HasNext:    hasNext,
+		HasPrev:    hasPrev,
+	}
+}
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	t.Parallel()
+
+	score := 4.5
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	valid := Cursor{Dir: CursorNext, ID: uuid.New(), Score: &score, Time: &at, Mode: "rating"}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    *Cursor
+		wantErr bool
+	}{
+		{name: "empty", in: ""},
+		{name: "round trip", in: valid.Encode(), want: &valid},
+		{name: "not base64", in: "!!!", wantErr: true},
+		{name: "not json", in: base64.RawURLEncoding.EncodeToString([]byte("nope")), wantErr: true},
+		{name: "unknown direction", in: Cursor{Dir: "up", ID: uuid.New()}.Encode(), wantErr: true},
+		{name: "missing id", in: Cursor{Dir: CursorPrev}.Encode(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := DecodeCursor(tt.in)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidCursor)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want.Dir, got.Dir)
+			assert.Equal(t, tt.want.ID, got.ID)
+			assert.Equal(t, *tt.want.Score, *got.Score)
+			assert.True(t, tt.want.Time.Equal(*got.Time))
+			assert.Equal(t, tt.want.Mode, got.Mode)
+		})
+	}
+}
+
+func TestKeysetPage(t *testing.T) {
+	t.Parallel()
+
+	next := &Cursor{Dir: CursorNext, ID: uuid.New()}
+	prev := &Cursor{Dir: CursorPrev, ID: uuid.New()}
+
+	tests := []struct {
+		name     string
+		rows     []int
+		cur      *Cursor
+		want     []int
+		wantNext bool
+		wantPrev bool
+	}{
+		{name: "first page with more", rows: []int{1, 2, 3}, want: []int{1, 2}, wantNext: true},
+		{name: "first page exact", rows: []int{1, 2}, want: []int{1, 2}},
+		{name: "forward with more", rows: []int{3, 4, 5}, cur: next, want: []int{3, 4}, wantNext: true, wantPrev: true},
+		{name: "forward last page", rows: []int{5}, cur: next, want: []int{5}, wantPrev: true},
+		{name: "backward with more", rows: []int{4, 3, 2}, cur: prev, want: []int{3, 4}, wantNext: true, wantPrev: true},
+		{name: "backward first page", rows: []int{2, 1}, cur: prev, want: []int{1, 2}, wantNext: true},
+		{name: "empty", rows: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			page, hasNext, hasPrev := KeysetPage(tt.rows, 2, tt.cur)
+			assert.Equal(t, tt.want, page)
+			assert.Equal(t, tt.wantNext, hasNext, "hasNext")
+			assert.Equal(t, tt.wantPrev, hasPrev, "hasPrev")
+		})
+	}
+}
+
+func TestNewCursorPage(t *testing.T) {
+	t.Parallel()
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	key := func(id uuid.UUID) Cursor { return Cursor{ID: id} }
+
+	page := NewCursorPage(ids, 2, &Cursor{Dir: CursorNext, ID: uuid.New()}, key)
+	require.Len(t, page.Items, 2)
+
+	next, err := DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, CursorNext, next.Dir)
+	assert.Equal(t, ids[1], next.ID)
+
+	prev, err := DecodeCursor(page.PrevCursor)
+	require.NoError(t, err)
+	assert.Equal(t, CursorPrev, prev.Dir)
+	assert.Equal(t, ids[0], prev.ID)
+
+	last := NewCursorPage(ids[:1], 2, nil, key)
+	assert.Empty(t, last.NextCursor)
+	assert.Empty(t, last.PrevCursor)
+	assert.False(t, last.HasNext)
+	assert.False(t, last.HasPrev)
+}
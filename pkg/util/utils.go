@@ -14,6 +14,16 @@ func ParseIntDefault(s string, def int) int {
 	return def
 }
 
+func ParseBoolDefault(s string, def bool) bool {
+	if s == "" {
+		return def
+	}
+	if v, err := strconv.ParseBool(s); err == nil {
+		return v
+	}
+	return def
+}
+
 func Calculate(page, size int) (offset int, limit int) {
     if page < 1 {
         page = 1
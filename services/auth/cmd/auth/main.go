@@ -8,9 +8,20 @@ import (
 	"os/signal"
 	"time"
 
+	"github.com/Skotchmaster/online_shop/pkg/events"
+	"github.com/Skotchmaster/online_shop/pkg/mykafka"
+	"github.com/Skotchmaster/online_shop/pkg/permissions"
+	"github.com/Skotchmaster/online_shop/pkg/revocation"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/cartclient"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/config"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/httpserver"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/keyset"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/mailer"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/password"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/secretbox"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
 	"github.com/labstack/echo/v4"
 )
@@ -19,6 +30,9 @@ func main() {
 	cfg := config.Load()
 
 	e := echo.New()
+	// Requests arrive through the gateway; trust X-Forwarded-For from
+	// private networks only.
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
 	e.Server.ReadTimeout = 10 * time.Second
 	e.Server.WriteTimeout = 15 * time.Second
 	e.Server.ReadHeaderTimeout = 3 * time.Second
@@ -36,9 +50,112 @@ func main() {
 		RefreshSecret: cfg.RefreshSecret,
 	}
 
+	var mail mailer.Mailer = mailer.LogMailer{}
+	if cfg.Mailer == "file" {
+		fm, err := mailer.NewFileMailer(cfg.MailDir)
+		if err != nil {
+			log.Fatalf("mailer init error: %v", err)
+		}
+		mail = fm
+	}
+
+	policy := password.Default()
+	policy.MinLength = cfg.PasswordMinLength
+	policy.MinClasses = cfg.PasswordMinClasses
+	if cfg.PasswordBlocklist != "" {
+		if err := policy.LoadBlocklistFile(cfg.PasswordBlocklist); err != nil {
+			log.Fatalf("password blocklist: %v", err)
+		}
+	}
+
 	authService := &service.AuthService{
-		Repo: gormRepo,
+		Repo:      gormRepo,
+		Mailer:    mail,
+		BaseURL:   cfg.BaseURL,
+		VerifyTTL: cfg.VerifyTTL,
+		ResetTTL:  cfg.ResetTTL,
+		Passwords: policy,
+		Throttle: &service.LoginThrottle{
+			User:        service.ThrottleRule{FreeAttempts: cfg.LoginUserFreeAttempts, LockoutAfter: cfg.LoginUserLockoutAfter},
+			IP:          service.ThrottleRule{FreeAttempts: cfg.LoginIPFreeAttempts, LockoutAfter: cfg.LoginIPLockoutAfter},
+			BackoffBase: time.Second,
+			BackoffMax:  cfg.LoginBackoffMax,
+			LockoutFor:  cfg.LoginLockoutFor,
+		},
+		RefreshReuseGrace: cfg.RefreshReuseGrace,
+	}
+	var publicKeys tokens.KeySource
+	if cfg.KeysDir != "" {
+		keys, err := keyset.Load(cfg.KeysDir, cfg.KeyOverlap, cfg.KeyAlg)
+		if err != nil {
+			log.Fatalf("signing keys: %v", err)
+		}
+		keys.Watch(context.Background(), time.Minute)
+		authService.Keys = keys
+		publicKeys = keys
+	} else {
+		log.Println("JWT_KEYS_DIR is empty, access tokens are signed with the shared JWT_SECRET")
+	}
+	box, err := secretbox.New(cfg.TOTPKey)
+	if err != nil {
+		log.Fatalf("totp key: %v", err)
+	}
+	authService.TwoFactor = &service.TwoFactorConfig{Box: box, Issuer: cfg.TOTPIssuer}
+	if cfg.RequireAdmin2FA {
+		authService.TwoFactor.RequiredPermissions = permissions.Privileged
+	}
+	if len(cfg.OIDCProviders) > 0 {
+		authService.OIDC = make(map[string]*oidc.Provider, len(cfg.OIDCProviders))
+		for _, p := range cfg.OIDCProviders {
+			authService.OIDC[p.Name] = oidc.New(p)
+		}
+	}
+	if cfg.CartURL != "" {
+		authService.Carts = cartclient.NewClient(cfg.CartURL, cfg.InternalToken)
+	}
+
+	ctx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+
+	authService.Revocations = revocation.New()
+	loadCtx, loadCancel := context.WithTimeout(ctx, 10*time.Second)
+	err = authService.LoadRevocations(loadCtx)
+	loadCancel()
+	if err != nil {
+		log.Fatalf("revocations: %v", err)
+	}
+	if len(cfg.KafkaBrokers) > 0 {
+		producer, err := mykafka.NewProducer(cfg.KafkaBrokers, []string{events.AuthEventsTopic})
+		if err != nil {
+			log.Fatalf("kafka producer: %v", err)
+		}
+		defer producer.Close()
+		authService.Events = producer
+
+		// Other auth instances revoke tokens too.
+		consumer, err := authService.Revocations.Follow(ctx, cfg.KafkaBrokers)
+		if err != nil {
+			log.Fatalf("kafka consumer: %v", err)
+		}
+		defer consumer.Close()
+	} else {
+		log.Println("KAFKA_BROKERS is empty, access token revocations are not sent to other services")
+	}
+
+	if cfg.BootstrapAdmin != "" {
+		if err := authService.BootstrapAdmin(ctx, cfg.BootstrapAdmin, cfg.BootstrapAdminPassword); err != nil {
+			log.Printf("bootstrap admin: %v", err)
+		}
+	}
+
+	// With signing keys configured HS256 tokens are never accepted, even if
+	// JWT_SECRET is still set.
+	var legacySecret []byte
+	if publicKeys == nil {
+		legacySecret = cfg.JWTSecret
 	}
+	verifier := tokens.NewVerifier(publicKeys, legacySecret)
+	verifier.Revocations = authService.Revocations
 
 	authHandler := &httpserver.AuthHTTP{
 		Svc: authService,
@@ -46,7 +163,7 @@ func main() {
 
 	httpserver.Register(e, &httpserver.Deps{
 		AuthHandler: authHandler,
-		JWT_Secret: cfg.JWTSecret,
+		Verifier:    verifier,
 	})
 
 	go func() {
@@ -0,0 +1,41 @@
+// Command oidc-mock runs the test OpenID provider for trying social login
+// locally. It signs in everyone who asks, so never expose it.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc/oidctest"
+)
+
+func envDefault(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func main() {
+	addr := envDefault("OIDC_MOCK_ADDR", ":9000")
+	provider, err := oidctest.New(
+		envDefault("OIDC_MOCK_ISSUER", "http://localhost:9000"),
+		envDefault("OIDC_MOCK_CLIENT_ID", "shop"),
+		envDefault("OIDC_MOCK_CLIENT_SECRET", "secret"),
+	)
+	if err != nil {
+		log.Fatalf("oidc mock: %v", err)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           provider,
+		ReadHeaderTimeout: 3 * time.Second,
+	}
+	log.Printf("mock OpenID provider %s listening on %s", provider.Issuer, addr)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("listen: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS user_tokens;
+
+DROP INDEX IF EXISTS idx_users_email;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS email_verified_at,
+  DROP COLUMN IF EXISTS email;
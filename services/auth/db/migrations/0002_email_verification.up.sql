@@ -0,0 +1,20 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS email             text,
+  ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email
+  ON users (lower(email));
+
+-- Single-use tokens mailed to users; only the sha256 of the token is stored.
+CREATE TABLE IF NOT EXISTS user_tokens (
+  id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id    uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose    text NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  used_at    timestamptz,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_purpose
+  ON user_tokens (user_id, purpose);
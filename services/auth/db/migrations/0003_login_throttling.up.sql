@@ -0,0 +1,23 @@
+-- Failed-login counters shared by all auth replicas. key is "user:<username>"
+-- or "ip:<address>".
+CREATE TABLE IF NOT EXISTS login_throttles (
+  key             text PRIMARY KEY,
+  failures        integer NOT NULL DEFAULT 0,
+  last_failure_at timestamptz NOT NULL DEFAULT now(),
+  locked_until    timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+  id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  username   text NOT NULL,
+  ip         text NOT NULL DEFAULT '',
+  user_agent text NOT NULL DEFAULT '',
+  reason     text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username
+  ON login_attempts (username, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip
+  ON login_attempts (ip, created_at DESC);
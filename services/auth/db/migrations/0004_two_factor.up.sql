@@ -0,0 +1,35 @@
+-- totp_secret is AES-GCM sealed. It is set but not enabled while enrolment
+-- waits for the first code.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS totp_secret     text,
+  ADD COLUMN IF NOT EXISTS totp_enabled_at timestamptz,
+  ADD COLUMN IF NOT EXISTS totp_last_step  bigint;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id        uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id   uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash text NOT NULL,
+  used_at   timestamptz,
+  UNIQUE (user_id, code_hash)
+);
+
+-- A password check that still needs a second factor.
+CREATE TABLE IF NOT EXISTS login_challenges (
+  id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id    uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash text NOT NULL UNIQUE,
+  setup      boolean NOT NULL DEFAULT false,
+  attempts   integer NOT NULL DEFAULT 0,
+  expires_at timestamptz NOT NULL,
+  used_at    timestamptz,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_challenges_expires_at
+  ON login_challenges (expires_at);
+
+-- Forced enrolment mails a link to the verified email; the secret is shown
+-- only to whoever opens it.
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+  CHECK (purpose IN ('verify_email', 'reset_password', 'enrol_totp'));
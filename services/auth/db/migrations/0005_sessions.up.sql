@@ -0,0 +1,11 @@
+-- A session is the chain of refresh tokens issued by one login. Rotation
+-- copies session_id and created_at to the new row.
+ALTER TABLE refresh_tokens
+  ADD COLUMN IF NOT EXISTS session_id   uuid NOT NULL DEFAULT gen_random_uuid(),
+  ADD COLUMN IF NOT EXISTS created_at   timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS last_used_at timestamptz,
+  ADD COLUMN IF NOT EXISTS ip           text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS user_agent   text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_refresh_session
+  ON refresh_tokens (session_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS security_events;
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN IF EXISTS rotated_at;
@@ -0,0 +1,19 @@
+-- session_id doubles as the refresh token family: every token issued by one
+-- login shares it. rotated_at marks tokens replaced by a refresh, so a second
+-- use of such a token can be told apart from a logout.
+ALTER TABLE refresh_tokens
+  ADD COLUMN IF NOT EXISTS rotated_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS security_events (
+  id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id    uuid REFERENCES users(id) ON DELETE CASCADE,
+  type       text NOT NULL,
+  session_id uuid,
+  ip         text NOT NULL DEFAULT '',
+  user_agent text NOT NULL DEFAULT '',
+  details    text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user
+  ON security_events (user_id, created_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS token_watermarks;
+DROP TABLE IF EXISTS revoked_access_tokens;
@@ -0,0 +1,17 @@
+-- Access tokens are checked without a database call; these tables are the
+-- durable copy of the in-memory denylist that auth reloads on start. Rows
+-- older than the access token lifetime no longer matter.
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+  jti        text PRIMARY KEY,
+  user_id    uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_expires
+  ON revoked_access_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS token_watermarks (
+  user_id    uuid PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  not_before timestamptz NOT NULL
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS audit_log;
+
+DROP INDEX IF EXISTS idx_users_role;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS disabled_at,
+  DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,25 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS created_at  timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS disabled_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
+
+-- audit_log outlives the users it mentions, so target_id and actor_id are
+-- not foreign keys; details keeps the username of a deleted account. Login
+-- unlocks have no target user.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  actor_id   uuid,
+  action     text NOT NULL,
+  target_id  uuid,
+  details    text NOT NULL DEFAULT '',
+  ip         text NOT NULL DEFAULT '',
+  user_agent text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target
+  ON audit_log (target_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor
+  ON audit_log (actor_id, created_at DESC);
@@ -0,0 +1,10 @@
+DELETE FROM audit_log WHERE target_role <> '';
+
+ALTER TABLE audit_log
+  DROP COLUMN IF EXISTS target_role;
+
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS fk_users_role;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,29 @@
+-- Roles map to permissions (see pkg/permissions). admin is built in and
+-- always has every permission, so it has no rows in role_permissions.
+CREATE TABLE IF NOT EXISTS roles (
+  name        text PRIMARY KEY,
+  description text NOT NULL DEFAULT '',
+  created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role       text NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  permission text NOT NULL,
+  PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+  ('user', 'Customer'),
+  ('admin', 'Full access')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name)
+SELECT DISTINCT role FROM users
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE users
+  ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles(name);
+
+-- Role changes are audited too; they name the role instead of a target user.
+ALTER TABLE audit_log
+  ADD COLUMN IF NOT EXISTS target_role text NOT NULL DEFAULT '';
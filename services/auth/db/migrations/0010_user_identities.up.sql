@@ -0,0 +1,31 @@
+-- External OpenID Connect accounts linked to users. Users created by a
+-- social login have an empty password_hash until they set a password
+-- through password reset.
+CREATE TABLE IF NOT EXISTS user_identities (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider      text NOT NULL,
+  subject       text NOT NULL,
+  email         text NOT NULL DEFAULT '',
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  last_login_at timestamptz,
+  UNIQUE (provider, subject),
+  UNIQUE (user_id, provider)
+);
+
+-- A sign-in started at a provider and waiting for its callback. user_id is
+-- set when a signed-in user links another account.
+CREATE TABLE IF NOT EXISTS oidc_logins (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  provider      text NOT NULL,
+  state_hash    text NOT NULL UNIQUE,
+  nonce         text NOT NULL,
+  code_verifier text NOT NULL,
+  user_id       uuid REFERENCES users(id) ON DELETE CASCADE,
+  expires_at    timestamptz NOT NULL,
+  used_at       timestamptz,
+  created_at    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_logins_expires_at
+  ON oidc_logins (expires_at);
@@ -0,0 +1,75 @@
+package cartclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/pkg/middleware/servicetoken"
+	"github.com/google/uuid"
+)
+
+// GuestCookieName is the cookie the cart service sets for anonymous carts.
+const GuestCookieName = "guestCart"
+
+type Client struct {
+	baseURL       string
+	internalToken string
+	httpClient    *http.Client
+}
+
+func NewClient(cartServiceURL, internalToken string) *Client {
+	return &Client{
+		baseURL:       cartServiceURL,
+		internalToken: internalToken,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+type mergeRequest struct {
+	UserID     uuid.UUID `json:"user_id"`
+	GuestToken string    `json:"guest_token"`
+}
+
+// MergeGuestCart asks the cart service to move the guest cart identified by
+// guestToken into the cart of userID.
+func (c *Client) MergeGuestCart(ctx context.Context, userID uuid.UUID, guestToken string) error {
+	mergeURL, err := url.JoinPath(c.baseURL, "internal", "cart", "merge")
+	if err != nil {
+		return fmt.Errorf("build merge url: %w", err)
+	}
+
+	body, err := json.Marshal(mergeRequest{UserID: userID, GuestToken: guestToken})
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, mergeURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(servicetoken.HeaderName, c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("merge guest cart failed with status: %d", resp.StatusCode)
+	}
+	return nil
+}
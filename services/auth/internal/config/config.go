@@ -3,11 +3,17 @@ package config
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	pkgconfig "github.com/Skotchmaster/online_shop/pkg/config"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -16,6 +22,95 @@ type Config struct {
 	AuthURL       string
 	JWTSecret     []byte
 	RefreshSecret []byte
+	CartURL       string
+	InternalToken string
+
+	Mailer    string
+	MailDir   string
+	BaseURL   string
+	VerifyTTL time.Duration
+	ResetTTL  time.Duration
+
+	PasswordMinLength  int
+	PasswordMinClasses int
+	PasswordBlocklist  string
+
+	LoginUserFreeAttempts int
+	LoginUserLockoutAfter int
+	LoginIPFreeAttempts   int
+	LoginIPLockoutAfter   int
+	LoginBackoffMax       time.Duration
+	LoginLockoutFor       time.Duration
+
+	TOTPKey         []byte
+	TOTPIssuer      string
+	// RequireAdmin2FA makes TOTP mandatory for every role granting one of
+	// permissions.Privileged, admin included.
+	RequireAdmin2FA bool
+
+	RefreshReuseGrace time.Duration
+
+	// KeysDir holds the access token signing keys; empty keeps HS256 with
+	// JWTSecret. When it is set JWTSecret is ignored.
+	KeysDir    string
+	KeyOverlap time.Duration
+	KeyAlg     string
+
+	// KafkaBrokers receives access token revocations; empty keeps them
+	// local to this instance.
+	KafkaBrokers []string
+
+	// BootstrapAdmin becomes admin on start while there is no active admin:
+	// the account is created with BootstrapAdminPassword, or promoted if it
+	// already has that password.
+	BootstrapAdmin         string
+	BootstrapAdminPassword string
+
+	// OIDCProviders are the identity providers offered for social login.
+	OIDCProviders []oidc.Config
+}
+
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Fatalf("invalid %s %q", name, v)
+	}
+	return n
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("invalid %s %q", name, v)
+	}
+	return d
+}
+
+func envBool(name string, def bool) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("invalid %s %q", name, v)
+	}
+	return b
+}
+
+func envDefault(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
 }
 
 func must(v string, name string) string {
@@ -25,12 +120,79 @@ func must(v string, name string) string {
 	return v
 }
 
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// loadOIDCProviders reads OIDC_<NAME>_* for every name in OIDC_PROVIDERS.
+func loadOIDCProviders(baseURL string) []oidc.Config {
+	var providers []oidc.Config
+	for _, name := range pkgconfig.CSV(os.Getenv("OIDC_PROVIDERS")) {
+		if !providerName.MatchString(name) || name == "providers" {
+			log.Fatalf("invalid OIDC provider name %q", name)
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, oidc.Config{
+			Name:         name,
+			Issuer:       must(os.Getenv(prefix+"ISSUER"), prefix+"ISSUER"),
+			ClientID:     must(os.Getenv(prefix+"CLIENT_ID"), prefix+"CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  envDefault(prefix+"REDIRECT_URL", strings.TrimRight(baseURL, "/")+"/api/v1/auth/oauth/"+name+"/callback"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		})
+	}
+	return providers
+}
+
 func Load() *Config {
 	cfg := &Config{
 		AuthURL:    must(os.Getenv("AUTH_URL"), "AUTH_URL"),
-		JWTSecret:  []byte(must(os.Getenv("JWT_SECRET"), "JWT_SECRET")),
+		JWTSecret:  []byte(os.Getenv("JWT_SECRET")),
 		RefreshSecret:  []byte(must(os.Getenv("REFRESH_SECRET"), "REFRESH_SECRET")),
+		CartURL:        os.Getenv("CART_URL"),
+		InternalToken:  os.Getenv("INTERNAL_API_TOKEN"),
+		// No default: log and file write live tokens, so the choice is explicit.
+		Mailer:         must(os.Getenv("MAILER"), "MAILER"),
+		MailDir:        envDefault("MAIL_DIR", "mail"),
+		BaseURL:        envDefault("APP_BASE_URL", "http://localhost:8080"),
+		VerifyTTL:      envDuration("EMAIL_VERIFY_TTL", 48*time.Hour),
+		ResetTTL:       envDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordMinLength:  envInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMinClasses: envInt("PASSWORD_MIN_CLASSES", 3),
+		PasswordBlocklist:  os.Getenv("PASSWORD_BLOCKLIST_FILE"),
+		LoginUserFreeAttempts: envInt("LOGIN_USER_FREE_ATTEMPTS", 3),
+		LoginUserLockoutAfter: envInt("LOGIN_USER_LOCKOUT_AFTER", 10),
+		LoginIPFreeAttempts:   envInt("LOGIN_IP_FREE_ATTEMPTS", 20),
+		LoginIPLockoutAfter:   envInt("LOGIN_IP_LOCKOUT_AFTER", 100),
+		LoginBackoffMax:       envDuration("LOGIN_BACKOFF_MAX", 15*time.Minute),
+		LoginLockoutFor:       envDuration("LOGIN_LOCKOUT_DURATION", time.Hour),
+		TOTPIssuer:            envDefault("TOTP_ISSUER", "Online Shop"),
+		RequireAdmin2FA:       envBool("REQUIRE_ADMIN_2FA", true),
+		RefreshReuseGrace:     envDuration("REFRESH_REUSE_GRACE", 5*time.Second),
+		KeysDir:               os.Getenv("JWT_KEYS_DIR"),
+		KeyOverlap:            envDuration("JWT_KEY_OVERLAP", 5*time.Minute),
+		KeyAlg:                envDefault("JWT_KEY_ALG", "EdDSA"),
+		KafkaBrokers:          pkgconfig.CSV(os.Getenv("KAFKA_BROKERS")),
+		BootstrapAdmin:        os.Getenv("BOOTSTRAP_ADMIN"),
+	}
+	cfg.OIDCProviders = loadOIDCProviders(cfg.BaseURL)
+	if cfg.BootstrapAdmin != "" {
+		cfg.BootstrapAdminPassword = must(os.Getenv("BOOTSTRAP_ADMIN_PASSWORD"), "BOOTSTRAP_ADMIN_PASSWORD")
+	}
+	if cfg.KeysDir == "" {
+		must(string(cfg.JWTSecret), "JWT_SECRET")
+	}
+	if cfg.KeyAlg != "EdDSA" && cfg.KeyAlg != "RS256" {
+		log.Fatalf("invalid JWT_KEY_ALG %q: want EdDSA or RS256", cfg.KeyAlg)
+	}
+	if cfg.Mailer != "log" && cfg.Mailer != "file" {
+		log.Fatalf("invalid MAILER %q: want log or file", cfg.Mailer)
+	}
+	// Every deployment needs its own key: a shared one would let anyone who
+	// reads the users table decrypt the TOTP secrets.
+	key, err := base64.StdEncoding.DecodeString(must(os.Getenv("TOTP_ENCRYPTION_KEY"), "TOTP_ENCRYPTION_KEY"))
+	if err != nil || len(key) != 32 {
+		log.Fatalf("invalid TOTP_ENCRYPTION_KEY: want 32 bytes in base64")
 	}
+	cfg.TOTPKey = key
 	return cfg
 }
 
@@ -0,0 +1,107 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *AuthHTTP) ListLoginAttempts(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_login_attempts")
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	attempts, err := h.Svc.ListLoginAttempts(ctx, c.QueryParam("username"), c.QueryParam("ip"), limit)
+	if err != nil {
+		l.Error("login_attempts_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+	return c.JSON(http.StatusOK, attempts)
+}
+
+func (h *AuthHTTP) UnlockLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_unlock_login")
+
+	actorID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("unlock_login_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	var req struct {
+		Username string `json:"username"`
+		IP       string `json:"ip"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("unlock_login_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	cleared, err := h.Svc.UnlockLogin(ctx, actorID, req.Username, req.IP)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("unlock_login_failed", "status", 400, "reason", "username or ip is required", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "username or ip is required")
+		}
+		l.Error("unlock_login_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("unlock_login_successful", "username", req.Username, "ip", req.IP, "cleared", cleared)
+	return c.JSON(http.StatusOK, echo.Map{
+		"cleared": cleared,
+	})
+}
+
+func (h *AuthHTTP) RevokeUserSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_revoke_user_sessions")
+
+	actorID, userID, err := adminTarget(c, l, "revoke_user_sessions_failed")
+	if err != nil {
+		return err
+	}
+
+	revoked, err := h.Svc.RevokeUserSessions(ctx, actorID, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("revoke_user_sessions_failed", "status", 404, "reason", "user not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+		}
+		l.Error("revoke_user_sessions_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("revoke_user_sessions_successful", "user_id", userID, "revoked", revoked)
+	return c.JSON(http.StatusOK, echo.Map{
+		"revoked": revoked,
+	})
+}
+
+func (h *AuthHTTP) ListSecurityEvents(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_security_events")
+
+	var userID *uuid.UUID
+	if v := c.QueryParam("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			l.Warn("security_events_failed", "status", 400, "reason", "invalid user id", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid user id")
+		}
+		userID = &id
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	events, err := h.Svc.ListSecurityEvents(ctx, userID, limit)
+	if err != nil {
+		l.Error("security_events_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+	return c.JSON(http.StatusOK, events)
+}
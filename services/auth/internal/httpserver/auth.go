@@ -2,11 +2,17 @@ package httpserver
 
 import (
 	"errors"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 
 	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
 	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/cartclient"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/password"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
@@ -20,6 +26,7 @@ func (h *AuthHTTP) Register(c echo.Context) error {
 
 	var req struct {
 		Username string `json:"username"`
+		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
 
@@ -28,7 +35,11 @@ func (h *AuthHTTP) Register(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
 	}
 
-	if err := h.Svc.Register(ctx, req.Username, req.Password); err != nil {
+	user, err := h.Svc.RegisterUser(ctx, req.Username, req.Email, req.Password)
+	if err != nil {
+		if httpErr := weakPasswordError(l, "register_failed", err); httpErr != nil {
+			return httpErr
+		}
 		if errors.Is(err, service.ErrValidation) {
 			l.Warn("register_failed", "status", 400, "reason", "invalid credentials", "error", err)
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid credentials")
@@ -41,6 +52,7 @@ func (h *AuthHTTP) Register(c echo.Context) error {
 		l.Error("register_failed", "status", 500, "reason", "registration failed", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "registration failed")
 	}
+	h.mergeGuestCart(c, user.ID)
 
 	return c.JSON(http.StatusOK, echo.Map{
 		"username": req.Username,
@@ -64,6 +76,13 @@ func (h *AuthHTTP) Login(c echo.Context) error {
 
 	res, err := h.Svc.Login(ctx, req.Username, req.Password)
 	if err != nil {
+		if httpErr := loginLockedError(c, l, "login_failed", err); httpErr != nil {
+			return httpErr
+		}
+		if errors.Is(err, service.ErrAccountDisabled) {
+			l.Warn("login_failed", "status", 403, "reason", "account disabled", "error", err)
+			return echo.NewHTTPError(http.StatusForbidden, "account disabled")
+		}
 		if errors.Is(err, service.ErrUnauthorized){
 			l.Warn("login_failed", "status", 401, "reason", "invalid username or password", "error", err)
 			return echo.NewHTTPError(http.StatusUnauthorized, "invalid username or password")
@@ -77,11 +96,17 @@ func (h *AuthHTTP) Login(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
 	}
 
+	if res.Challenge != nil {
+		l.Info("login_challenge_issued", "user_id", res.UserID, "setup", res.Challenge.SetupRequired)
+		return c.JSON(http.StatusOK, res.Challenge)
+	}
+
 	accessCookie := jwthelp.CreateCookie("accessToken", res.AccessToken, "/", res.AccessExp)
 	c.SetCookie(accessCookie)
 
 	refreshCookie := jwthelp.CreateCookie("refreshToken", res.RefreshToken, "/", res.RefreshExp)
 	c.SetCookie(refreshCookie)
+	h.mergeGuestCart(c, res.UserID)
 	l.Info("login_successful")
 
 	return c.JSON(http.StatusOK, echo.Map{
@@ -101,6 +126,17 @@ func (h *AuthHTTP) LogOut(c echo.Context) error {
         refreshTokenValue = refreshCookie.Value
     }
 
+    var accessTokenValue string
+    accessCookie, err := c.Cookie("accessToken")
+    if err == nil && accessCookie != nil {
+        accessTokenValue = accessCookie.Value
+    }
+
+    if err := h.Svc.RevokeAccessToken(ctx, accessTokenValue); err != nil {
+        l.Error("logout_failed", "status", 500, "reason", "internal error", "error", err)
+        return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+    }
+
     if err := h.Svc.LogOut(ctx, refreshTokenValue); err != nil {
         c.SetCookie(jwthelp.DeleteCookie("refreshToken", "/"))
         c.SetCookie(jwthelp.DeleteCookie("accessToken", "/"))
@@ -148,4 +184,47 @@ func (h *AuthHTTP) Refresh(c echo.Context) error {
 	l.Info("refresh_successful")
 	return c.JSON(http.StatusOK, res)
 }
- 
\ No newline at end of file
+ 
+
+// weakPasswordError reports every failed password rule to the client, or
+// returns nil when err is not a policy failure.
+func weakPasswordError(l *slog.Logger, event string, err error) error {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	l.Warn(event, "status", 400, "reason", "weak password", "error", err)
+	return echo.NewHTTPError(http.StatusBadRequest, echo.Map{
+		"message":    "password does not meet the policy",
+		"violations": policyErr.Violations,
+	})
+}
+
+// mergeGuestCart moves the visitor's guest cart into their user cart. A failed
+// merge must not fail the login, so the guest cookie is kept for a later try.
+func (h *AuthHTTP) mergeGuestCart(c echo.Context, userID uuid.UUID) {
+	cookie, err := c.Cookie(cartclient.GuestCookieName)
+	if err != nil || cookie.Value == "" {
+		return
+	}
+
+	ctx := c.Request().Context()
+	if err := h.Svc.MergeGuestCart(ctx, userID, cookie.Value); err != nil {
+		logging.FromContext(ctx).Warn("guest_cart_merge_failed", "user_id", userID, "error", err)
+		return
+	}
+	c.SetCookie(jwthelp.DeleteCookie(cartclient.GuestCookieName, "/"))
+}
+
+// loginLockedError answers 429 with Retry-After when err is a login lock and
+// returns nil otherwise.
+func loginLockedError(c echo.Context, l *slog.Logger, event string, err error) error {
+	var locked *service.LockedError
+	if !errors.As(err, &locked) {
+		return nil
+	}
+	retry := int(math.Ceil(locked.RetryAfter.Seconds()))
+	l.Warn(event, "status", 429, "reason", "too many attempts", "retry_after", retry)
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(retry))
+	return echo.NewHTTPError(http.StatusTooManyRequests, "too many login attempts")
+}
@@ -0,0 +1,121 @@
+package httpserver
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *AuthHTTP) VerifyEmail(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_verify_email")
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("verify_email_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	if err := h.Svc.VerifyEmail(ctx, req.Token); err != nil {
+		return tokenFlowError(l, "verify_email_failed", err)
+	}
+
+	l.Info("verify_email_successful")
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "email verified",
+	})
+}
+
+func (h *AuthHTTP) ResendVerification(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_resend_verification")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("resend_verification_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+
+	if err := h.Svc.ResendVerification(ctx, userID); err != nil {
+		if errors.Is(err, service.ErrConflict) {
+			l.Warn("resend_verification_failed", "status", 409, "reason", "email already verified", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "email already verified")
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			l.Warn("resend_verification_failed", "status", 401, "reason", "user not found", "error", err)
+			return echo.NewHTTPError(http.StatusUnauthorized, "user not found")
+		}
+		return tokenFlowError(l, "resend_verification_failed", err)
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"message": "verification email sent",
+	})
+}
+
+func (h *AuthHTTP) ForgotPassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_forgot_password")
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("forgot_password_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	if err := h.Svc.RequestPasswordReset(ctx, req.Email); err != nil {
+		return tokenFlowError(l, "forgot_password_failed", err)
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"message": "if the email is registered, a reset link has been sent",
+	})
+}
+
+func (h *AuthHTTP) ResetPassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_reset_password")
+
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("reset_password_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	if err := h.Svc.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		return tokenFlowError(l, "reset_password_failed", err)
+	}
+
+	l.Info("reset_password_successful")
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "password changed",
+	})
+}
+
+func tokenFlowError(l *slog.Logger, event string, err error) error {
+	if httpErr := weakPasswordError(l, event, err); httpErr != nil {
+		return httpErr
+	}
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		l.Warn(event, "status", 400, "reason", "validation error", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "validation error")
+	case errors.Is(err, service.ErrInvalidToken):
+		l.Warn(event, "status", 400, "reason", "invalid or expired token", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid or expired token")
+	}
+	l.Error(event, "status", 500, "reason", "internal error", "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+}
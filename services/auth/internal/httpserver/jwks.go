@@ -0,0 +1,28 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/labstack/echo/v4"
+)
+
+// JWKS publishes the public keys access tokens are signed with. Verifiers
+// refetch on an unknown kid, so a short cache is enough.
+func (h *AuthHTTP) JWKS(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_jwks")
+
+	set := tokens.JWKS{Keys: []tokens.JWK{}}
+	if h.Svc.Keys != nil {
+		var err error
+		if set, err = h.Svc.Keys.JWKS(); err != nil {
+			l.Error("jwks_failed", "status", 500, "reason", "internal error", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(http.StatusOK, set)
+}
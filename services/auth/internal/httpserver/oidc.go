@@ -0,0 +1,221 @@
+package httpserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// oidcStateCookie binds a sign-in at a provider to the browser that started
+// it, so a callback URL cannot be replayed in another browser.
+const oidcStateCookie = "oidcState"
+
+func (h *AuthHTTP) ListOIDCProviders(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"providers": h.Svc.OIDCProviders(),
+	})
+}
+
+// StartOIDCLogin redirects the browser to the provider.
+func (h *AuthHTTP) StartOIDCLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_oidc_start")
+
+	return h.startOIDC(c, l, "oidc_start_failed", uuid.Nil)
+}
+
+// LinkOIDCIdentity redirects a signed-in user to the provider to add that
+// account to theirs.
+func (h *AuthHTTP) LinkOIDCIdentity(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_oidc_link")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("oidc_link_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	return h.startOIDC(c, l, "oidc_link_failed", userID)
+}
+
+func (h *AuthHTTP) startOIDC(c echo.Context, l *slog.Logger, event string, linkUser uuid.UUID) error {
+	provider := c.Param("provider")
+	start, err := h.Svc.StartOIDCLogin(c.Request().Context(), provider, linkUser, c.QueryParam("login_hint"))
+	if err != nil {
+		return oidcError(l, event, err)
+	}
+
+	c.SetCookie(jwthelp.CreateCookie(oidcStateCookie, start.State, "/", start.ExpiresAt))
+	l.Info("oidc_redirect", "provider", provider, "link", linkUser != uuid.Nil)
+	return c.Redirect(http.StatusFound, start.URL)
+}
+
+// OIDCCallback finishes a sign-in or link with the code the provider sent
+// back. A sign-in answers like /login.
+func (h *AuthHTTP) OIDCCallback(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_oidc_callback")
+	provider := c.Param("provider")
+
+	if reason := c.QueryParam("error"); reason != "" {
+		l.Warn("oidc_callback_failed", "status", 401, "reason", "denied by identity provider", "provider", provider, "error", reason)
+		return echo.NewHTTPError(http.StatusUnauthorized, echo.Map{
+			"message": "sign-in denied by identity provider",
+			"error":   reason,
+		})
+	}
+
+	state := c.QueryParam("state")
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		l.Warn("oidc_callback_failed", "status", 401, "reason", "state does not match this browser", "provider", provider)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired state")
+	}
+	c.SetCookie(jwthelp.DeleteCookie(oidcStateCookie, "/"))
+
+	res, identity, err := h.Svc.CompleteOIDCLogin(ctx, provider, state, c.QueryParam("code"))
+	if err != nil {
+		return oidcError(l, "oidc_callback_failed", err)
+	}
+	if identity != nil {
+		l.Info("oidc_link_successful", "user_id", identity.UserID, "provider", provider)
+		return c.JSON(http.StatusOK, identity)
+	}
+	if res.Challenge != nil {
+		l.Info("login_challenge_issued", "user_id", res.UserID, "setup", res.Challenge.SetupRequired)
+		return c.JSON(http.StatusOK, res.Challenge)
+	}
+
+	accessCookie := jwthelp.CreateCookie("accessToken", res.AccessToken, "/", res.AccessExp)
+	c.SetCookie(accessCookie)
+
+	refreshCookie := jwthelp.CreateCookie("refreshToken", res.RefreshToken, "/", res.RefreshExp)
+	c.SetCookie(refreshCookie)
+	h.mergeGuestCart(c, res.UserID)
+	l.Info("login_successful", "user_id", res.UserID, "provider", provider)
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"is_admin": res.IsAdmin,
+	})
+}
+
+func (h *AuthHTTP) ListIdentities(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_list_identities")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("list_identities_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+
+	identities, err := h.Svc.ListIdentities(ctx, userID)
+	if err != nil {
+		l.Error("list_identities_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+	return c.JSON(http.StatusOK, identities)
+}
+
+func (h *AuthHTTP) SetPassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_set_password")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("set_password_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("set_password_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	if err := h.Svc.SetPassword(ctx, userID, req.Password); err != nil {
+		if httpErr := weakPasswordError(l, "set_password_failed", err); httpErr != nil {
+			return httpErr
+		}
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			l.Warn("set_password_failed", "status", 400, "reason", "validation error", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "validation error")
+		case errors.Is(err, service.ErrNotFound):
+			l.Warn("set_password_failed", "status", 401, "reason", "user not found", "error", err)
+			return echo.NewHTTPError(http.StatusUnauthorized, "user not found")
+		case errors.Is(err, service.ErrConflict):
+			l.Warn("set_password_failed", "status", 409, "reason", "password already set", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "password already set, use the password reset to change it")
+		}
+		l.Error("set_password_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("set_password_successful", "user_id", userID)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHTTP) UnlinkIdentity(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_unlink_identity")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("unlink_identity_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+
+	provider := c.Param("provider")
+	if err := h.Svc.UnlinkIdentity(ctx, userID, provider); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			l.Warn("unlink_identity_failed", "status", 404, "reason", "identity not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "identity not found")
+		case errors.Is(err, service.ErrConflict):
+			l.Warn("unlink_identity_failed", "status", 409, "reason", "last sign-in method", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "set a password before unlinking the last identity")
+		}
+		l.Error("unlink_identity_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("unlink_identity_successful", "user_id", userID, "provider", provider)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func oidcError(l *slog.Logger, event string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		l.Warn(event, "status", 400, "reason", "state and code are required", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "state and code are required")
+	case errors.Is(err, service.ErrNotFound):
+		l.Warn(event, "status", 404, "reason", "unknown identity provider", "error", err)
+		return echo.NewHTTPError(http.StatusNotFound, "unknown identity provider")
+	case errors.Is(err, service.ErrInvalidToken):
+		l.Warn(event, "status", 401, "reason", "invalid or expired state", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired state")
+	case errors.Is(err, service.ErrUnauthorized):
+		l.Warn(event, "status", 401, "reason", "rejected by identity provider", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "sign-in rejected by identity provider")
+	case errors.Is(err, service.ErrAccountDisabled):
+		l.Warn(event, "status", 403, "reason", "account disabled", "error", err)
+		return echo.NewHTTPError(http.StatusForbidden, "account disabled")
+	case errors.Is(err, service.ErrConflict):
+		l.Warn(event, "status", 409, "reason", "account exists or identity linked", "error", err)
+		return echo.NewHTTPError(http.StatusConflict, "account exists or identity is linked to another user")
+	case errors.Is(err, service.ErrProviderUnavailable):
+		l.Error(event, "status", 502, "reason", "identity provider unavailable", "error", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "identity provider unavailable")
+	}
+	l.Error(event, "status", 500, "reason", "internal error", "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+}
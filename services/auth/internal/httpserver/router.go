@@ -3,27 +3,72 @@ package httpserver
 import (
 	"net/http"
 
+	"github.com/Skotchmaster/online_shop/pkg/permissions"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/middleware"
 	"github.com/labstack/echo/v4"
 )
 
 type Deps struct {
 	AuthHandler *AuthHTTP
-	JWT_Secret []byte
+	Verifier    *tokens.Verifier
 }
 
 func Register(e *echo.Echo, d *Deps) {
 	e.GET("/health/live", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
 	e.GET("/health/ready", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
 
-	authMw := middleware.NewSimpleAuth(d.JWT_Secret)
+	authMw := middleware.NewSimpleAuth(d.Verifier)
+	e.Use(clientinfo.Middleware)
 
+	e.GET("/.well-known/jwks.json", d.AuthHandler.JWKS)
 	e.POST("/register", d.AuthHandler.Register)
 	e.POST("/login", d.AuthHandler.Login)
+	e.POST("/login/2fa", d.AuthHandler.LoginSecondFactor)
+	e.POST("/2fa/enrol", d.AuthHandler.EnrolTOTP)
 	e.POST("/refresh", d.AuthHandler.Refresh)
+	e.POST("/email/verify", d.AuthHandler.VerifyEmail)
+	e.POST("/password/forgot", d.AuthHandler.ForgotPassword)
+	e.POST("/password/reset", d.AuthHandler.ResetPassword)
+	e.GET("/oauth/providers", d.AuthHandler.ListOIDCProviders)
+	e.GET("/oauth/:provider", d.AuthHandler.StartOIDCLogin)
+	e.GET("/oauth/:provider/callback", d.AuthHandler.OIDCCallback)
 
 	private := e.Group("")
 	private.Use(authMw.RequireAuth)
 	
 	private.POST("/logout", d.AuthHandler.LogOut)
+	private.POST("/email/verify/resend", d.AuthHandler.ResendVerification)
+	private.POST("/2fa/setup", d.AuthHandler.SetupTOTP)
+	private.POST("/2fa/enable", d.AuthHandler.EnableTOTP)
+	private.POST("/2fa/disable", d.AuthHandler.DisableTOTP)
+	private.POST("/2fa/recovery-codes", d.AuthHandler.RegenerateRecoveryCodes)
+	private.GET("/sessions", d.AuthHandler.ListSessions)
+	private.DELETE("/sessions/:id", d.AuthHandler.RevokeSession)
+	private.POST("/sessions/revoke-others", d.AuthHandler.RevokeOtherSessions)
+	private.GET("/oauth/:provider/link", d.AuthHandler.LinkOIDCIdentity)
+	private.GET("/identities", d.AuthHandler.ListIdentities)
+	private.DELETE("/identities/:provider", d.AuthHandler.UnlinkIdentity)
+	private.POST("/password/set", d.AuthHandler.SetPassword)
+
+	admin := private.Group("/admin")
+
+	audit := admin.Group("", authMw.RequirePermission(permissions.AuditRead))
+	audit.GET("/login-attempts", d.AuthHandler.ListLoginAttempts)
+	audit.GET("/security-events", d.AuthHandler.ListSecurityEvents)
+	audit.GET("/audit-log", d.AuthHandler.ListAuditLog)
+
+	users := admin.Group("", authMw.RequirePermission(permissions.UsersManage))
+	users.POST("/login-locks/unlock", d.AuthHandler.UnlockLogin)
+	users.DELETE("/users/:id/sessions", d.AuthHandler.RevokeUserSessions)
+	users.GET("/users", d.AuthHandler.ListUsers)
+	users.GET("/users/:id", d.AuthHandler.GetUser)
+	users.PUT("/users/:id/role", d.AuthHandler.ChangeUserRole)
+	users.POST("/users/:id/disable", d.AuthHandler.DisableUser)
+	users.POST("/users/:id/enable", d.AuthHandler.EnableUser)
+	users.DELETE("/users/:id", d.AuthHandler.DeleteUser)
+	users.GET("/roles", d.AuthHandler.ListRoles)
+	users.PUT("/roles/:name", d.AuthHandler.SaveRole)
+	users.DELETE("/roles/:name", d.AuthHandler.DeleteRole)
 }
\ No newline at end of file
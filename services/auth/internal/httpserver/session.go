@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func refreshCookieValue(c echo.Context) string {
+	cookie, err := c.Cookie("refreshToken")
+	if err != nil || cookie == nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func (h *AuthHTTP) ListSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_list_sessions")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("list_sessions_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+
+	sessions, err := h.Svc.ListSessions(ctx, userID, refreshCookieValue(c))
+	if err != nil {
+		l.Error("list_sessions_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+func (h *AuthHTTP) RevokeSession(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_revoke_session")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("revoke_session_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("revoke_session_failed", "status", 400, "reason", "invalid session id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid session id")
+	}
+
+	if err := h.Svc.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("revoke_session_failed", "status", 404, "reason", "session not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "session not found")
+		}
+		l.Error("revoke_session_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("revoke_session_successful", "session_id", sessionID)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHTTP) RevokeOtherSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_revoke_other_sessions")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("revoke_sessions_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+
+	revoked, err := h.Svc.RevokeOtherSessions(ctx, userID, refreshCookieValue(c))
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("revoke_sessions_failed", "status", 400, "reason", "missing or invalid refresh token", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "missing or invalid refresh token")
+		}
+		l.Error("revoke_sessions_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("revoke_sessions_successful", "revoked", revoked)
+	return c.JSON(http.StatusOK, echo.Map{
+		"revoked": revoked,
+	})
+}
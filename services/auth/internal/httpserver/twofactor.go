@@ -0,0 +1,202 @@
+package httpserver
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type secondFactorRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (h *AuthHTTP) LoginSecondFactor(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_login_2fa")
+
+	var req struct {
+		Challenge string `json:"challenge"`
+		secondFactorRequest
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("login_2fa_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	res, recovery, err := h.Svc.CompleteLogin(ctx, req.Challenge, req.Code, req.RecoveryCode)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			l.Warn("login_2fa_failed", "status", 401, "reason", "invalid or expired challenge", "error", err)
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired challenge")
+		}
+		if httpErr := loginLockedError(c, l, "login_2fa_failed", err); httpErr != nil {
+			return httpErr
+		}
+		return twoFactorError(l, "login_2fa_failed", err)
+	}
+
+	accessCookie := jwthelp.CreateCookie("accessToken", res.AccessToken, "/", res.AccessExp)
+	c.SetCookie(accessCookie)
+
+	refreshCookie := jwthelp.CreateCookie("refreshToken", res.RefreshToken, "/", res.RefreshExp)
+	c.SetCookie(refreshCookie)
+	h.mergeGuestCart(c, res.UserID)
+	l.Info("login_successful", "user_id", res.UserID)
+
+	resp := echo.Map{
+		"is_admin": res.IsAdmin,
+	}
+	if recovery != nil {
+		resp["recovery_codes"] = recovery
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// EnrolTOTP opens the enrolment link mailed by a setup challenge.
+func (h *AuthHTTP) EnrolTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_2fa_enrol")
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("2fa_enrol_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	setup, err := h.Svc.EnrolTOTP(ctx, req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			l.Warn("2fa_enrol_failed", "status", 400, "reason", "invalid or expired token", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid or expired token")
+		}
+		return twoFactorError(l, "2fa_enrol_failed", err)
+	}
+	return c.JSON(http.StatusOK, setup)
+}
+
+func (h *AuthHTTP) SetupTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_2fa_setup")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("2fa_setup_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+
+	setup, err := h.Svc.SetupTOTP(ctx, userID)
+	if err != nil {
+		return twoFactorError(l, "2fa_setup_failed", err)
+	}
+	return c.JSON(http.StatusOK, setup)
+}
+
+func (h *AuthHTTP) EnableTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_2fa_enable")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("2fa_enable_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	var req secondFactorRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("2fa_enable_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	codes, err := h.Svc.EnableTOTP(ctx, userID, req.Code)
+	if err != nil {
+		return twoFactorError(l, "2fa_enable_failed", err)
+	}
+	l.Info("2fa_enabled", "user_id", userID)
+	return c.JSON(http.StatusOK, echo.Map{
+		"recovery_codes": codes,
+	})
+}
+
+func (h *AuthHTTP) DisableTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_2fa_disable")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("2fa_disable_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	var req secondFactorRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("2fa_disable_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	if err := h.Svc.DisableTOTP(ctx, userID, req.Code, req.RecoveryCode); err != nil {
+		return twoFactorError(l, "2fa_disable_failed", err)
+	}
+	l.Info("2fa_disabled", "user_id", userID)
+	return c.JSON(http.StatusOK, echo.Map{
+		"message": "two-factor authentication disabled",
+	})
+}
+
+func (h *AuthHTTP) RegenerateRecoveryCodes(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "auth_2fa_recovery_codes")
+
+	userID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("recovery_codes_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	var req secondFactorRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("recovery_codes_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	codes, err := h.Svc.RegenerateRecoveryCodes(ctx, userID, req.Code)
+	if err != nil {
+		return twoFactorError(l, "recovery_codes_failed", err)
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"recovery_codes": codes,
+	})
+}
+
+func twoFactorError(l *slog.Logger, event string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrAccountDisabled):
+		l.Warn(event, "status", 403, "reason", "account disabled", "error", err)
+		return echo.NewHTTPError(http.StatusForbidden, "account disabled")
+	case errors.Is(err, service.ErrTwoFactorOff):
+		l.Warn(event, "status", 409, "reason", "two-factor authentication is not enabled", "error", err)
+		return echo.NewHTTPError(http.StatusConflict, "two-factor authentication is not enabled")
+	case errors.Is(err, service.ErrConflict):
+		l.Warn(event, "status", 409, "reason", "two-factor authentication already enabled", "error", err)
+		return echo.NewHTTPError(http.StatusConflict, "two-factor authentication already enabled")
+	case errors.Is(err, service.ErrForbidden):
+		l.Warn(event, "status", 403, "reason", "two-factor authentication is mandatory", "error", err)
+		return echo.NewHTTPError(http.StatusForbidden, "two-factor authentication is mandatory")
+	case errors.Is(err, service.ErrInvalidCode):
+		l.Warn(event, "status", 401, "reason", "invalid code", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid code")
+	case errors.Is(err, service.ErrValidation):
+		l.Warn(event, "status", 400, "reason", "validation error", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "validation error")
+	case errors.Is(err, service.ErrUnauthorized):
+		l.Warn(event, "status", 401, "reason", "user not found", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not found")
+	}
+	l.Error(event, "status", 500, "reason", "internal error", "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+}
@@ -0,0 +1,279 @@
+package httpserver
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// userAdminError maps the errors of the user management calls.
+func userAdminError(l *slog.Logger, event string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		l.Warn(event, "status", 404, "reason", "user not found", "error", err)
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	case errors.Is(err, service.ErrValidation):
+		l.Warn(event, "status", 400, "reason", "validation error", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "validation error")
+	case errors.Is(err, service.ErrNotGrantable):
+		l.Warn(event, "status", 403, "reason", "permission not held", "error", err)
+		return echo.NewHTTPError(http.StatusForbidden, "you cannot grant permissions you do not have")
+	case errors.Is(err, service.ErrForbidden):
+		l.Warn(event, "status", 403, "reason", "change of own account", "error", err)
+		return echo.NewHTTPError(http.StatusForbidden, "admins cannot change their own account")
+	case errors.Is(err, service.ErrConflict):
+		l.Warn(event, "status", 409, "reason", "last active admin", "error", err)
+		return echo.NewHTTPError(http.StatusConflict, "the last active admin cannot be removed")
+	}
+	l.Error(event, "status", 500, "reason", "internal error", "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+}
+
+// adminTarget returns the signed-in admin and the user named by the :id
+// path parameter.
+func adminTarget(c echo.Context, l *slog.Logger, event string) (actorID, userID uuid.UUID, err error) {
+	actorID, err = uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn(event, "status", 401, "reason", "invalid user id", "error", err)
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	userID, err = uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn(event, "status", 400, "reason", "invalid user id", "error", err)
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "invalid user id")
+	}
+	return actorID, userID, nil
+}
+
+func (h *AuthHTTP) ListUsers(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_list_users")
+
+	filter := repo.UserFilter{
+		Query: c.QueryParam("q"),
+		Role:  c.QueryParam("role"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	filter.Offset, _ = strconv.Atoi(c.QueryParam("offset"))
+	if v := c.QueryParam("disabled"); v != "" {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			l.Warn("list_users_failed", "status", 400, "reason", "invalid disabled flag", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid disabled flag")
+		}
+		filter.Disabled = &disabled
+	}
+
+	page, err := h.Svc.ListUsers(ctx, filter)
+	if err != nil {
+		return userAdminError(l, "list_users_failed", err)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *AuthHTTP) GetUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_get_user")
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("get_user_failed", "status", 400, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user id")
+	}
+
+	user, err := h.Svc.GetUser(ctx, userID)
+	if err != nil {
+		return userAdminError(l, "get_user_failed", err)
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+func (h *AuthHTTP) ChangeUserRole(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_change_user_role")
+
+	actorID, userID, err := adminTarget(c, l, "change_role_failed")
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("change_role_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	user, err := h.Svc.ChangeUserRole(ctx, actorID, userID, req.Role)
+	if err != nil {
+		return userAdminError(l, "change_role_failed", err)
+	}
+	l.Info("change_role_successful", "user_id", userID, "role", user.Role)
+	return c.JSON(http.StatusOK, user)
+}
+
+func (h *AuthHTTP) DisableUser(c echo.Context) error {
+	return h.setUserDisabled(c, true)
+}
+
+func (h *AuthHTTP) EnableUser(c echo.Context) error {
+	return h.setUserDisabled(c, false)
+}
+
+func (h *AuthHTTP) setUserDisabled(c echo.Context, disabled bool) error {
+	ctx := c.Request().Context()
+	event := "enable_user"
+	if disabled {
+		event = "disable_user"
+	}
+	l := logging.FromContext(ctx).With("handler", "admin_"+event)
+
+	actorID, userID, err := adminTarget(c, l, event+"_failed")
+	if err != nil {
+		return err
+	}
+
+	var user *models.User
+	if disabled {
+		user, err = h.Svc.DisableUser(ctx, actorID, userID)
+	} else {
+		user, err = h.Svc.EnableUser(ctx, actorID, userID)
+	}
+	if err != nil {
+		return userAdminError(l, event+"_failed", err)
+	}
+	l.Info(event+"_successful", "user_id", userID)
+	return c.JSON(http.StatusOK, user)
+}
+
+func (h *AuthHTTP) DeleteUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_delete_user")
+
+	actorID, userID, err := adminTarget(c, l, "delete_user_failed")
+	if err != nil {
+		return err
+	}
+
+	if err := h.Svc.DeleteUser(ctx, actorID, userID); err != nil {
+		return userAdminError(l, "delete_user_failed", err)
+	}
+	l.Info("delete_user_successful", "user_id", userID)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHTTP) ListAuditLog(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_audit_log")
+
+	var actorID, targetID *uuid.UUID
+	if v := c.QueryParam("actor_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			l.Warn("audit_log_failed", "status", 400, "reason", "invalid actor id", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid actor id")
+		}
+		actorID = &id
+	}
+	if v := c.QueryParam("target_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			l.Warn("audit_log_failed", "status", 400, "reason", "invalid target id", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid target id")
+		}
+		targetID = &id
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	entries, err := h.Svc.ListAuditLog(ctx, actorID, targetID, limit)
+	if err != nil {
+		l.Error("audit_log_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+func (h *AuthHTTP) ListRoles(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_list_roles")
+
+	roles, err := h.Svc.ListRoles(ctx)
+	if err != nil {
+		l.Error("list_roles_failed", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+	return c.JSON(http.StatusOK, roles)
+}
+
+func (h *AuthHTTP) SaveRole(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_save_role")
+
+	actorID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("save_role_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+	var req struct {
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("save_role_failed", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	role, err := h.Svc.SaveRole(ctx, actorID, c.Param("name"), req.Description, req.Permissions)
+	if err != nil {
+		return roleError(l, "save_role_failed", err)
+	}
+	l.Info("save_role_successful", "role", role.Name, "permissions", role.Permissions)
+	return c.JSON(http.StatusOK, role)
+}
+
+func (h *AuthHTTP) DeleteRole(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "admin_delete_role")
+
+	actorID, err := uuid.Parse(c.Get("user_id").(string))
+	if err != nil {
+		l.Warn("delete_role_failed", "status", 401, "reason", "invalid user id", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user id")
+	}
+
+	if err := h.Svc.DeleteRole(ctx, actorID, c.Param("name")); err != nil {
+		return roleError(l, "delete_role_failed", err)
+	}
+	l.Info("delete_role_successful", "role", c.Param("name"))
+	return c.NoContent(http.StatusNoContent)
+}
+
+func roleError(l *slog.Logger, event string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		l.Warn(event, "status", 404, "reason", "role not found", "error", err)
+		return echo.NewHTTPError(http.StatusNotFound, "role not found")
+	case errors.Is(err, service.ErrValidation):
+		l.Warn(event, "status", 400, "reason", "validation error", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "validation error")
+	case errors.Is(err, service.ErrNotGrantable):
+		l.Warn(event, "status", 403, "reason", "permission not held", "error", err)
+		return echo.NewHTTPError(http.StatusForbidden, "you cannot grant permissions you do not have")
+	case errors.Is(err, service.ErrForbidden):
+		l.Warn(event, "status", 403, "reason", "built-in role", "error", err)
+		return echo.NewHTTPError(http.StatusForbidden, "built-in role cannot be changed")
+	case errors.Is(err, service.ErrConflict):
+		l.Warn(event, "status", 409, "reason", "role in use", "error", err)
+		return echo.NewHTTPError(http.StatusConflict, "role is assigned to users")
+	}
+	l.Error(event, "status", 500, "reason", "internal error", "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+}
@@ -3,19 +3,32 @@ package tests
 import (
 	"context"
 	"errors"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/Skotchmaster/online_shop/pkg/permissions"
+	"github.com/Skotchmaster/online_shop/pkg/revocation"
 	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/mailer"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc/oidctest"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/secretbox"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/service"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/totp"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type integrationEnv struct {
@@ -34,7 +47,10 @@ func newIntegrationEnv(t *testing.T) *integrationEnv {
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&models.User{}, &models.RefreshToken{}))
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.UserToken{}, &models.LoginThrottle{}, &models.LoginAttempt{}, &models.RecoveryCode{}, &models.LoginChallenge{}, &models.SecurityEvent{}, &models.RevokedAccessToken{}, &models.TokenWatermark{}, &models.AuditEntry{}, &models.Role{}, &models.RolePermission{}, &models.UserIdentity{}, &models.OIDCLogin{}))
+	// AutoMigrate cannot express the expression index from the migrations.
+	require.NoError(t, db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (lower(email))").Error)
+	require.NoError(t, db.Clauses(clause.OnConflict{DoNothing: true}).Create([]models.Role{{Name: service.RoleUser}, {Name: service.RoleAdmin}}).Error)
 
 	rp := repo.GormRepo{
 		DB:            db,
@@ -60,32 +76,92 @@ func newIntegrationEnv(t *testing.T) *integrationEnv {
 func truncateTables(t *testing.T, db *gorm.DB) {
 	t.Helper()
 
-	db.Exec("TRUNCATE TABLE refresh_tokens, users RESTART IDENTITY CASCADE")
+	db.Exec("TRUNCATE TABLE oidc_logins, user_identities, role_permissions, roles, audit_log, revoked_access_tokens, token_watermarks, security_events, login_challenges, recovery_codes, login_throttles, login_attempts, user_tokens, refresh_tokens, users RESTART IDENTITY CASCADE")
 }
 
 func uniqueUsername() string {
 	return "u_" + uuid.NewString()
 }
 
+func uniqueEmail() string {
+	return "u_" + uuid.NewString() + "@example.com"
+}
+
+type captureMailer struct {
+	sent []mailer.Message
+}
+
+func (m *captureMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// tokenFromMail extracts the token query parameter from the link in a mail.
+func tokenFromMail(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+
+	for _, field := range strings.Fields(msg.Body) {
+		if u, err := url.Parse(field); err == nil && u.Query().Get("token") != "" {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no token in mail %q", msg.Body)
+	return ""
+}
+
 func TestAuthService_Register_SuccessAndConflict(t *testing.T) {
 	env := newIntegrationEnv(t)
 	ctx := context.Background()
 	username := uniqueUsername()
 
-	err := env.svc.Register(ctx, username, "Secret123")
+	err := env.svc.Register(ctx, username, uniqueEmail(), "Secret123")
 	require.NoError(t, err)
 
-	err = env.svc.Register(ctx, username, "Secret123")
+	err = env.svc.Register(ctx, username, uniqueEmail(), "Secret123")
 	require.Error(t, err)
 	assert.ErrorIs(t, err, service.ErrConflict)
 }
 
+func TestAuthService_Register_ConcurrentSameEmail(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	email := uniqueEmail()
+
+	const callers = 10
+	errs := make(chan error, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addr := email
+			if i%2 == 1 {
+				addr = strings.ToUpper(email)
+			}
+			_, err := env.svc.RegisterUser(ctx, uniqueUsername(), addr, "Secret123")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, err, service.ErrConflict, "a lost race is a conflict, not an internal error")
+	}
+	assert.Equal(t, 1, created)
+}
+
 func TestAuthService_Login_Success_IssuesTokens(t *testing.T) {
 	env := newIntegrationEnv(t)
 	ctx := context.Background()
 	username := uniqueUsername()
 
-	require.NoError(t, env.svc.Register(ctx, username, "Secret123"))
+	require.NoError(t, env.svc.Register(ctx, username, uniqueEmail(), "Secret123"))
 
 	res, err := env.svc.Login(ctx, username, "Secret123")
 	require.NoError(t, err)
@@ -112,7 +188,7 @@ func TestAuthService_Refresh_Success_RotatesToken(t *testing.T) {
 	ctx := context.Background()
 	username := uniqueUsername()
 
-	require.NoError(t, env.svc.Register(ctx, username, "Secret123"))
+	require.NoError(t, env.svc.Register(ctx, username, uniqueEmail(), "Secret123"))
 	loginRes, err := env.svc.Login(ctx, username, "Secret123")
 	require.NoError(t, err)
 
@@ -142,7 +218,7 @@ func TestAuthService_LogOut_RevokesRefreshToken(t *testing.T) {
 	ctx := context.Background()
 	username := uniqueUsername()
 
-	require.NoError(t, env.svc.Register(ctx, username, "Secret123"))
+	require.NoError(t, env.svc.Register(ctx, username, uniqueEmail(), "Secret123"))
 	loginRes, err := env.svc.Login(ctx, username, "Secret123")
 	require.NoError(t, err)
 
@@ -161,7 +237,7 @@ func TestAuthService_Refresh_RevokedToken_ReturnsInvalidRefresh(t *testing.T) {
 	ctx := context.Background()
 	username := uniqueUsername()
 
-	require.NoError(t, env.svc.Register(ctx, username, "Secret123"))
+	require.NoError(t, env.svc.Register(ctx, username, uniqueEmail(), "Secret123"))
 	loginRes, err := env.svc.Login(ctx, username, "Secret123")
 	require.NoError(t, err)
 
@@ -172,3 +248,683 @@ func TestAuthService_Refresh_RevokedToken_ReturnsInvalidRefresh(t *testing.T) {
 	assert.Nil(t, res)
 	assert.True(t, errors.Is(err, service.ErrInvalidRefreshToken))
 }
+
+func TestAuthService_VerifyEmail_TokenIsSingleUse(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	mail := &captureMailer{}
+	env.svc.Mailer = mail
+
+	user, err := env.svc.RegisterUser(ctx, uniqueUsername(), uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	require.Len(t, mail.sent, 1)
+	token := tokenFromMail(t, mail.sent[0])
+
+	require.NoError(t, env.svc.VerifyEmail(ctx, token))
+	stored, err := env.rp.GetUserById(ctx, user.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored.EmailVerifiedAt)
+
+	err = env.svc.VerifyEmail(ctx, token)
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestAuthService_ResetPassword_ChangesPasswordAndRevokesSessions(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	mail := &captureMailer{}
+	env.svc.Mailer = mail
+	username := uniqueUsername()
+	email := uniqueEmail()
+
+	require.NoError(t, env.svc.Register(ctx, username, email, "Secret123"))
+	loginRes, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.RequestPasswordReset(ctx, email))
+	require.Len(t, mail.sent, 2)
+	token := tokenFromMail(t, mail.sent[1])
+
+	require.NoError(t, env.svc.ResetPassword(ctx, token, "NewSecret456"))
+
+	_, err = env.svc.Login(ctx, username, "Secret123")
+	assert.ErrorIs(t, err, service.ErrUnauthorized)
+	_, err = env.svc.Login(ctx, username, "NewSecret456")
+	require.NoError(t, err)
+
+	_, err = env.svc.Refresh(ctx, loginRes.RefreshToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+	err = env.svc.ResetPassword(ctx, token, "Another789")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestAuthService_Login_LocksAfterFailuresAndAdminUnlocks(t *testing.T) {
+	env := newIntegrationEnv(t)
+	env.svc.Throttle = &service.LoginThrottle{
+		User:        service.ThrottleRule{FreeAttempts: 1, LockoutAfter: 3},
+		IP:          service.ThrottleRule{FreeAttempts: 100, LockoutAfter: 1000},
+		BackoffBase: time.Millisecond,
+		BackoffMax:  time.Millisecond,
+		LockoutFor:  time.Hour,
+	}
+	ctx := clientinfo.IntoContext(context.Background(), clientinfo.Info{IP: "203.0.113.7"})
+	username := uniqueUsername()
+	require.NoError(t, env.svc.Register(ctx, username, uniqueEmail(), "Secret123"))
+
+	for i := 0; i < 3; i++ {
+		_, err := env.svc.Login(ctx, username, "Wrong-pass1")
+		require.ErrorIs(t, err, service.ErrUnauthorized)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	_, err := env.svc.Login(ctx, username, "Secret123")
+	var locked *service.LockedError
+	require.ErrorAs(t, err, &locked)
+	assert.Greater(t, locked.RetryAfter, 50*time.Minute)
+
+	attempts, err := env.svc.ListLoginAttempts(ctx, username, "", 0)
+	require.NoError(t, err)
+	assert.Len(t, attempts, 4)
+
+	adminID := uuid.New()
+	cleared, err := env.svc.UnlockLogin(ctx, adminID, username, "")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, cleared)
+
+	entries, err := env.svc.ListAuditLog(ctx, &adminID, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.AuditLoginUnlocked, entries[0].Action)
+	assert.Contains(t, entries[0].Details, username)
+	assert.Equal(t, "203.0.113.7", entries[0].IP)
+
+	_, err = env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+}
+
+func TestAuthService_Login_AdminEnrolsTOTPAndUsesRecoveryCode(t *testing.T) {
+	env := newIntegrationEnv(t)
+	box, err := secretbox.New([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	env.svc.TwoFactor = &service.TwoFactorConfig{Box: box, Issuer: "Test", RequiredPermissions: permissions.Privileged}
+	ctx := context.Background()
+	username := uniqueUsername()
+
+	user, err := env.svc.RegisterUser(ctx, username, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	require.NoError(t, env.db.Model(&models.User{}).Where("id = ?", user.ID).Update("role", "admin").Error)
+
+	res, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+	require.NotNil(t, res.Challenge)
+	assert.Empty(t, res.AccessToken)
+	assert.True(t, res.Challenge.SetupRequired)
+	require.NotEmpty(t, res.Challenge.Secret)
+
+	_, _, err = env.svc.CompleteLogin(ctx, res.Challenge.Token, "000000", "")
+	require.ErrorIs(t, err, service.ErrInvalidCode)
+
+	code, err := totp.Code(res.Challenge.Secret, time.Now())
+	require.NoError(t, err)
+	session, recovery, err := env.svc.CompleteLogin(ctx, res.Challenge.Token, code, "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, session.AccessToken)
+	assert.True(t, session.IsAdmin)
+	require.Len(t, recovery, 10)
+	events, err := env.rp.ListSecurityEvents(ctx, &user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.SecurityTwoFactorEnabled, events[0].Type)
+	assert.Equal(t, "enrolled at login", events[0].Details)
+
+	_, _, err = env.svc.CompleteLogin(ctx, res.Challenge.Token, code, "")
+	require.ErrorIs(t, err, service.ErrInvalidToken)
+
+	res, err = env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+	require.NotNil(t, res.Challenge)
+	assert.False(t, res.Challenge.SetupRequired)
+	assert.Empty(t, res.Challenge.Secret)
+
+	_, _, err = env.svc.CompleteLogin(ctx, res.Challenge.Token, code, "")
+	require.ErrorIs(t, err, service.ErrInvalidCode, "a code must not be accepted twice")
+
+	session, _, err = env.svc.CompleteLogin(ctx, res.Challenge.Token, "", strings.ToUpper(recovery[0]))
+	require.NoError(t, err)
+	assert.NotEmpty(t, session.RefreshToken)
+
+	res, err = env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+	_, _, err = env.svc.CompleteLogin(ctx, res.Challenge.Token, "", recovery[0])
+	require.ErrorIs(t, err, service.ErrInvalidCode, "recovery codes are single use")
+
+	err = env.svc.DisableTOTP(ctx, user.ID, "", recovery[1])
+	require.ErrorIs(t, err, service.ErrForbidden)
+}
+
+func TestAuthService_Login_EnrolmentIsMailedToVerifiedEmail(t *testing.T) {
+	env := newIntegrationEnv(t)
+	box, err := secretbox.New([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	env.svc.TwoFactor = &service.TwoFactorConfig{Box: box, Issuer: "Test", RequiredPermissions: permissions.Privileged}
+	mail := &captureMailer{}
+	env.svc.Mailer = mail
+	ctx := context.Background()
+	username := uniqueUsername()
+
+	user, err := env.svc.RegisterUser(ctx, username, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	require.Len(t, mail.sent, 1)
+	require.NoError(t, env.svc.VerifyEmail(ctx, tokenFromMail(t, mail.sent[0])))
+	require.NoError(t, env.db.Model(&models.User{}).Where("id = ?", user.ID).Update("role", "admin").Error)
+
+	res, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+	require.NotNil(t, res.Challenge)
+	assert.True(t, res.Challenge.SetupRequired)
+	assert.True(t, res.Challenge.EnrolmentMailed)
+	assert.Empty(t, res.Challenge.Secret, "the password alone must not reveal the secret")
+	assert.Empty(t, res.Challenge.URI)
+	require.Len(t, mail.sent, 2)
+
+	_, _, err = env.svc.CompleteLogin(ctx, res.Challenge.Token, "000000", "")
+	require.ErrorIs(t, err, service.ErrValidation, "no secret before the link is opened")
+
+	token := tokenFromMail(t, mail.sent[1])
+	setup, err := env.svc.EnrolTOTP(ctx, token)
+	require.NoError(t, err)
+	require.NotEmpty(t, setup.Secret)
+	_, err = env.svc.EnrolTOTP(ctx, token)
+	require.ErrorIs(t, err, service.ErrInvalidToken, "the link is single use")
+
+	code, err := totp.Code(setup.Secret, time.Now())
+	require.NoError(t, err)
+	session, recovery, err := env.svc.CompleteLogin(ctx, res.Challenge.Token, code, "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, session.AccessToken)
+	assert.Len(t, recovery, 10)
+}
+
+func TestAuthService_CompleteLogin_WrongCodesLockTheUser(t *testing.T) {
+	env := newIntegrationEnv(t)
+	box, err := secretbox.New([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	env.svc.TwoFactor = &service.TwoFactorConfig{Box: box, Issuer: "Test", RequiredPermissions: permissions.Privileged}
+	env.svc.Throttle = &service.LoginThrottle{
+		User:        service.ThrottleRule{FreeAttempts: 1, LockoutAfter: 3},
+		IP:          service.ThrottleRule{FreeAttempts: 100, LockoutAfter: 1000},
+		BackoffBase: time.Millisecond,
+		BackoffMax:  time.Millisecond,
+		LockoutFor:  time.Hour,
+	}
+	ctx := clientinfo.IntoContext(context.Background(), clientinfo.Info{IP: "203.0.113.8"})
+	username := uniqueUsername()
+
+	user, err := env.svc.RegisterUser(ctx, username, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	require.NoError(t, env.db.Model(&models.User{}).Where("id = ?", user.ID).Update("role", "admin").Error)
+
+	// A fresh challenge per round: the correct password must not reset the
+	// failures collected by wrong codes.
+	for i := 0; i < 3; i++ {
+		res, err := env.svc.Login(ctx, username, "Secret123")
+		require.NoError(t, err)
+		require.NotNil(t, res.Challenge)
+
+		_, _, err = env.svc.CompleteLogin(ctx, res.Challenge.Token, "000000", "")
+		require.ErrorIs(t, err, service.ErrInvalidCode)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	_, err = env.svc.Login(ctx, username, "Secret123")
+	var locked *service.LockedError
+	require.ErrorAs(t, err, &locked)
+	assert.Greater(t, locked.RetryAfter, 50*time.Minute)
+
+	attempts, err := env.svc.ListLoginAttempts(ctx, username, "", 0)
+	require.NoError(t, err)
+	codeFailures := 0
+	for _, a := range attempts {
+		if a.Reason == models.LoginFailedSecondFactor {
+			codeFailures++
+		}
+	}
+	assert.Equal(t, 3, codeFailures)
+}
+
+func TestAuthService_Sessions_ListAndRevoke(t *testing.T) {
+	env := newIntegrationEnv(t)
+	username := uniqueUsername()
+	laptop := clientinfo.IntoContext(context.Background(), clientinfo.Info{
+		IP:        "198.51.100.1",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0",
+	})
+	phone := clientinfo.IntoContext(context.Background(), clientinfo.Info{IP: "198.51.100.2", UserAgent: "curl/8.5.0"})
+
+	user, err := env.svc.RegisterUser(laptop, username, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	first, err := env.svc.Login(laptop, username, "Secret123")
+	require.NoError(t, err)
+	second, err := env.svc.Login(phone, username, "Secret123")
+	require.NoError(t, err)
+	third, err := env.svc.Login(phone, username, "Secret123")
+	require.NoError(t, err)
+
+	sessions, err := env.svc.ListSessions(laptop, user.ID, first.RefreshToken)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	var current uuid.UUID
+	for _, s := range sessions {
+		if s.Current {
+			current = s.ID
+			assert.Equal(t, "Firefox on Linux", s.Device)
+			assert.Equal(t, "198.51.100.1", s.IP)
+		}
+	}
+	require.NotEqual(t, uuid.Nil, current)
+
+	refreshed, err := env.svc.Refresh(phone, first.RefreshToken)
+	require.NoError(t, err)
+	sessions, err = env.svc.ListSessions(laptop, user.ID, refreshed.RefreshToken)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3, "rotation keeps the session")
+	for _, s := range sessions {
+		if s.Current {
+			assert.Equal(t, current, s.ID)
+			assert.Equal(t, "198.51.100.2", s.IP)
+		}
+	}
+
+	thirdClaims, err := tokens.RefreshClaimsFromToken(third.RefreshToken, env.rp.RefreshSecret)
+	require.NoError(t, err)
+	thirdRow, err := env.rp.FindRefreshByID(laptop, thirdClaims.ID)
+	require.NoError(t, err)
+	require.NoError(t, env.svc.RevokeSession(laptop, user.ID, thirdRow.SessionID))
+	require.ErrorIs(t, env.svc.RevokeSession(laptop, user.ID, thirdRow.SessionID), service.ErrNotFound)
+
+	revoked, err := env.svc.RevokeOtherSessions(laptop, user.ID, refreshed.RefreshToken)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, revoked)
+	_, err = env.svc.Refresh(phone, second.RefreshToken)
+	require.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+	adminID := uuid.New()
+	revoked, err = env.svc.RevokeUserSessions(laptop, adminID, user.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, revoked)
+	entries, err := env.svc.ListAuditLog(laptop, &adminID, &user.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.AuditSessionsRevoked, entries[0].Action)
+	_, err = env.svc.Refresh(laptop, refreshed.RefreshToken)
+	require.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+}
+
+func TestAuthService_Refresh_ReusedTokenRevokesFamily(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	username := uniqueUsername()
+
+	user, err := env.svc.RegisterUser(ctx, username, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	other, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+	loginRes, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+	rotated, err := env.svc.Refresh(ctx, loginRes.RefreshToken)
+	require.NoError(t, err)
+
+	env.svc.RefreshReuseGrace = time.Hour
+	_, err = env.svc.Refresh(ctx, loginRes.RefreshToken)
+	require.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+	events, err := env.svc.ListSecurityEvents(ctx, &user.ID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, events, "reuse within the grace period is not an incident")
+
+	env.svc.RefreshReuseGrace = 0
+	_, err = env.svc.Refresh(ctx, loginRes.RefreshToken)
+	require.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+	_, err = env.svc.Refresh(ctx, rotated.RefreshToken)
+	require.ErrorIs(t, err, service.ErrInvalidRefreshToken, "the whole family is revoked")
+	_, err = env.svc.Refresh(ctx, other.RefreshToken)
+	require.NoError(t, err, "other sessions are not affected")
+
+	events, err = env.svc.ListSecurityEvents(ctx, &user.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.SecurityRefreshReuse, events[0].Type)
+}
+
+func TestAuthService_RevokeAccessTokens(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	username := uniqueUsername()
+	env.svc.Revocations = revocation.New()
+
+	user, err := env.svc.RegisterUser(ctx, username, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	loggedOut, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+	kept, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+
+	loggedOutClaims, err := tokens.AccessClaimsFromToken(loggedOut.AccessToken, env.rp.JWTSecret)
+	require.NoError(t, err)
+	keptClaims, err := tokens.AccessClaimsFromToken(kept.AccessToken, env.rp.JWTSecret)
+	require.NoError(t, err)
+	require.NotEmpty(t, loggedOutClaims.ID)
+	require.NotEqual(t, loggedOutClaims.ID, keptClaims.ID)
+
+	require.NoError(t, env.svc.RevokeAccessToken(ctx, loggedOut.AccessToken))
+	assert.True(t, env.svc.Revocations.Revoked(loggedOutClaims))
+	assert.False(t, env.svc.Revocations.Revoked(keptClaims))
+
+	_, err = env.svc.RevokeUserSessions(ctx, uuid.New(), user.ID)
+	require.NoError(t, err)
+	older := *keptClaims
+	older.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	assert.True(t, env.svc.Revocations.Revoked(&older), "tokens issued before the watermark are revoked")
+
+	restarted := &service.AuthService{Repo: env.rp, Revocations: revocation.New()}
+	require.NoError(t, restarted.LoadRevocations(ctx))
+	assert.True(t, restarted.Revocations.Revoked(loggedOutClaims))
+	assert.True(t, restarted.Revocations.Revoked(&older))
+}
+
+func TestAuthService_AdminManagesUsers(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	adminName, username := uniqueUsername(), uniqueUsername()
+
+	admin, err := env.svc.RegisterUser(ctx, adminName, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	require.NoError(t, env.svc.BootstrapAdmin(ctx, adminName, "Secret123"))
+	user, err := env.svc.RegisterUser(ctx, username, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	loginRes, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+
+	page, err := env.svc.ListUsers(ctx, repo.UserFilter{Query: username[2:10]})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, page.Total)
+	assert.Equal(t, user.ID, page.Users[0].ID)
+
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, admin.ID, "user")
+	require.ErrorIs(t, err, service.ErrForbidden)
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, user.ID, "superuser")
+	require.ErrorIs(t, err, service.ErrValidation)
+	changed, err := env.svc.ChangeUserRole(ctx, admin.ID, user.ID, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", changed.Role)
+
+	_, err = env.svc.DisableUser(ctx, admin.ID, user.ID)
+	require.NoError(t, err)
+	_, err = env.svc.Login(ctx, username, "Secret123")
+	require.ErrorIs(t, err, service.ErrAccountDisabled)
+	_, err = env.svc.Refresh(ctx, loginRes.RefreshToken)
+	require.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+	_, err = env.svc.EnableUser(ctx, admin.ID, user.ID)
+	require.NoError(t, err)
+	_, err = env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, user.ID, "user")
+	require.NoError(t, err)
+	err = env.svc.DeleteUser(ctx, user.ID, admin.ID)
+	require.ErrorIs(t, err, service.ErrConflict, "the last active admin stays")
+
+	require.NoError(t, env.svc.DeleteUser(ctx, admin.ID, user.ID))
+	_, err = env.svc.GetUser(ctx, user.ID)
+	require.ErrorIs(t, err, service.ErrNotFound)
+
+	entries, err := env.svc.ListAuditLog(ctx, nil, &user.ID, 0)
+	require.NoError(t, err)
+	actions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+		assert.Equal(t, admin.ID, *e.ActorID)
+	}
+	assert.Equal(t, []string{
+		models.AuditUserDeleted, models.AuditRoleChanged, models.AuditUserEnabled,
+		models.AuditUserDisabled, models.AuditRoleChanged,
+	}, actions)
+}
+
+func TestAuthService_BootstrapAdmin(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	squatted, adminName, lateName := uniqueUsername(), uniqueUsername(), uniqueUsername()
+
+	squatter, err := env.svc.RegisterUser(ctx, squatted, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	err = env.svc.BootstrapAdmin(ctx, squatted, "Operator-pass1")
+	require.ErrorIs(t, err, service.ErrConflict, "a registered name is not promoted without its password")
+	user, err := env.svc.GetUser(ctx, squatter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, service.RoleUser, user.Role)
+
+	require.NoError(t, env.svc.BootstrapAdmin(ctx, adminName, "Operator-pass1"))
+	loginRes, err := env.svc.Login(ctx, adminName, "Operator-pass1")
+	require.NoError(t, err)
+	claims, err := tokens.AccessClaimsFromToken(loginRes.AccessToken, env.rp.JWTSecret)
+	require.NoError(t, err)
+	assert.Equal(t, service.RoleAdmin, claims.Role)
+
+	require.NoError(t, env.svc.BootstrapAdmin(ctx, lateName, "Operator-pass1"), "an active admin makes it a no-op")
+	_, err = env.svc.Login(ctx, lateName, "Operator-pass1")
+	require.ErrorIs(t, err, service.ErrUnauthorized)
+
+	adminID, err := uuid.Parse(claims.Subject)
+	require.NoError(t, err)
+	entries, err := env.svc.ListAuditLog(ctx, nil, &adminID, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.AuditUserCreated, entries[0].Action)
+	assert.Nil(t, entries[0].ActorID)
+}
+
+func TestAuthService_RolesGrantPermissions(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	adminName, username := uniqueUsername(), uniqueUsername()
+
+	admin, err := env.svc.RegisterUser(ctx, adminName, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	require.NoError(t, env.svc.BootstrapAdmin(ctx, adminName, "Secret123"))
+	user, err := env.svc.RegisterUser(ctx, username, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+
+	_, err = env.svc.SaveRole(ctx, admin.ID, "warehouse", "Stock keepers", []string{"catalog:delete"})
+	require.ErrorIs(t, err, service.ErrValidation)
+	_, err = env.svc.SaveRole(ctx, admin.ID, service.RoleAdmin, "", nil)
+	require.ErrorIs(t, err, service.ErrForbidden)
+	role, err := env.svc.SaveRole(ctx, admin.ID, "warehouse", "Stock keepers", []string{permissions.OrdersUpdateStatus, permissions.CatalogWrite, permissions.CatalogWrite})
+	require.NoError(t, err)
+	assert.Equal(t, []string{permissions.CatalogWrite, permissions.OrdersUpdateStatus}, role.Permissions)
+
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, user.ID, "warehouse")
+	require.NoError(t, err)
+	loginRes, err := env.svc.Login(ctx, username, "Secret123")
+	require.NoError(t, err)
+	claims, err := tokens.AccessClaimsFromToken(loginRes.AccessToken, env.rp.JWTSecret)
+	require.NoError(t, err)
+	assert.Equal(t, "warehouse", claims.Role)
+	assert.True(t, claims.HasScope(permissions.CatalogWrite))
+	assert.False(t, claims.HasScope(permissions.UsersManage))
+
+	err = env.svc.DeleteRole(ctx, admin.ID, "warehouse")
+	require.ErrorIs(t, err, service.ErrConflict)
+	err = env.svc.DeleteRole(ctx, admin.ID, service.RoleUser)
+	require.ErrorIs(t, err, service.ErrForbidden)
+
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, user.ID, service.RoleUser)
+	require.NoError(t, err)
+	require.NoError(t, env.svc.DeleteRole(ctx, admin.ID, "warehouse"))
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, user.ID, "warehouse")
+	require.ErrorIs(t, err, service.ErrValidation)
+}
+
+func TestAuthService_RoleManagersCannotEscalate(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	adminName, managerName, staffName := uniqueUsername(), uniqueUsername(), uniqueUsername()
+
+	admin, err := env.svc.RegisterUser(ctx, adminName, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	require.NoError(t, env.svc.BootstrapAdmin(ctx, adminName, "Secret123"))
+	manager, err := env.svc.RegisterUser(ctx, managerName, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	staff, err := env.svc.RegisterUser(ctx, staffName, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+
+	_, err = env.svc.SaveRole(ctx, admin.ID, "support", "", []string{permissions.UsersManage})
+	require.NoError(t, err)
+	_, err = env.svc.SaveRole(ctx, admin.ID, "warehouse", "", []string{permissions.CatalogWrite})
+	require.NoError(t, err)
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, manager.ID, "support")
+	require.NoError(t, err)
+
+	_, err = env.svc.ChangeUserRole(ctx, manager.ID, staff.ID, service.RoleAdmin)
+	require.ErrorIs(t, err, service.ErrNotGrantable, "admin holds more than support")
+	_, err = env.svc.ChangeUserRole(ctx, manager.ID, staff.ID, "warehouse")
+	require.ErrorIs(t, err, service.ErrNotGrantable)
+	_, err = env.svc.ChangeUserRole(ctx, manager.ID, admin.ID, service.RoleUser)
+	require.ErrorIs(t, err, service.ErrNotGrantable, "an admin cannot be demoted by a lesser role")
+	_, err = env.svc.SaveRole(ctx, manager.ID, "support", "", []string{permissions.UsersManage, permissions.AuditRead})
+	require.ErrorIs(t, err, service.ErrNotGrantable)
+	_, err = env.svc.SaveRole(ctx, manager.ID, "warehouse", "", nil)
+	require.ErrorIs(t, err, service.ErrNotGrantable, "permissions the actor lacks cannot be taken away either")
+	err = env.svc.DeleteRole(ctx, manager.ID, "warehouse")
+	require.ErrorIs(t, err, service.ErrNotGrantable)
+
+	_, err = env.svc.ChangeUserRole(ctx, manager.ID, staff.ID, "support")
+	require.NoError(t, err, "a role within the actor's permissions can be granted")
+	user, err := env.svc.GetUser(ctx, staff.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "support", user.Role)
+}
+
+func TestAuthService_Login_PrivilegedPermissionRequiresTOTP(t *testing.T) {
+	env := newIntegrationEnv(t)
+	box, err := secretbox.New([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	env.svc.TwoFactor = &service.TwoFactorConfig{Box: box, Issuer: "Test", RequiredPermissions: permissions.Privileged}
+	ctx := context.Background()
+	adminName, supportName, stockName := uniqueUsername(), uniqueUsername(), uniqueUsername()
+
+	admin, err := env.svc.RegisterUser(ctx, adminName, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	require.NoError(t, env.svc.BootstrapAdmin(ctx, adminName, "Secret123"))
+
+	_, err = env.svc.SaveRole(ctx, admin.ID, "support", "", []string{permissions.UsersManage})
+	require.NoError(t, err)
+	_, err = env.svc.SaveRole(ctx, admin.ID, "warehouse", "", []string{permissions.CatalogWrite})
+	require.NoError(t, err)
+	support, err := env.svc.RegisterUser(ctx, supportName, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	stock, err := env.svc.RegisterUser(ctx, stockName, uniqueEmail(), "Secret123")
+	require.NoError(t, err)
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, support.ID, "support")
+	require.NoError(t, err)
+
+	res, err := env.svc.Login(ctx, stockName, "Secret123")
+	require.NoError(t, err)
+	require.Nil(t, res.Challenge, "a role without permissions needs no TOTP")
+	stockRefresh := res.RefreshToken
+	_, err = env.svc.ChangeUserRole(ctx, admin.ID, stock.ID, "warehouse")
+	require.NoError(t, err)
+
+	res, err = env.svc.Login(ctx, supportName, "Secret123")
+	require.NoError(t, err)
+	require.NotNil(t, res.Challenge, "users:manage needs TOTP whatever the role is called")
+	assert.True(t, res.Challenge.SetupRequired)
+
+	res, err = env.svc.Login(ctx, stockName, "Secret123")
+	require.NoError(t, err)
+	require.NotNil(t, res.Challenge, "catalog:write alone needs TOTP too")
+	assert.True(t, res.Challenge.SetupRequired)
+	assert.Empty(t, res.AccessToken)
+
+	_, err = env.svc.Refresh(ctx, stockRefresh)
+	require.ErrorIs(t, err, service.ErrInvalidRefreshToken, "a session from before the role change is not renewed until TOTP is set up")
+}
+
+// signInAt runs the browser part of a sign-in at the mock provider and
+// returns the state and code of its callback.
+func signInAt(t *testing.T, env *integrationEnv, idp *oidctest.Provider, linkUser uuid.UUID, email string) (string, string) {
+	t.Helper()
+	start, err := env.svc.StartOIDCLogin(context.Background(), "mock", linkUser, email)
+	require.NoError(t, err)
+	code, state, err := idp.Authorize(start.URL)
+	require.NoError(t, err)
+	require.Equal(t, start.State, state)
+	return state, code
+}
+
+func TestAuthService_OIDCLoginAndLink(t *testing.T) {
+	env := newIntegrationEnv(t)
+	ctx := context.Background()
+	idp := oidctest.NewServer(t, "shop", "secret")
+	env.svc.OIDC = map[string]*oidc.Provider{
+		"mock": oidc.New(idp.Config("mock", "http://shop.test/api/v1/auth/oauth/mock/callback")),
+	}
+
+	socialEmail := uniqueEmail()
+	state, code := signInAt(t, env, idp, uuid.Nil, socialEmail)
+	res, linked, err := env.svc.CompleteOIDCLogin(ctx, "mock", state, code)
+	require.NoError(t, err)
+	require.Nil(t, linked)
+	require.NotEmpty(t, res.AccessToken)
+	require.NotEmpty(t, res.RefreshToken)
+	social, err := env.svc.GetUser(ctx, res.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, socialEmail, *social.Email)
+	assert.NotNil(t, social.EmailVerifiedAt)
+
+	_, _, err = env.svc.CompleteOIDCLogin(ctx, "mock", state, code)
+	require.ErrorIs(t, err, service.ErrInvalidToken, "state is single use")
+
+	state, code = signInAt(t, env, idp, uuid.Nil, socialEmail)
+	again, _, err := env.svc.CompleteOIDCLogin(ctx, "mock", state, code)
+	require.NoError(t, err)
+	assert.Equal(t, social.ID, again.UserID)
+
+	username, email := uniqueUsername(), uniqueEmail()
+	user, err := env.svc.RegisterUser(ctx, username, email, "Secret123")
+	require.NoError(t, err)
+	state, code = signInAt(t, env, idp, uuid.Nil, email)
+	_, _, err = env.svc.CompleteOIDCLogin(ctx, "mock", state, code)
+	require.ErrorIs(t, err, service.ErrConflict, "an account is not taken over by email")
+
+	state, code = signInAt(t, env, idp, user.ID, email)
+	_, linked, err = env.svc.CompleteOIDCLogin(ctx, "mock", state, code)
+	require.NoError(t, err)
+	require.NotNil(t, linked)
+	assert.Equal(t, user.ID, linked.UserID)
+
+	state, code = signInAt(t, env, idp, uuid.Nil, email)
+	res, _, err = env.svc.CompleteOIDCLogin(ctx, "mock", state, code)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, res.UserID)
+
+	err = env.svc.UnlinkIdentity(ctx, social.ID, "mock")
+	require.ErrorIs(t, err, service.ErrConflict, "the only way to sign in stays")
+	require.NoError(t, env.svc.UnlinkIdentity(ctx, user.ID, "mock"))
+	identities, err := env.svc.ListIdentities(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, identities)
+
+	err = env.svc.SetPassword(ctx, user.ID, "Another123")
+	require.ErrorIs(t, err, service.ErrConflict, "an existing password is changed by reset only")
+	err = env.svc.SetPassword(ctx, social.ID, "short")
+	require.ErrorIs(t, err, service.ErrValidation)
+	require.NoError(t, env.svc.SetPassword(ctx, social.ID, "Social-pass1"))
+	social, err = env.svc.GetUser(ctx, social.ID)
+	require.NoError(t, err)
+	_, err = env.svc.Login(ctx, social.Username, "Social-pass1")
+	require.NoError(t, err)
+	require.NoError(t, env.svc.UnlinkIdentity(ctx, social.ID, "mock"), "a password lets the last identity go")
+}
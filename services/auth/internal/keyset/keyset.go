@@ -0,0 +1,255 @@
+// Package keyset holds the private keys access tokens are signed with.
+//
+// Keys are PEM files in one directory, the file name without ".pem" being the
+// kid. Every key in the directory is published in the JWKS. A new key only
+// starts signing once it has been there for the overlap period, so verifiers
+// had time to fetch it; a retired key can be deleted once tokens signed with
+// it have expired.
+package keyset
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+type Key struct {
+	ID      string
+	Private crypto.Signer
+	Method  jwt.SigningMethod
+	// Added is the modification time of the key file.
+	Added time.Time
+}
+
+type Keyset struct {
+	dir     string
+	overlap time.Duration
+
+	mu   sync.RWMutex
+	keys []*Key // oldest first
+}
+
+// Load reads the keys in dir, creating one with alg when there is none.
+func Load(dir string, overlap time.Duration, alg string) (*Keyset, error) {
+	s := &Keyset{dir: dir, overlap: overlap}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	if len(s.keys) > 0 {
+		return s, nil
+	}
+	if _, err := Generate(dir, alg); err != nil {
+		return nil, err
+	}
+	return s, s.Reload()
+}
+
+// Reload rereads the directory, picking up added and removed keys.
+func (s *Keyset) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].Added.Equal(keys[j].Added) {
+			return keys[i].Added.Before(keys[j].Added)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the directory every interval until ctx is done.
+func (s *Keyset) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Reload(); err != nil {
+					slog.Error("signing_keys_reload_failed", "dir", s.dir, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Active returns the newest key published for at least the overlap period,
+// or the oldest key when none is that old yet.
+func (s *Keyset) Active() (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+	cutoff := time.Now().Add(-s.overlap)
+	active := s.keys[0]
+	for _, key := range s.keys[1:] {
+		if key.Added.After(cutoff) {
+			break
+		}
+		active = key
+	}
+	return active, nil
+}
+
+// Sign signs claims with the active key and names it in the kid header.
+func (s *Keyset) Sign(claims jwt.Claims) (string, error) {
+	key, err := s.Active()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// PublicKey makes the keyset a tokens.KeySource for local verification.
+func (s *Keyset) PublicKey(_ context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key.Private.Public(), nil
+		}
+	}
+	return nil, fmt.Errorf("kid %q: %w", kid, tokens.ErrUnknownKey)
+}
+
+func (s *Keyset) JWKS() (tokens.JWKS, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := tokens.JWKS{Keys: make([]tokens.JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwk, err := tokens.NewJWK(key.ID, key.Private.Public())
+		if err != nil {
+			return tokens.JWKS{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// Generate writes a new private key to dir and returns its kid.
+func Generate(dir, alg string) (string, error) {
+	var private crypto.Signer
+	switch alg {
+	case AlgEdDSA, "":
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		private = priv
+	case AlgRS256:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return "", err
+		}
+		private = priv
+	default:
+		return "", fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	kid := time.Now().UTC().Format("20060102T150405Z")
+	path := filepath.Join(dir, kid+".pem")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return "", err
+	}
+	return kid, f.Close()
+}
+
+func readKey(path string) (*Key, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key := &Key{
+		ID:    strings.TrimSuffix(filepath.Base(path), ".pem"),
+		Added: info.ModTime(),
+	}
+	switch priv := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.Private, key.Method = priv, jwt.SigningMethodEdDSA
+	case *rsa.PrivateKey:
+		if priv.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA keys must have at least 2048 bits", path)
+		}
+		key.Private, key.Method = priv, jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+	}
+	return key, nil
+}
@@ -0,0 +1,94 @@
+package keyset
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func accessClaims(sub string) tokens.AccessClaims {
+	return tokens.AccessClaims{
+		Role: "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sub,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+// age backdates every key file so it is past the overlap period.
+func age(t *testing.T, dir string, d time.Duration) {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	require.NoError(t, err)
+	for _, p := range paths {
+		old := time.Now().Add(-d)
+		require.NoError(t, os.Chtimes(p, old, old))
+	}
+}
+
+func TestKeyset_SignAndVerifyThroughJWKS(t *testing.T) {
+	for _, alg := range []string{AlgEdDSA, AlgRS256} {
+		t.Run(alg, func(t *testing.T) {
+			ks, err := Load(t.TempDir(), time.Minute, alg)
+			require.NoError(t, err)
+
+			token, err := ks.Sign(accessClaims("user-1"))
+			require.NoError(t, err)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				set, err := ks.JWKS()
+				require.NoError(t, err)
+				require.NoError(t, json.NewEncoder(w).Encode(set))
+			}))
+			defer srv.Close()
+
+			v := tokens.NewVerifier(tokens.NewJWKSClient(srv.URL, time.Minute), nil)
+			claims, err := v.AccessClaims(context.Background(), token)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims.Subject)
+		})
+	}
+}
+
+func TestKeyset_NewKeyActivatesAfterOverlap(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := Load(dir, time.Hour, AlgEdDSA)
+	require.NoError(t, err)
+	age(t, dir, 2*time.Hour)
+	require.NoError(t, ks.Reload())
+	first, err := ks.Active()
+	require.NoError(t, err)
+
+	time.Sleep(time.Second) // kids are second-resolution timestamps
+	second, err := Generate(dir, AlgEdDSA)
+	require.NoError(t, err)
+	require.NoError(t, ks.Reload())
+
+	set, err := ks.JWKS()
+	require.NoError(t, err)
+	assert.Len(t, set.Keys, 2, "a new key is published at once")
+	active, err := ks.Active()
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, active.ID, "but does not sign during the overlap")
+
+	old := time.Now().Add(-90 * time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, second+".pem"), old, old))
+	require.NoError(t, ks.Reload())
+	active, err = ks.Active()
+	require.NoError(t, err)
+	assert.Equal(t, second, active.ID)
+
+	_, err = ks.PublicKey(context.Background(), first.ID)
+	require.NoError(t, err, "the previous key still verifies")
+}
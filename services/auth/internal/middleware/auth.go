@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/Skotchmaster/online_shop/pkg/tokens"
 	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
@@ -9,11 +10,11 @@ import (
 )
 
 type SimpleAuth struct {
-	JWTSecret []byte
+	Verifier *tokens.Verifier
 }
 
-func NewSimpleAuth(secret []byte) *SimpleAuth {
-	return &SimpleAuth{JWTSecret: secret}
+func NewSimpleAuth(verifier *tokens.Verifier) *SimpleAuth {
+	return &SimpleAuth{Verifier: verifier}
 }
 
 func (m *SimpleAuth) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc {
@@ -23,7 +24,7 @@ func (m *SimpleAuth) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc {
 			return echo.NewHTTPError(http.StatusUnauthorized, "missing access token")
 		}
 
-		claims, err := tokens.AccessClaimsFromToken(accessCookie.Value, m.JWTSecret)
+		claims, err := m.Verifier.AccessClaims(c.Request().Context(), accessCookie.Value)
 		if err != nil || claims == nil {
 			c.SetCookie(jwthelp.DeleteCookie("accessToken", "/"))
 			c.SetCookie(jwthelp.DeleteCookie("refreshToken", "/"))
@@ -32,7 +33,21 @@ func (m *SimpleAuth) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc {
 
 		c.Set("user_id", claims.Subject)
 		c.Set("role", claims.Role)
+		c.Set("permissions", claims.Scopes)
 
 		return next(c)
 	}
-}
\ No newline at end of file
+}
+
+// RequirePermission must run after RequireAuth.
+func (m *SimpleAuth) RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			granted, _ := c.Get("permissions").([]string)
+			if !slices.Contains(granted, permission) {
+				return echo.NewHTTPError(http.StatusForbidden, "permission "+permission+" required")
+			}
+			return next(c)
+		}
+	}
+}
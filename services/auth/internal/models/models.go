@@ -10,6 +10,45 @@ type User struct {
 	Username     string    `json:"username" gorm:"type:text;not null;uniqueIndex"`
 	PasswordHash string    `json:"-" gorm:"type:text;not null"`
 	Role         string    `json:"role" gorm:"type:text;not null"`
+	// Email is nil for accounts created before emails were collected.
+	Email           *string    `json:"email,omitempty" gorm:"type:text"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" gorm:"type:timestamptz"`
+
+	TOTPSecret    *string    `json:"-" gorm:"column:totp_secret;type:text"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at,omitempty" gorm:"column:totp_enabled_at;type:timestamptz"`
+	TOTPLastStep  *int64     `json:"-" gorm:"column:totp_last_step"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamptz;not null;default:now()"`
+	// DisabledAt is set while an admin has the account disabled; such users
+	// cannot log in or refresh.
+	DisabledAt *time.Time `json:"disabled_at,omitempty" gorm:"type:timestamptz"`
+}
+
+func (u *User) TwoFactorEnabled() bool { return u.TOTPEnabledAt != nil }
+
+func (u *User) Disabled() bool { return u.DisabledAt != nil }
+
+// HasPassword is false for users created by a social login until they set a
+// password.
+func (u *User) HasPassword() bool { return u.PasswordHash != "" }
+
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "verify_email"
+	TokenResetPassword TokenPurpose = "reset_password"
+	TokenEnrolTOTP     TokenPurpose = "enrol_totp"
+)
+
+// UserToken is a single-use token sent by email; only its sha256 is stored.
+type UserToken struct {
+	ID        uuid.UUID    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    uuid.UUID    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Purpose   TokenPurpose `json:"purpose" gorm:"type:text;not null"`
+	TokenHash string       `json:"-" gorm:"type:text;not null;uniqueIndex"`
+	ExpiresAt time.Time    `json:"expires_at" gorm:"type:timestamptz;not null"`
+	UsedAt    *time.Time   `json:"used_at" gorm:"type:timestamptz"`
+	CreatedAt time.Time    `json:"created_at" gorm:"type:timestamptz;not null"`
 }
 
 type RefreshToken struct {
@@ -19,4 +58,164 @@ type RefreshToken struct {
 	JTI       string    `json:"jti" gorm:"type:text;not null;uniqueIndex"`
 	ExpiresAt time.Time `json:"expires_at" gorm:"type:timestamptz;not null;index:idx_refresh_expires_at"`
 	Revoked   bool      `json:"revoked" gorm:"not null;default:false;index"`
+
+	// SessionID and CreatedAt are kept across rotations, so SessionID also
+	// names the token family. IP and UserAgent are those of the latest login
+	// or refresh.
+	SessionID  uuid.UUID  `json:"session_id" gorm:"type:uuid;not null;index:idx_refresh_session"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"type:timestamptz;not null"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"type:timestamptz"`
+	IP         string     `json:"ip" gorm:"type:text;not null;default:''"`
+	UserAgent  string     `json:"user_agent" gorm:"type:text;not null;default:''"`
+	// RotatedAt is set when a refresh replaced this token.
+	RotatedAt *time.Time `json:"rotated_at,omitempty" gorm:"type:timestamptz"`
+}
+
+// LoginThrottle counts recent failed logins for one username or IP.
+type LoginThrottle struct {
+	Key           string     `json:"key" gorm:"type:text;primaryKey"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"type:timestamptz;not null"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty" gorm:"type:timestamptz"`
+}
+
+const (
+	LoginFailedPassword     = "invalid_credentials"
+	LoginFailedSecondFactor = "invalid_second_factor"
+	LoginFailedLocked       = "locked"
+)
+
+// LoginAttempt is the audit record of a rejected login.
+type LoginAttempt struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Username  string    `json:"username" gorm:"type:text;not null;index"`
+	IP        string    `json:"ip" gorm:"type:text;not null"`
+	UserAgent string    `json:"user_agent" gorm:"type:text;not null"`
+	Reason    string    `json:"reason" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamptz;not null"`
+}
+
+type RecoveryCode struct {
+	ID       uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID   uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash string     `json:"-" gorm:"type:text;not null"`
+	UsedAt   *time.Time `json:"used_at,omitempty" gorm:"type:timestamptz"`
+}
+
+// LoginChallenge is a passed password check waiting for a second factor. A
+// setup challenge enrols TOTP for a user who must have it but does not yet.
+type LoginChallenge struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	TokenHash string     `json:"-" gorm:"type:text;not null;uniqueIndex"`
+	Setup     bool       `json:"setup" gorm:"not null;default:false"`
+	Attempts  int        `json:"attempts" gorm:"not null;default:0"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"type:timestamptz;not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty" gorm:"type:timestamptz"`
+	CreatedAt time.Time  `json:"created_at" gorm:"type:timestamptz;not null"`
 }
+
+const (
+	SecurityRefreshReuse     = "refresh_token_reuse"
+	SecurityIdentityLinked   = "identity_linked"
+	SecurityIdentityUnlinked = "identity_unlinked"
+	SecurityPasswordSet      = "password_set"
+	SecurityTwoFactorEnabled = "two_factor_enabled"
+)
+
+// SecurityEvent records suspicious activity and sign-in changes on an
+// account.
+type SecurityEvent struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid;index:idx_security_events_user"`
+	Type      string     `json:"type" gorm:"type:text;not null"`
+	SessionID *uuid.UUID `json:"session_id,omitempty" gorm:"type:uuid"`
+	IP        string     `json:"ip" gorm:"type:text;not null;default:''"`
+	UserAgent string     `json:"user_agent" gorm:"type:text;not null;default:''"`
+	Details   string     `json:"details" gorm:"type:text;not null;default:''"`
+	CreatedAt time.Time  `json:"created_at" gorm:"type:timestamptz;not null"`
+}
+
+// RevokedAccessToken denies one access token until it expires.
+type RevokedAccessToken struct {
+	JTI       string    `json:"jti" gorm:"type:text;primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"type:timestamptz;not null;index:idx_revoked_access_expires"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamptz;not null"`
+}
+
+// TokenWatermark denies every access token of the user issued before
+// NotBefore.
+type TokenWatermark struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primaryKey"`
+	NotBefore time.Time `json:"not_before" gorm:"type:timestamptz;not null"`
+}
+
+const (
+	AuditUserCreated     = "user.created"
+	AuditRoleChanged     = "user.role_changed"
+	AuditUserDisabled    = "user.disabled"
+	AuditUserEnabled     = "user.enabled"
+	AuditUserDeleted     = "user.deleted"
+	AuditSessionsRevoked = "user.sessions_revoked"
+	AuditLoginUnlocked   = "login.unlocked"
+	AuditRoleSaved       = "role.saved"
+	AuditRoleDeleted     = "role.deleted"
+)
+
+// AuditEntry records a change an admin made to an account or, with
+// TargetRole instead of TargetID, to a role. Login unlocks name their
+// username and IP in Details. ActorID is nil for changes made by auth
+// itself, such as the bootstrap admin.
+type AuditEntry struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ActorID    *uuid.UUID `json:"actor_id,omitempty" gorm:"type:uuid;index:idx_audit_log_actor"`
+	Action     string     `json:"action" gorm:"type:text;not null"`
+	TargetID   *uuid.UUID `json:"target_id,omitempty" gorm:"type:uuid;index:idx_audit_log_target"`
+	TargetRole string     `json:"target_role,omitempty" gorm:"type:text;not null;default:''"`
+	Details    string     `json:"details" gorm:"type:text;not null;default:''"`
+	IP         string     `json:"ip" gorm:"type:text;not null;default:''"`
+	UserAgent  string     `json:"user_agent" gorm:"type:text;not null;default:''"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"type:timestamptz;not null"`
+}
+
+func (AuditEntry) TableName() string { return "audit_log" }
+
+// Role groups permissions; users have exactly one role.
+type Role struct {
+	Name        string    `json:"name" gorm:"type:text;primaryKey"`
+	Description string    `json:"description" gorm:"type:text;not null;default:''"`
+	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamptz;not null"`
+}
+
+type RolePermission struct {
+	Role       string `json:"role" gorm:"type:text;primaryKey"`
+	Permission string `json:"permission" gorm:"type:text;primaryKey"`
+}
+
+// UserIdentity links an account at an OpenID provider to a user.
+type UserIdentity struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_user_identities_user_provider"`
+	Provider    string     `json:"provider" gorm:"type:text;not null;uniqueIndex:idx_user_identities_subject;uniqueIndex:idx_user_identities_user_provider"`
+	Subject     string     `json:"-" gorm:"type:text;not null;uniqueIndex:idx_user_identities_subject"`
+	Email       string     `json:"email" gorm:"type:text;not null;default:''"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"type:timestamptz;not null"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" gorm:"type:timestamptz"`
+}
+
+// OIDCLogin is a sign-in sent to a provider and waiting for its callback.
+// UserID is set when a signed-in user links another account.
+type OIDCLogin struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Provider     string     `json:"provider" gorm:"type:text;not null"`
+	StateHash    string     `json:"-" gorm:"type:text;not null;uniqueIndex"`
+	Nonce        string     `json:"-" gorm:"type:text;not null"`
+	CodeVerifier string     `json:"-" gorm:"type:text;not null"`
+	UserID       *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"type:timestamptz;not null"`
+	UsedAt       *time.Time `json:"used_at,omitempty" gorm:"type:timestamptz"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"type:timestamptz;not null"`
+}
+
+func (OIDCLogin) TableName() string { return "oidc_logins" }
@@ -0,0 +1,88 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://shop.test/api/v1/auth/oauth/mock/callback"
+
+func authorize(t *testing.T, idp *oidctest.Provider, p *oidc.Provider, state, nonce, verifier, hint string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier, hint)
+	require.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, oidc.CodeChallenge(verifier), u.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+	code, gotState, err := idp.Authorize(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, state, gotState)
+	return code
+}
+
+func TestProvider_CodeFlowWithPKCE(t *testing.T) {
+	idp := oidctest.NewServer(t, "shop", "secret")
+	p := oidc.New(idp.Config("mock", redirectURL))
+	verifier, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+
+	code := authorize(t, idp, p, "state-1", "nonce-1", verifier, "alice@example.com")
+	id, err := p.Exchange(context.Background(), code, verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "mock-alice@example.com", id.Subject)
+	assert.Equal(t, "alice@example.com", id.Email)
+	assert.True(t, id.EmailVerified)
+
+	_, err = p.Exchange(context.Background(), code, verifier, "nonce-1")
+	require.ErrorIs(t, err, oidc.ErrRejected, "codes are single use")
+}
+
+func TestProvider_RejectsWrongVerifierAndNonce(t *testing.T) {
+	idp := oidctest.NewServer(t, "shop", "secret")
+	p := oidc.New(idp.Config("mock", redirectURL))
+	verifier, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+	other, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+
+	code := authorize(t, idp, p, "state", "nonce", verifier, "")
+	_, err = p.Exchange(context.Background(), code, other, "nonce")
+	require.ErrorIs(t, err, oidc.ErrRejected)
+
+	code = authorize(t, idp, p, "state", "nonce", verifier, "")
+	_, err = p.Exchange(context.Background(), code, verifier, "another-nonce")
+	require.ErrorIs(t, err, oidc.ErrRejected)
+}
+
+func TestProvider_RejectsWrongClientSecret(t *testing.T) {
+	idp := oidctest.NewServer(t, "shop", "secret")
+	cfg := idp.Config("mock", redirectURL)
+	cfg.ClientSecret = "guess"
+	p := oidc.New(cfg)
+	verifier, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+
+	code := authorize(t, idp, p, "state", "nonce", verifier, "")
+	_, err = p.Exchange(context.Background(), code, verifier, "nonce")
+	require.ErrorIs(t, err, oidc.ErrRejected)
+}
+
+func TestProvider_IssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer(t, "shop", "secret")
+	cfg := idp.Config("mock", redirectURL)
+	p := oidc.New(cfg)
+	idp.Issuer = "https://elsewhere.test"
+
+	_, err := p.AuthCodeURL(context.Background(), "state", "nonce", "verifier", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,171 @@
+// Package password checks new passwords against a configurable policy and a
+// list of common or breached passwords.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrWeak is matched by every *PolicyError.
+var ErrWeak = errors.New("password does not meet the policy")
+
+const (
+	RuleMinLength  = "min_length"
+	RuleMaxLength  = "max_length"
+	RuleClasses    = "character_classes"
+	RuleSimilarity = "similar_to_account"
+	RuleBreached   = "breached"
+)
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a password failed.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	rules := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		rules[i] = v.Rule
+	}
+	return "weak password: " + strings.Join(rules, ", ")
+}
+
+func (e *PolicyError) Is(target error) bool { return target == ErrWeak }
+
+//go:embed common.txt
+var commonPasswords string
+
+type Policy struct {
+	MinLength int
+	// MaxLength keeps passwords within what bcrypt hashes (72 bytes).
+	MaxLength int
+	// MinClasses is how many of lower, upper, digit and symbol are required.
+	MinClasses int
+	// Blocked holds lowercased passwords that are refused outright.
+	Blocked map[string]struct{}
+}
+
+// Default is the policy used when none is configured.
+func Default() *Policy {
+	p := &Policy{MinLength: 8, MaxLength: 72, MinClasses: 3, Blocked: map[string]struct{}{}}
+	_ = p.LoadBlocklist(strings.NewReader(commonPasswords))
+	return p
+}
+
+// LoadBlocklist adds one password per line from r; blank lines and lines
+// starting with # are skipped.
+func (p *Policy) LoadBlocklist(r io.Reader) error {
+	if p.Blocked == nil {
+		p.Blocked = map[string]struct{}{}
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.Blocked[strings.ToLower(line)] = struct{}{}
+	}
+	return sc.Err()
+}
+
+func (p *Policy) LoadBlocklistFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open blocklist: %w", err)
+	}
+	defer f.Close()
+	return p.LoadBlocklist(f)
+}
+
+// Check returns a *PolicyError when pw breaks any rule. identifiers are the
+// account's username, email and the like; the password must not resemble them.
+func (p *Policy) Check(pw string, identifiers ...string) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(pw)
+	if length < p.MinLength {
+		violations = append(violations, Violation{RuleMinLength, fmt.Sprintf("must be at least %d characters long", p.MinLength)})
+	}
+	if p.MaxLength > 0 && len(pw) > p.MaxLength {
+		violations = append(violations, Violation{RuleMaxLength, fmt.Sprintf("must be at most %d bytes long", p.MaxLength)})
+	}
+	if classes := charClasses(pw); classes < p.MinClasses {
+		violations = append(violations, Violation{RuleClasses, fmt.Sprintf("must contain at least %d of: lowercase, uppercase, digits, symbols", p.MinClasses)})
+	}
+	if similar(pw, identifiers) {
+		violations = append(violations, Violation{RuleSimilarity, "must not contain or resemble the username or email"})
+	}
+	if _, ok := p.Blocked[strings.ToLower(pw)]; ok {
+		violations = append(violations, Violation{RuleBreached, "is too common or appeared in a data breach"})
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func charClasses(pw string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// similar reports whether pw contains an identifier (or the local part of an
+// email), its reverse, or is contained in one.
+func similar(pw string, identifiers []string) bool {
+	pw = strings.ToLower(pw)
+	for _, id := range identifiers {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if local, _, ok := strings.Cut(id, "@"); ok {
+			id = local
+		}
+		if len(id) < 3 {
+			continue
+		}
+		if strings.Contains(pw, id) || strings.Contains(pw, reverse(id)) || strings.Contains(id, pw) {
+			return true
+		}
+	}
+	return false
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
@@ -0,0 +1,74 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(t *testing.T, err error) []string {
+	t.Helper()
+
+	var policyErr *PolicyError
+	require.True(t, errors.As(err, &policyErr), "expected *PolicyError, got %v", err)
+
+	out := make([]string, len(policyErr.Violations))
+	for i, v := range policyErr.Violations {
+		out[i] = v.Rule
+	}
+	return out
+}
+
+func TestPolicy_Check(t *testing.T) {
+	t.Parallel()
+
+	policy := Default()
+
+	tests := []struct {
+		name        string
+		password    string
+		identifiers []string
+		want        []string
+	}{
+		{name: "strong", password: "Correct-Horse7", identifiers: []string{"alice", "alice@example.com"}},
+		{name: "too short", password: "Ab1!", want: []string{RuleMinLength}},
+		{name: "too long", password: "Aa1" + strings.Repeat("x", 70), want: []string{RuleMaxLength}},
+		{name: "single class", password: "lowercaseonly", want: []string{RuleClasses}},
+		{name: "contains username", password: "Alice-2024!", identifiers: []string{"alice"}, want: []string{RuleSimilarity}},
+		{name: "reversed username", password: "Ecila-2024!", identifiers: []string{"alice"}, want: []string{RuleSimilarity}},
+		{name: "contains email local part", password: "Bob.smith99", identifiers: []string{"bob.smith@example.com"}, want: []string{RuleSimilarity}},
+		{name: "common password", password: "Password123", want: []string{RuleBreached}},
+		{name: "several rules", password: "qwerty", want: []string{RuleMinLength, RuleClasses, RuleBreached}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := policy.Check(tt.password, tt.identifiers...)
+			if tt.want == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrWeak)
+			assert.Equal(t, tt.want, rules(t, err))
+		})
+	}
+}
+
+func TestPolicy_LoadBlocklist(t *testing.T) {
+	t.Parallel()
+
+	policy := &Policy{MinLength: 1}
+	require.NoError(t, policy.LoadBlocklist(strings.NewReader("# comment\n\nHunter2-Strong\n")))
+
+	err := policy.Check("hunter2-strong")
+	require.Error(t, err)
+	assert.Equal(t, []string{RuleBreached}, rules(t, err))
+	assert.Len(t, policy.Blocked, 1)
+}
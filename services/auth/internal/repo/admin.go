@@ -0,0 +1,257 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLastAdmin is returned for a change that would leave no active admin.
+var ErrLastAdmin = errors.New("last active admin")
+
+// ErrNotOwned is returned when the bootstrap admin name belongs to an
+// account the operator could not prove to own.
+var ErrNotOwned = errors.New("account not owned")
+
+type UserFilter struct {
+	// Query matches a part of the username or email.
+	Query    string
+	Role     string
+	Disabled *bool
+	Limit    int
+	Offset   int
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *GormRepo) ListUsers(ctx context.Context, f UserFilter) ([]models.User, int64, error) {
+	q := r.DB.WithContext(ctx).Model(&models.User{})
+	if f.Query != "" {
+		pattern := "%" + likeEscaper.Replace(f.Query) + "%"
+		q = q.Where("username ILIKE ? OR email ILIKE ?", pattern, pattern)
+	}
+	if f.Role != "" {
+		q = q.Where("role = ?", f.Role)
+	}
+	if f.Disabled != nil {
+		if *f.Disabled {
+			q = q.Where("disabled_at IS NOT NULL")
+		} else {
+			q = q.Where("disabled_at IS NULL")
+		}
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	err := q.Order("created_at DESC, id").Limit(f.Limit).Offset(f.Offset).Find(&users).Error
+	return users, total, err
+}
+
+// lockAdmins locks the rows of every active admin, so concurrent changes
+// cannot demote, disable or delete the last two admins at the same time.
+func lockAdmins(tx *gorm.DB) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := tx.Model(&models.User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND disabled_at IS NULL", "admin").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// lockUser loads the user for a change and, when the change takes away an
+// active admin, makes sure another one is left.
+func lockUser(tx *gorm.DB, id uuid.UUID, removesAdmin func(u *models.User) bool) (*models.User, error) {
+	admins, err := lockAdmins(tx)
+	if err != nil {
+		return nil, err
+	}
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if user.Role == "admin" && !user.Disabled() && removesAdmin(&user) && len(admins) <= 1 {
+		return nil, ErrLastAdmin
+	}
+	return &user, nil
+}
+
+// ChangeUserRole sets the role and records entry. It reports false without
+// an audit entry when the user already has the role.
+func (r *GormRepo) ChangeUserRole(ctx context.Context, id uuid.UUID, role string, entry *models.AuditEntry) (*models.User, bool, error) {
+	var user *models.User
+	changed := false
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockUser(tx, id, func(u *models.User) bool { return role != "admin" })
+		if err != nil || user.Role == role {
+			return err
+		}
+
+		entry.TargetID = &id
+		entry.Details = user.Role + " -> " + role
+		if err := tx.Model(user).Update("role", role).Error; err != nil {
+			return err
+		}
+		changed = true
+		return tx.Create(entry).Error
+	})
+	return user, changed, err
+}
+
+// SetUserDisabled disables or enables the account and records entry.
+// Disabling also revokes every refresh token of the user.
+func (r *GormRepo) SetUserDisabled(ctx context.Context, id uuid.UUID, disabled bool, entry *models.AuditEntry) (*models.User, bool, error) {
+	var user *models.User
+	changed := false
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockUser(tx, id, func(*models.User) bool { return disabled })
+		if err != nil || user.Disabled() == disabled {
+			return err
+		}
+
+		var disabledAt *time.Time
+		if disabled {
+			now := time.Now()
+			disabledAt = &now
+			if err := tx.Model(&models.RefreshToken{}).
+				Where("user_id = ? AND revoked = false", id).
+				Update("revoked", true).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(user).Update("disabled_at", disabledAt).Error; err != nil {
+			return err
+		}
+		user.DisabledAt = disabledAt
+
+		entry.TargetID = &id
+		changed = true
+		return tx.Create(entry).Error
+	})
+	return user, changed, err
+}
+
+// DeleteUser removes the account with its tokens and sessions and records
+// entry.
+func (r *GormRepo) DeleteUser(ctx context.Context, id uuid.UUID, entry *models.AuditEntry) (*models.User, error) {
+	var user *models.User
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockUser(tx, id, func(*models.User) bool { return true })
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(user).Error; err != nil {
+			return err
+		}
+
+		entry.TargetID = &id
+		entry.Details = "username " + user.Username
+		return tx.Create(entry).Error
+	})
+	return user, err
+}
+
+// CreateFirstAdmin makes admin the first admin unless an active admin
+// exists. A missing account is created; an existing one is promoted only
+// when owned accepts it.
+func (r *GormRepo) CreateFirstAdmin(ctx context.Context, admin *models.User, owned func(*models.User) bool, entry *models.AuditEntry) (bool, error) {
+	promoted := false
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		admins, err := lockAdmins(tx)
+		if err != nil || len(admins) > 0 {
+			return err
+		}
+
+		var user models.User
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("username = ?", admin.Username).First(&user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			admin.Role = "admin"
+			if err := tx.Create(admin).Error; err != nil {
+				return err
+			}
+			entry.Action = models.AuditUserCreated
+			entry.Details = "bootstrap admin"
+		case err != nil:
+			return err
+		case !owned(&user):
+			return ErrNotOwned
+		default:
+			if err := tx.Model(&user).Updates(map[string]any{"role": "admin", "disabled_at": nil}).Error; err != nil {
+				return err
+			}
+			entry.Details = user.Role + " -> admin"
+			*admin = user
+			admin.Role, admin.DisabledAt = "admin", nil
+		}
+
+		entry.TargetID = &admin.ID
+		promoted = true
+		return tx.Create(entry).Error
+	})
+	return promoted, err
+}
+
+// UnlockLogin clears counters and locks of keys and records entry.
+func (r *GormRepo) UnlockLogin(ctx context.Context, keys []string, entry *models.AuditEntry) (int64, error) {
+	var cleared int64
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("key IN ?", keys).Delete(&models.LoginThrottle{})
+		if res.Error != nil {
+			return res.Error
+		}
+		cleared = res.RowsAffected
+		return tx.Create(entry).Error
+	})
+	return cleared, err
+}
+
+// RevokeUserSessions revokes every session of the user and records entry.
+func (r *GormRepo) RevokeUserSessions(ctx context.Context, userID uuid.UUID, entry *models.AuditEntry) (int64, error) {
+	var revoked int64
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").Where("id = ?", userID).First(&models.User{}).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked = false AND expires_at > ?", userID, time.Now()).
+			Update("revoked", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		revoked = res.RowsAffected
+
+		entry.TargetID = &userID
+		entry.Details = fmt.Sprintf("%d sessions", revoked)
+		return tx.Create(entry).Error
+	})
+	return revoked, err
+}
+
+func (r *GormRepo) ListAuditLog(ctx context.Context, actorID, targetID *uuid.UUID, limit int) ([]models.AuditEntry, error) {
+	q := r.DB.WithContext(ctx).Model(&models.AuditEntry{})
+	if actorID != nil {
+		q = q.Where("actor_id = ?", *actorID)
+	}
+	if targetID != nil {
+		q = q.Where("target_id = ?", *targetID)
+	}
+
+	var entries []models.AuditEntry
+	err := q.Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}, Desc: true}).
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
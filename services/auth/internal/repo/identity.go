@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrIdentityTaken is returned when the external account is linked to
+	// another user or the user already has an account at the provider.
+	ErrIdentityTaken = errors.New("identity already linked")
+	// ErrLastSignInMethod is returned when unlinking would leave a user
+	// without a password or another linked account.
+	ErrLastSignInMethod = errors.New("last sign-in method")
+	// ErrPasswordSet is returned when a first password is set for a user
+	// who already has one.
+	ErrPasswordSet = errors.New("password already set")
+)
+
+func (r *GormRepo) CreateOIDCLogin(ctx context.Context, login *models.OIDCLogin) error {
+	return r.DB.WithContext(ctx).Create(login).Error
+}
+
+// ConsumeOIDCLogin marks the pending sign-in used and returns it, or
+// ErrTokenInvalid when it is unknown, used, expired or for another provider.
+func (r *GormRepo) ConsumeOIDCLogin(ctx context.Context, provider, stateHash string) (*models.OIDCLogin, error) {
+	var login models.OIDCLogin
+	res := r.DB.WithContext(ctx).
+		Model(&login).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ? AND provider = ? AND used_at IS NULL AND expires_at > now()", stateHash, provider).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrTokenInvalid
+	}
+	return &login, nil
+}
+
+func (r *GormRepo) FindIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.DB.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// TouchIdentity records a sign-in through the identity and keeps its email
+// current.
+func (r *GormRepo) TouchIdentity(ctx context.Context, id uuid.UUID, email string) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.UserIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"last_login_at": time.Now(), "email": email}).Error
+}
+
+// CreateUserWithIdentity creates a user signed up through a provider. It
+// returns ErrUserAlreadyExist when the username or email is taken.
+func (r *GormRepo) CreateUserWithIdentity(ctx context.Context, u *models.User, identity *models.UserIdentity) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(u)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserAlreadyExist
+		}
+
+		identity.UserID = u.ID
+		return createIdentity(tx, identity)
+	})
+}
+
+// LinkIdentity adds an external account to an existing user and records
+// event.
+func (r *GormRepo) LinkIdentity(ctx context.Context, identity *models.UserIdentity, event *models.SecurityEvent) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := createIdentity(tx, identity); err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+func createIdentity(tx *gorm.DB, identity *models.UserIdentity) error {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(identity)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrIdentityTaken
+	}
+	return nil
+}
+
+func (r *GormRepo) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.DB.WithContext(ctx).Where("user_id = ?", userID).Order("provider").Find(&identities).Error
+	return identities, err
+}
+
+// SetFirstPassword gives a user created by a social login a password and
+// records event.
+func (r *GormRepo) SetFirstPassword(ctx context.Context, userID uuid.UUID, passwordHash string, event *models.SecurityEvent) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if user.HasPassword() {
+			return ErrPasswordSet
+		}
+		if err := tx.Model(&user).Update("password_hash", passwordHash).Error; err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+// UnlinkIdentity removes the user's account at provider and records event.
+// It reports false when there is none.
+func (r *GormRepo) UnlinkIdentity(ctx context.Context, userID uuid.UUID, provider string, event *models.SecurityEvent) (bool, error) {
+	removed := false
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		var identities int64
+		if err := tx.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&identities).Error; err != nil {
+			return err
+		}
+
+		res := tx.Where("user_id = ? AND provider = ?", userID, provider).Delete(&models.UserIdentity{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		if !user.HasPassword() && identities <= 1 {
+			return ErrLastSignInMethod
+		}
+		removed = true
+		return tx.Create(event).Error
+	})
+	return removed, err
+}
@@ -7,14 +7,20 @@ import (
 
 	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
 	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var ErrTokenExpiredOrRevoked = errors.New("token expired or revoked")
+var (
+	ErrTokenExpiredOrRevoked = errors.New("token expired or revoked")
+	// ErrTokenReused is returned for a token that a refresh already replaced.
+	ErrTokenReused = errors.New("refresh token reused")
+)
 
-func (r *GormRepo) AddRefreshToDB(ctx context.Context, refreshToken string) error {
+func (r *GormRepo) AddRefreshToDB(ctx context.Context, refreshToken string, client clientinfo.Info) error {
 	claims, err := tokens.RefreshClaimsFromToken(refreshToken, r.RefreshSecret)
 	if err != nil {
 		return err
@@ -29,6 +35,9 @@ func (r *GormRepo) AddRefreshToDB(ctx context.Context, refreshToken string) erro
 		UserID:    userID,
 		ExpiresAt: claims.ExpiresAt.Time,
 		JTI:       claims.ID,
+		SessionID: uuid.New(),
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
 	}
 
 	if err := r.DB.WithContext(ctx).Create(&refreshModel).Error; err != nil {
@@ -70,7 +79,7 @@ func (r *GormRepo) refreshExpiredOrRevoked(ctx context.Context, db *gorm.DB, tok
 func (r *GormRepo) markAsUsed(ctx context.Context, db *gorm.DB, tokenID string) error {
 	return db.WithContext(ctx).Model(&models.RefreshToken{}).
 		Where("jti = ?", tokenID).
-		Update("revoked", true).Error
+		Updates(map[string]any{"revoked": true, "rotated_at": time.Now()}).Error
 }
 
 func (r *GormRepo) RefreshExpiredOrRevoked(ctx context.Context, tokenID string) (bool, error) {
@@ -81,15 +90,28 @@ func (r *GormRepo) MarkAsUsed(ctx context.Context, tokenID string) error {
 	return r.markAsUsed(ctx, r.DB, tokenID)
 }
 
+// RotateRefreshToken replaces the token oldJTI with newToken in the same
+// session. The old row is locked, so of two concurrent refreshes with one
+// token the second sees ErrTokenReused.
 func (r *GormRepo) RotateRefreshToken(ctx context.Context, oldJTI string, newToken models.RefreshToken) error {
 	return r.DB.Transaction(func(tx *gorm.DB) error {
-		expired, err := r.refreshExpiredOrRevoked(ctx, tx, oldJTI)
-		if err != nil {
+		var old models.RefreshToken
+		if err := tx.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("jti = ?", oldJTI).
+			First(&old).Error; err != nil {
 			return err
 		}
-		if expired {
+		if old.RotatedAt != nil {
+			return ErrTokenReused
+		}
+		if old.ExpiresAt.Before(time.Now()) || old.Revoked {
 			return ErrTokenExpiredOrRevoked
 		}
+		now := time.Now()
+		newToken.SessionID = old.SessionID
+		newToken.CreatedAt = old.CreatedAt
+		newToken.LastUsedAt = &now
 
 		if err := r.markAsUsed(ctx, tx, oldJTI); err != nil {
 			return err
@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *GormRepo) RevokeAccessToken(ctx context.Context, token *models.RevokedAccessToken) error {
+	return r.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(token).Error
+}
+
+// SetTokenWatermark moves the user's watermark forward to notBefore; an
+// older value is ignored.
+func (r *GormRepo) SetTokenWatermark(ctx context.Context, userID uuid.UUID, notBefore time.Time) error {
+	return r.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"not_before": gorm.Expr("GREATEST(token_watermarks.not_before, EXCLUDED.not_before)"),
+			}),
+		}).
+		Create(&models.TokenWatermark{UserID: userID, NotBefore: notBefore}).Error
+}
+
+// ActiveRevocations deletes revocations that can no longer match a live
+// token and returns the rest. Watermarks set before since are dropped.
+func (r *GormRepo) ActiveRevocations(ctx context.Context, since time.Time) ([]models.RevokedAccessToken, []models.TokenWatermark, error) {
+	db := r.DB.WithContext(ctx)
+	if err := db.Where("expires_at <= ?", time.Now()).Delete(&models.RevokedAccessToken{}).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := db.Where("not_before < ?", since).Delete(&models.TokenWatermark{}).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var denied []models.RevokedAccessToken
+	if err := db.Find(&denied).Error; err != nil {
+		return nil, nil, err
+	}
+	var watermarks []models.TokenWatermark
+	if err := db.Find(&watermarks).Error; err != nil {
+		return nil, nil, err
+	}
+	return denied, watermarks, nil
+}
@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRoleInUse is returned when deleting a role that users still have.
+var ErrRoleInUse = errors.New("role in use")
+
+// ListRoles returns every role with its permissions.
+func (r *GormRepo) ListRoles(ctx context.Context) ([]models.Role, map[string][]string, error) {
+	db := r.DB.WithContext(ctx)
+	var roles []models.Role
+	if err := db.Order("name").Find(&roles).Error; err != nil {
+		return nil, nil, err
+	}
+	var rows []models.RolePermission
+	if err := db.Order("role, permission").Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	perms := make(map[string][]string, len(roles))
+	for _, row := range rows {
+		perms[row.Role] = append(perms[row.Role], row.Permission)
+	}
+	return roles, perms, nil
+}
+
+func (r *GormRepo) RoleExists(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&models.Role{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormRepo) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	var perms []string
+	err := r.DB.WithContext(ctx).Model(&models.RolePermission{}).
+		Where("role = ?", role).
+		Order("permission").
+		Pluck("permission", &perms).Error
+	return perms, err
+}
+
+// SaveRole creates the role or replaces its description and permissions,
+// and records entry.
+func (r *GormRepo) SaveRole(ctx context.Context, role *models.Role, perms []string, entry *models.AuditEntry) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"description"}),
+		}).Create(role).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role = ?", role.Name).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(perms) > 0 {
+			rows := make([]models.RolePermission, len(perms))
+			for i, p := range perms {
+				rows[i] = models.RolePermission{Role: role.Name, Permission: p}
+			}
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+
+		entry.TargetRole = role.Name
+		entry.Details = "permissions: " + strings.Join(perms, ", ")
+		return tx.Create(entry).Error
+	})
+}
+
+// DeleteRole removes a role no user has and records entry. It returns
+// gorm.ErrRecordNotFound for an unknown role.
+func (r *GormRepo) DeleteRole(ctx context.Context, name string, entry *models.AuditEntry) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&role).Error; err != nil {
+			return err
+		}
+		var users int64
+		if err := tx.Model(&models.User{}).Where("role = ?", name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return ErrRoleInUse
+		}
+		if err := tx.Delete(&role).Error; err != nil {
+			return err
+		}
+
+		entry.TargetRole = name
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *GormRepo) UsersWithRole(ctx context.Context, role string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.DB.WithContext(ctx).Model(&models.User{}).Where("role = ?", role).Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// ListSessions returns the live refresh token of every active session of
+// the user, most recently used first.
+func (r *GormRepo) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.RefreshToken, error) {
+	var rows []models.RefreshToken
+	err := r.DB.WithContext(ctx).
+		Where("user_id = ? AND revoked = false AND expires_at > ?", userID, time.Now()).
+		Order("COALESCE(last_used_at, created_at) DESC").
+		Find(&rows).Error
+	return rows, err
+}
+
+func (r *GormRepo) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	res := r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND session_id = ? AND revoked = false", userID, sessionID).
+		Update("revoked", true)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeSessions revokes every session of the user except keep; pass
+// uuid.Nil to revoke them all.
+func (r *GormRepo) RevokeSessions(ctx context.Context, userID, keep uuid.UUID) (int64, error) {
+	res := r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND session_id <> ? AND revoked = false AND expires_at > ?", userID, keep, time.Now()).
+		Update("revoked", true)
+	return res.RowsAffected, res.Error
+}
+
+// RevokeFamily revokes every live token of a session, whoever holds them.
+func (r *GormRepo) RevokeFamily(ctx context.Context, sessionID uuid.UUID) (int64, error) {
+	res := r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked = false", sessionID).
+		Update("revoked", true)
+	return res.RowsAffected, res.Error
+}
+
+func (r *GormRepo) AddSecurityEvent(ctx context.Context, event *models.SecurityEvent) error {
+	return r.DB.WithContext(ctx).Create(event).Error
+}
+
+func (r *GormRepo) ListSecurityEvents(ctx context.Context, userID *uuid.UUID, limit int) ([]models.SecurityEvent, error) {
+	q := r.DB.WithContext(ctx).Model(&models.SecurityEvent{})
+	if userID != nil {
+		q = q.Where("user_id = ?", *userID)
+	}
+
+	var events []models.SecurityEvent
+	err := q.Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}, Desc: true}).
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+const recordFailureSQL = `
+INSERT INTO login_throttles (key, failures, last_failure_at)
+VALUES (?, 1, now())
+ON CONFLICT (key) DO UPDATE SET
+  failures = CASE
+    WHEN login_throttles.last_failure_at < now() - make_interval(secs => ?) THEN 1
+    ELSE login_throttles.failures + 1
+  END,
+  last_failure_at = now()
+RETURNING failures`
+
+// LoginLockedUntil returns the latest active lock among keys, or nil.
+func (r *GormRepo) LoginLockedUntil(ctx context.Context, keys []string) (*time.Time, error) {
+	var until *time.Time
+	err := r.DB.WithContext(ctx).
+		Model(&models.LoginThrottle{}).
+		Select("max(locked_until)").
+		Where("key IN ? AND locked_until > now()", keys).
+		Scan(&until).Error
+	return until, err
+}
+
+// RecordLoginFailure counts a failure for key and returns the running count.
+// Failures older than window are forgotten.
+func (r *GormRepo) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures int
+	err := r.DB.WithContext(ctx).Raw(recordFailureSQL, key, window.Seconds()).Scan(&failures).Error
+	return failures, err
+}
+
+func (r *GormRepo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.LoginThrottle{}).
+		Where("key = ? AND (locked_until IS NULL OR locked_until < ?)", key, until).
+		Update("locked_until", until).Error
+}
+
+// ResetLoginFailures clears counters and locks of keys and reports how many
+// existed.
+func (r *GormRepo) ResetLoginFailures(ctx context.Context, keys ...string) (int64, error) {
+	res := r.DB.WithContext(ctx).Where("key IN ?", keys).Delete(&models.LoginThrottle{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *GormRepo) AddLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	return r.DB.WithContext(ctx).Create(attempt).Error
+}
+
+func (r *GormRepo) ListLoginAttempts(ctx context.Context, username, ip string, limit int) ([]models.LoginAttempt, error) {
+	q := r.DB.WithContext(ctx).Model(&models.LoginAttempt{})
+	if username != "" {
+		q = q.Where("username = ?", username)
+	}
+	if ip != "" {
+		q = q.Where("ip = ?", ip)
+	}
+
+	var attempts []models.LoginAttempt
+	err := q.Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}, Desc: true}).
+		Limit(limit).
+		Find(&attempts).Error
+	return attempts, err
+}
@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTwoFactorEnabled = errors.New("two-factor authentication already enabled")
+
+// SetPendingTOTP stores a new secret for a user who has not enabled TOTP.
+func (r *GormRepo) SetPendingTOTP(ctx context.Context, userID uuid.UUID, sealed string) error {
+	res := r.DB.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND totp_enabled_at IS NULL", userID).
+		Updates(map[string]any{"totp_secret": sealed, "totp_last_step": nil})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+func insertRecoveryCodes(tx *gorm.DB, userID uuid.UUID, hashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.RecoveryCode, len(hashes))
+	for i, h := range hashes {
+		codes[i] = models.RecoveryCode{UserID: userID, CodeHash: h}
+	}
+	return tx.Create(&codes).Error
+}
+
+// EnableTOTP turns on the pending secret, recording step as used, replaces
+// the recovery codes and records event.
+func (r *GormRepo) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string, event *models.SecurityEvent) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.User{}).
+			Where("id = ? AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL", userID).
+			Updates(map[string]any{"totp_enabled_at": time.Now(), "totp_last_step": step})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTwoFactorEnabled
+		}
+		if err := insertRecoveryCodes(tx, userID, recoveryHashes); err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+func (r *GormRepo) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{"totp_secret": nil, "totp_enabled_at": nil, "totp_last_step": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+func (r *GormRepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return insertRecoveryCodes(tx, userID, hashes)
+	})
+}
+
+// UseRecoveryCode marks an unused code as used and reports whether it was.
+func (r *GormRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// AdvanceTOTPStep records step as the last used one. It reports false when
+// the step, or a later one, was already used, which refuses replayed codes.
+func (r *GormRepo) AdvanceTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", userID, step).
+		Update("totp_last_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *GormRepo) CreateLoginChallenge(ctx context.Context, ch *models.LoginChallenge) error {
+	return r.DB.WithContext(ctx).Create(ch).Error
+}
+
+// OpenLoginChallenge counts an attempt on a usable challenge and returns it,
+// or ErrTokenInvalid when it is unknown, used, expired or out of attempts.
+func (r *GormRepo) OpenLoginChallenge(ctx context.Context, hash string, maxAttempts int) (*models.LoginChallenge, error) {
+	var ch models.LoginChallenge
+	res := r.DB.WithContext(ctx).
+		Model(&ch).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > now() AND attempts < ?", hash, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrTokenInvalid
+	}
+	return &ch, nil
+}
+
+// CloseLoginChallenge marks the challenge used; false means another request
+// already completed it.
+func (r *GormRepo) CloseLoginChallenge(ctx context.Context, id uuid.UUID) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var ErrInvalidCredentials = errors.New("invalid credentials")
@@ -28,18 +29,19 @@ func (r *GormRepo) UserExist(ctx context.Context, username, password string) (*m
 	return &user, nil
 }
 
+// CreateUserIfNotExists returns ErrUserAlreadyExist when the username or
+// email is taken, including by a concurrent registration.
 func (r *GormRepo) CreateUserIfNotExists(ctx context.Context, u *models.User) error {
-    tx := r.DB.WithContext(ctx).Where("username = ?", u.Username).FirstOrCreate(u)
-    if tx.Error != nil {
-        return tx.Error
-    }
-    if tx.RowsAffected == 0 {
-        return ErrUserAlreadyExist
-    }
-    return nil
+	res := r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(u)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserAlreadyExist
+	}
+	return nil
 }
 
-
 func (r *GormRepo) LogOut(ctx context.Context, refreshtoken string) error {
 	result := r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
 		Where("token = ?", jwthelp.Sha256Hex(refreshtoken)).
@@ -53,4 +55,12 @@ func (r *GormRepo) GetUserById(ctx context.Context, id uuid.UUID) (*models.User,
 		return nil, err
 	}
 	return &user, nil
-}
\ No newline at end of file
+}
+
+func (r *GormRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.DB.WithContext(ctx).Where("lower(email) = lower(?)", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTokenInvalid = errors.New("token invalid, used or expired")
+
+// CreateUserToken stores a new token and retires unused tokens of the same
+// purpose, so only the latest mail works.
+func (r *GormRepo) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// UserByToken returns the owner of a usable token without consuming it.
+func (r *GormRepo) UserByToken(ctx context.Context, purpose models.TokenPurpose, hash string) (*models.User, error) {
+	var user models.User
+	err := r.DB.WithContext(ctx).
+		Joins("JOIN user_tokens t ON t.user_id = users.id").
+		Where("t.token_hash = ? AND t.purpose = ? AND t.used_at IS NULL AND t.expires_at > now()", hash, purpose).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func consumeToken(tx *gorm.DB, purpose models.TokenPurpose, hash string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > now()", hash, purpose).
+		First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&token).Update("used_at", time.Now()).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ConsumeUserToken uses up a token and returns its owner.
+func (r *GormRepo) ConsumeUserToken(ctx context.Context, purpose models.TokenPurpose, hash string) (*models.User, error) {
+	var user models.User
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeToken(tx, purpose, hash)
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ?", token.UserID).First(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// VerifyEmail consumes a verification token and marks the owner's email as
+// verified.
+func (r *GormRepo) VerifyEmail(ctx context.Context, hash string) (*models.User, error) {
+	var user models.User
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeToken(tx, models.TokenVerifyEmail, hash)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", token.UserID).
+			Update("email_verified_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", token.UserID).First(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ResetPassword consumes a reset token, stores the new password hash and
+// revokes every refresh token of the user.
+func (r *GormRepo) ResetPassword(ctx context.Context, hash, passwordHash string) (*models.User, error) {
+	var user models.User
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeToken(tx, models.TokenResetPassword, hash)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", token.UserID).
+			Update("password_hash", passwordHash).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked = false", token.UserID).
+			Update("revoked", true).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", token.UserID).First(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
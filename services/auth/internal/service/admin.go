@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Skotchmaster/online_shop/pkg/events"
+	pkg_hash "github.com/Skotchmaster/online_shop/pkg/hash"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/transport"
+)
+
+var ErrAccountDisabled = errors.New("account disabled")
+
+const (
+	maxUsersPage    = 200
+	maxAuditEntries = 200
+)
+
+func newAuditEntry(ctx context.Context, actorID uuid.UUID, action string) *models.AuditEntry {
+	info := clientinfo.FromContext(ctx)
+	entry := &models.AuditEntry{Action: action, IP: info.IP, UserAgent: info.UserAgent}
+	if actorID != uuid.Nil {
+		entry.ActorID = &actorID
+	}
+	return entry
+}
+
+// adminError maps repo errors of the user management calls.
+func adminError(op string, userID uuid.UUID, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	case errors.Is(err, repo.ErrLastAdmin):
+		return fmt.Errorf("user %s is the last active admin: %w", userID, ErrConflict)
+	}
+	return fmt.Errorf("%s: %v: %w", op, err, ErrInternal)
+}
+
+func (h *AuthService) ListUsers(ctx context.Context, f repo.UserFilter) (*transport.UserPage, error) {
+	if f.Limit <= 0 || f.Limit > maxUsersPage {
+		f.Limit = maxUsersPage
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	users, total, err := h.Repo.ListUsers(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %v: %w", err, ErrInternal)
+	}
+	return &transport.UserPage{Users: users, Total: total}, nil
+}
+
+func (h *AuthService) GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := h.Repo.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, adminError("get user", userID, err)
+	}
+	return user, nil
+}
+
+// ChangeUserRole gives the user a new role. Access tokens carrying the old
+// role are revoked, so the change applies on the user's next refresh. The
+// actor must hold every permission of both the old and the new role.
+func (h *AuthService) ChangeUserRole(ctx context.Context, actorID, userID uuid.UUID, role string) (*models.User, error) {
+	if actorID == userID {
+		return nil, fmt.Errorf("admins cannot change their own role: %w", ErrForbidden)
+	}
+	exists, err := h.Repo.RoleExists(ctx, role)
+	if err != nil {
+		return nil, fmt.Errorf("get role: %v: %w", err, ErrInternal)
+	}
+	if !exists {
+		return nil, fmt.Errorf("unknown role %q: %w", role, ErrValidation)
+	}
+	target, err := h.Repo.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, adminError("get user", userID, err)
+	}
+	for _, r := range []string{target.Role, role} {
+		if err := h.checkRoleGrantable(ctx, actorID, r); err != nil {
+			return nil, err
+		}
+	}
+	user, changed, err := h.Repo.ChangeUserRole(ctx, userID, role, newAuditEntry(ctx, actorID, models.AuditRoleChanged))
+	if err != nil {
+		return nil, adminError("change role", userID, err)
+	}
+	if changed {
+		if err := h.revokeUserTokens(ctx, userID, "role_changed"); err != nil {
+			return nil, err
+		}
+		user.Role = role
+	}
+	return user, nil
+}
+
+// DisableUser signs the user out everywhere and blocks login and refresh
+// until EnableUser.
+func (h *AuthService) DisableUser(ctx context.Context, actorID, userID uuid.UUID) (*models.User, error) {
+	if actorID == userID {
+		return nil, fmt.Errorf("admins cannot disable themselves: %w", ErrForbidden)
+	}
+	user, changed, err := h.Repo.SetUserDisabled(ctx, userID, true, newAuditEntry(ctx, actorID, models.AuditUserDisabled))
+	if err != nil {
+		return nil, adminError("disable user", userID, err)
+	}
+	if changed {
+		if err := h.revokeUserTokens(ctx, userID, "user_disabled"); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+func (h *AuthService) EnableUser(ctx context.Context, actorID, userID uuid.UUID) (*models.User, error) {
+	user, _, err := h.Repo.SetUserDisabled(ctx, userID, false, newAuditEntry(ctx, actorID, models.AuditUserEnabled))
+	if err != nil {
+		return nil, adminError("enable user", userID, err)
+	}
+	return user, nil
+}
+
+// DeleteUser removes the account. Its revocation rows go with it, so the
+// revocation is only sent as an event.
+func (h *AuthService) DeleteUser(ctx context.Context, actorID, userID uuid.UUID) error {
+	if actorID == userID {
+		return fmt.Errorf("admins cannot delete themselves: %w", ErrForbidden)
+	}
+	if _, err := h.Repo.DeleteUser(ctx, userID, newAuditEntry(ctx, actorID, models.AuditUserDeleted)); err != nil {
+		return adminError("delete user", userID, err)
+	}
+	h.publishRevocation(ctx, events.AuthEvent{
+		Type:      events.UserTokensRevoked,
+		UserID:    userID,
+		NotBefore: time.Now().UTC(),
+		Reason:    "user_deleted",
+	})
+	return nil
+}
+
+// BootstrapAdmin makes username the first admin when there is no active
+// admin yet, so it does not have to be set up in the database by hand. A
+// missing account is created with password; an existing one is promoted
+// only if password is its password, so registering the name is not enough.
+func (h *AuthService) BootstrapAdmin(ctx context.Context, username, password string) error {
+	if username == "" || password == "" {
+		return fmt.Errorf("username and password are required: %w", ErrValidation)
+	}
+	if err := h.checkPassword(password, username); err != nil {
+		return err
+	}
+	pwHash, err := pkg_hash.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("cannot hash the password: %w", ErrInternal)
+	}
+
+	admin := models.User{Username: username, PasswordHash: string(pwHash)}
+	owned := func(u *models.User) bool { return pkg_hash.CheckPassword(u.PasswordHash, password) }
+	promoted, err := h.Repo.CreateFirstAdmin(ctx, &admin, owned, newAuditEntry(ctx, uuid.Nil, models.AuditRoleChanged))
+	if errors.Is(err, repo.ErrNotOwned) {
+		return fmt.Errorf("bootstrap admin %q exists with another password: %w", username, ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("bootstrap admin: %v: %w", err, ErrInternal)
+	}
+	if promoted {
+		logging.FromContext(ctx).Info("bootstrap_admin_promoted", "username", username, "user_id", admin.ID)
+	}
+	return nil
+}
+
+func (h *AuthService) ListAuditLog(ctx context.Context, actorID, targetID *uuid.UUID, limit int) ([]models.AuditEntry, error) {
+	if limit <= 0 || limit > maxAuditEntries {
+		limit = maxAuditEntries
+	}
+	entries, err := h.Repo.ListAuditLog(ctx, actorID, targetID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log: %v: %w", err, ErrInternal)
+	}
+	return entries, nil
+}
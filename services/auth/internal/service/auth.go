@@ -13,7 +13,15 @@ import (
 	pkg_hash "github.com/Skotchmaster/online_shop/pkg/hash"
 	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
 	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/revocation"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/cartclient"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/keyset"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/mailer"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/password"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/transport"
 )
@@ -28,17 +36,76 @@ var (
 
 type AuthService struct {
 	Repo repo.GormRepo
+	// Keys signs access tokens; nil falls back to HS256 with Repo.JWTSecret.
+	Keys *keyset.Keyset
+	// Carts merges guest carts on login; nil disables merging.
+	Carts *cartclient.Client
+
+	// Mailer sends verification and password reset links built on BaseURL;
+	// nil disables both mails.
+	Mailer    mailer.Mailer
+	BaseURL   string
+	VerifyTTL time.Duration
+	ResetTTL  time.Duration
+
+	// Passwords validates new passwords; nil uses password.Default.
+	Passwords *password.Policy
+	// Throttle limits failed logins; nil disables throttling but failed
+	// logins are still audited.
+	Throttle *LoginThrottle
+
+	// TwoFactor configures TOTP; nil disables enrolment and enforcement.
+	TwoFactor *TwoFactorConfig
+
+	// RefreshReuseGrace tolerates a replaced refresh token for this long
+	// after its rotation, for clients refreshing in parallel. Later reuse
+	// revokes the whole token family.
+	RefreshReuseGrace time.Duration
+
+	// Revocations holds the access tokens revoked so far, as checked by this
+	// instance; nil keeps revocations in the database only. Events, when
+	// set, tells the gateway and the other services about new revocations.
+	Revocations *revocation.List
+	Events      EventPublisher
+
+	// OIDC holds the identity providers users can sign in with, by name;
+	// empty disables social login.
+	OIDC map[string]*oidc.Provider
 }
 
-func (h *AuthService) CreateAccessToken(role, id string, accessExp time.Time) (string, error) {
+var defaultPolicy = password.Default()
+
+// checkPassword applies the password policy. The returned error wraps both
+// ErrValidation and the *password.PolicyError listing the failed rules.
+func (h *AuthService) checkPassword(pw string, identifiers ...string) error {
+	policy := h.Passwords
+	if policy == nil {
+		policy = defaultPolicy
+	}
+	if err := policy.Check(pw, identifiers...); err != nil {
+		return fmt.Errorf("%w: %w", err, ErrValidation)
+	}
+	return nil
+}
+
+// CreateAccessToken signs an access token; scopes are the permissions of
+// role.
+func (h *AuthService) CreateAccessToken(role, id string, accessExp time.Time, scopes ...string) (string, error) {
 	accessClaims := tokens.AccessClaims{
-		Role: role,
+		Role:   role,
+		Scopes: scopes,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   id,
 			ExpiresAt: jwt.NewNumericDate(accessExp),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jwthelp.NewJTI(),
 		},
 	}
 
+	if h.Keys != nil {
+		return h.Keys.Sign(accessClaims)
+	}
+
 	tokenAccess := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
 	accessToken, err := tokenAccess.SignedString(h.Repo.JWTSecret)
 	if err != nil {
@@ -67,32 +134,60 @@ func (h *AuthService) CreateRefreshToken(id string, refreshExp time.Time) (strin
 	return refreshToken, nil
 }
 
-func (h *AuthService) Register(ctx context.Context, username, password string) error {
+func (h *AuthService) Register(ctx context.Context, username, email, password string) error {
+	_, err := h.RegisterUser(ctx, username, email, password)
+	return err
+}
+
+// RegisterUser is Register for callers that need the created user. A failed
+// verification mail is logged and can be resent later.
+func (h *AuthService) RegisterUser(ctx context.Context, username, email, password string) (*models.User, error) {
 	if username == "" {
-		return fmt.Errorf("username must not be empty: %w", ErrValidation)
+		return nil, fmt.Errorf("username must not be empty: %w", ErrValidation)
+	}
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
 	}
 	if password == "" {
-		return fmt.Errorf("password must not be empty: %w", ErrValidation)
+		return nil, fmt.Errorf("password must not be empty: %w", ErrValidation)
+	}
+	if err := h.checkPassword(password, username, email); err != nil {
+		return nil, err
 	}
 
 	pwHash, err := pkg_hash.HashPassword(password)
 	if err != nil {
-		return fmt.Errorf("cannot hash the password: %w", ErrInternal)
+		return nil, fmt.Errorf("cannot hash the password: %w", ErrInternal)
 	}
 	user := models.User{
 		Username:     username,
 		PasswordHash: string(pwHash),
-		Role:         "user",
+		Role:         RoleUser,
+		Email:        &email,
 	}
 
 	if err := h.Repo.CreateUserIfNotExists(ctx, &user); err != nil {
 		if errors.Is(err, repo.ErrUserAlreadyExist) {
-			return fmt.Errorf("user already exist: %w", ErrConflict)
+			return nil, fmt.Errorf("user already exist: %w", ErrConflict)
 		} else {
-			return fmt.Errorf("internal server error: %w", ErrInternal)
+			return nil, fmt.Errorf("internal server error: %w", ErrInternal)
 		}
 	}
-	return nil
+
+	if err := h.sendVerification(ctx, &user); err != nil {
+		logging.FromContext(ctx).Warn("verification_mail_failed", "user_id", user.ID, "error", err)
+	}
+	return &user, nil
+}
+
+// MergeGuestCart hands the guest cart of a visitor who just logged in or
+// registered over to the cart service.
+func (h *AuthService) MergeGuestCart(ctx context.Context, userID uuid.UUID, guestToken string) error {
+	if h.Carts == nil || guestToken == "" {
+		return nil
+	}
+	return h.Carts.MergeGuestCart(ctx, userID, guestToken)
 }
 
 func (h *AuthService) Login(ctx context.Context, username, password string) (*transport.LoginResult, error) {
@@ -103,16 +198,44 @@ func (h *AuthService) Login(ctx context.Context, username, password string) (*tr
 		return nil, fmt.Errorf("password must not be empty: %w", ErrValidation)
 	}
 
+	if err := h.checkLoginLock(ctx, username); err != nil {
+		return nil, err
+	}
+
 	user, err := h.Repo.UserExist(ctx, username, password)
 	if err != nil {
 		if errors.Is(err, repo.ErrInvalidCredentials) {
+			h.loginFailed(ctx, username)
 			return nil, fmt.Errorf("invalid username or password: %w", ErrUnauthorized)
 		}
 		return nil, fmt.Errorf("internal server error: %w", ErrInternal)
 	}
+	if user.Disabled() {
+		return nil, fmt.Errorf("user %s: %w", user.ID, ErrAccountDisabled)
+	}
+
+	challenge, err := h.secondFactorChallenge(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		// The failures are reset only once the second factor is answered.
+		return &transport.LoginResult{UserID: user.ID, Challenge: challenge}, nil
+	}
+
+	h.loginSucceeded(ctx, username)
+	return h.issueSession(ctx, user)
+}
 
+// issueSession creates the access and refresh tokens of a fully
+// authenticated login.
+func (h *AuthService) issueSession(ctx context.Context, user *models.User) (*transport.LoginResult, error) {
+	scopes, err := h.rolePermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
 	accessExp := time.Now().Add(time.Minute * 15)
-	accessToken, err := h.CreateAccessToken(user.Role, user.ID.String(), accessExp)
+	accessToken, err := h.CreateAccessToken(user.Role, user.ID.String(), accessExp, scopes...)
 	if err != nil {
 		return nil, fmt.Errorf("internal server error: %w", ErrInternal)
 	}
@@ -123,18 +246,18 @@ func (h *AuthService) Login(ctx context.Context, username, password string) (*tr
 		return nil, fmt.Errorf("internal server error: %w", ErrInternal)
 	}
 
-	if err := h.Repo.AddRefreshToDB(ctx, refreshToken); err != nil {
+	if err := h.Repo.AddRefreshToDB(ctx, refreshToken, clientinfo.FromContext(ctx)); err != nil {
 		return nil, fmt.Errorf("internal server error: %w", ErrInternal)
 	}
 
 	return &transport.LoginResult{
+		UserID:       user.ID,
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		AccessExp:    accessExp,
 		RefreshExp:   refreshExp,
-		IsAdmin:      user.Role == "admin",
+		IsAdmin:      user.Role == RoleAdmin,
 	}, nil
-
 }
 
 func (h *AuthService) LogOut(ctx context.Context, refreshToken string) error {
@@ -171,10 +294,26 @@ func (h *AuthService) Refresh(ctx context.Context, refreshToken string) (*transp
 			return nil, ErrInternal
 		}
 	}
+	if user.Disabled() {
+		return nil, fmt.Errorf("user %s is disabled: %w", userId, ErrInvalidRefreshToken)
+	}
 	role := user.Role
+	if !user.TwoFactorEnabled() {
+		required, err := h.twoFactorRequired(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			return nil, fmt.Errorf("two-factor authentication required for role %s: %w", role, ErrInvalidRefreshToken)
+		}
+	}
 
+	scopes, err := h.rolePermissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
 	accessExp := time.Now().Add(time.Minute * 15)
-	accessTokenNew, err := h.CreateAccessToken(role, userId, accessExp)
+	accessTokenNew, err := h.CreateAccessToken(role, userId, accessExp, scopes...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create access token: %w", ErrInternal)
 	}
@@ -190,14 +329,21 @@ func (h *AuthService) Refresh(ctx context.Context, refreshToken string) (*transp
 		return nil, ErrInternal
 	}
 
+	client := clientinfo.FromContext(ctx)
 	newRefreshModel := models.RefreshToken{
 		Token:     jwthelp.Sha256Hex(refreshTokenNew),
 		UserID:    userUuid,
 		ExpiresAt: newRefreshClaims.ExpiresAt.Time,
 		JTI:       newRefreshClaims.ID,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
 	}
 
 	if err := h.Repo.RotateRefreshToken(ctx, jti, newRefreshModel); err != nil {
+		if errors.Is(err, repo.ErrTokenReused) {
+			h.refreshReused(ctx, jti)
+			return nil, fmt.Errorf("refresh token with jti: %s reused: %w", jti, ErrInvalidRefreshToken)
+		}
 		if errors.Is(err, repo.ErrTokenExpiredOrRevoked) {
 			return nil, fmt.Errorf("failed to rotate refresh token with jti: %s with error: %w", jti, ErrInvalidRefreshToken)
 		}
@@ -205,10 +351,11 @@ func (h *AuthService) Refresh(ctx context.Context, refreshToken string) (*transp
 	}
 
 	return &transport.LoginResult{
+		UserID:       userUuid,
 		AccessToken:  accessTokenNew,
 		RefreshToken: refreshTokenNew,
 		AccessExp:    accessExp,
 		RefreshExp:   refreshExp,
-		IsAdmin:      role == "admin",
+		IsAdmin:      role == RoleAdmin,
 	}, nil
 }
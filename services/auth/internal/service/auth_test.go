@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/password"
 	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -72,10 +73,13 @@ func TestAuthService_Register_Validation(t *testing.T) {
 	tests := []struct {
 		name     string
 		username string
+		email    string
 		password string
 	}{
-		{name: "empty username", username: "", password: "secret"},
-		{name: "empty password", username: "user", password: ""},
+		{name: "empty username", username: "", email: "user@example.com", password: "secret"},
+		{name: "empty email", username: "user", email: "", password: "secret"},
+		{name: "invalid email", username: "user", email: "User <user@example.com>", password: "secret"},
+		{name: "empty password", username: "user", email: "user@example.com", password: ""},
 	}
 
 	for _, tt := range tests {
@@ -83,13 +87,27 @@ func TestAuthService_Register_Validation(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			err := svc.Register(ctx, tt.username, tt.password)
+			err := svc.Register(ctx, tt.username, tt.email, tt.password)
 			require.Error(t, err)
 			assert.ErrorIs(t, err, ErrValidation)
 		})
 	}
 }
 
+func TestAuthService_Register_WeakPassword(t *testing.T) {
+	t.Parallel()
+
+	svc := newTestAuthService()
+
+	err := svc.Register(context.Background(), "alice", "alice@example.com", "alice")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	var policyErr *password.PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.NotEmpty(t, policyErr.Violations)
+}
+
 func TestAuthService_Login_Validation(t *testing.T) {
 	t.Parallel()
 
@@ -136,3 +154,33 @@ func TestAuthService_Refresh_InvalidToken(t *testing.T) {
 	assert.Nil(t, res)
 	assert.True(t, errors.Is(err, ErrInvalidRefreshToken))
 }
+
+func TestLoginThrottle_LockFor(t *testing.T) {
+	t.Parallel()
+
+	throttle := &LoginThrottle{
+		BackoffBase: time.Second,
+		BackoffMax:  time.Minute,
+		LockoutFor:  time.Hour,
+	}
+	rule := ThrottleRule{FreeAttempts: 3, LockoutAfter: 10}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 7, want: 8 * time.Second},
+		{failures: 9, want: 32 * time.Second},
+		{failures: 10, want: time.Hour},
+		{failures: 1000, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, throttle.lockFor(rule, tt.failures), "failures=%d", tt.failures)
+	}
+	assert.Equal(t, time.Minute, throttle.lockFor(ThrottleRule{FreeAttempts: 0}, 100))
+}
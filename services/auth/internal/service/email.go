@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	pkg_hash "github.com/Skotchmaster/online_shop/pkg/hash"
+	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/mailer"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+const (
+	defaultVerifyTTL = 48 * time.Hour
+	defaultResetTTL  = time.Hour
+)
+
+// normalizeEmail accepts a bare address only and lowercases it.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", fmt.Errorf("email must not be empty: %w", ErrValidation)
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("invalid email: %w", ErrValidation)
+	}
+	return strings.ToLower(email), nil
+}
+
+// newOpaqueToken returns a random URL-safe token.
+func newOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func ttlOrDefault(ttl, def time.Duration) time.Duration {
+	if ttl <= 0 {
+		return def
+	}
+	return ttl
+}
+
+// issueToken stores a hashed token for user and mails the link built from
+// path to the user's email.
+func (h *AuthService) issueToken(ctx context.Context, user *models.User, purpose models.TokenPurpose, ttl time.Duration, path, subject, text string) error {
+	if h.Mailer == nil || user.Email == nil {
+		return nil
+	}
+
+	raw, err := newOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("cannot generate token: %w", ErrInternal)
+	}
+	token := models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: jwthelp.Sha256Hex(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := h.Repo.CreateUserToken(ctx, &token); err != nil {
+		return fmt.Errorf("cannot store token: %w", ErrInternal)
+	}
+
+	link := strings.TrimRight(h.BaseURL, "/") + path + "?token=" + url.QueryEscape(raw)
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      *user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("%s\n\n%s\n\nThe link expires in %s.\n", text, link, ttl),
+	})
+}
+
+func (h *AuthService) sendVerification(ctx context.Context, user *models.User) error {
+	return h.issueToken(ctx, user, models.TokenVerifyEmail, ttlOrDefault(h.VerifyTTL, defaultVerifyTTL),
+		"/verify-email", "Confirm your email", "Open the link to confirm your email address:")
+}
+
+func (h *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("token must not be empty: %w", ErrValidation)
+	}
+	_, err := h.Repo.VerifyEmail(ctx, jwthelp.Sha256Hex(token))
+	if errors.Is(err, repo.ErrTokenInvalid) {
+		return fmt.Errorf("verification token: %w", ErrInvalidToken)
+	}
+	if err != nil {
+		return fmt.Errorf("verify email: %v: %w", err, ErrInternal)
+	}
+	return nil
+}
+
+func (h *AuthService) ResendVerification(ctx context.Context, userID uuid.UUID) error {
+	user, err := h.Repo.GetUserById(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("user not found: %w", ErrUnauthorized)
+	}
+	if err != nil {
+		return fmt.Errorf("get user: %v: %w", err, ErrInternal)
+	}
+	if user.Email == nil {
+		return fmt.Errorf("user has no email: %w", ErrValidation)
+	}
+	if user.EmailVerifiedAt != nil {
+		return fmt.Errorf("email already verified: %w", ErrConflict)
+	}
+	return h.sendVerification(ctx, user)
+}
+
+// RequestPasswordReset mails a reset link when the email belongs to a user.
+// Unknown emails are not reported, so the endpoint cannot be used to probe
+// for accounts.
+func (h *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.Repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logging.FromContext(ctx).Info("password_reset_unknown_email")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get user: %v: %w", err, ErrInternal)
+	}
+
+	return h.issueToken(ctx, user, models.TokenResetPassword, ttlOrDefault(h.ResetTTL, defaultResetTTL),
+		"/reset-password", "Reset your password", "Open the link to choose a new password:")
+}
+
+// ResetPassword sets a new password and signs the user out everywhere.
+func (h *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	if token == "" {
+		return fmt.Errorf("token must not be empty: %w", ErrValidation)
+	}
+	if password == "" {
+		return fmt.Errorf("password must not be empty: %w", ErrValidation)
+	}
+
+	hash := jwthelp.Sha256Hex(token)
+	user, err := h.Repo.UserByToken(ctx, models.TokenResetPassword, hash)
+	if errors.Is(err, repo.ErrTokenInvalid) {
+		return fmt.Errorf("reset token: %w", ErrInvalidToken)
+	}
+	if err != nil {
+		return fmt.Errorf("reset password: %v: %w", err, ErrInternal)
+	}
+	identifiers := []string{user.Username}
+	if user.Email != nil {
+		identifiers = append(identifiers, *user.Email)
+	}
+	if err := h.checkPassword(password, identifiers...); err != nil {
+		return err
+	}
+
+	pwHash, err := pkg_hash.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("cannot hash the password: %w", ErrInternal)
+	}
+
+	_, err = h.Repo.ResetPassword(ctx, hash, pwHash)
+	if errors.Is(err, repo.ErrTokenInvalid) {
+		return fmt.Errorf("reset token: %w", ErrInvalidToken)
+	}
+	if err != nil {
+		return fmt.Errorf("reset password: %v: %w", err, ErrInternal)
+	}
+	return h.revokeUserTokens(ctx, user.ID, "password_reset")
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	pkg_hash "github.com/Skotchmaster/online_shop/pkg/hash"
+	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/oidc"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/transport"
+)
+
+var ErrProviderUnavailable = errors.New("identity provider unavailable")
+
+const (
+	oidcLoginTTL      = 10 * time.Minute
+	maxUsernameLength = 32
+)
+
+// OIDCProviders returns the names of the configured identity providers.
+func (h *AuthService) OIDCProviders() []string {
+	names := make([]string, 0, len(h.OIDC))
+	for name := range h.OIDC {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (h *AuthService) oidcProvider(name string) (*oidc.Provider, error) {
+	p, ok := h.OIDC[name]
+	if !ok {
+		return nil, fmt.Errorf("identity provider %q: %w", name, ErrNotFound)
+	}
+	return p, nil
+}
+
+// StartOIDCLogin prepares a sign-in at the provider. linkUser is the
+// signed-in user adding the provider to their account, or uuid.Nil for a
+// sign-in. The returned state must come back with the callback.
+func (h *AuthService) StartOIDCLogin(ctx context.Context, provider string, linkUser uuid.UUID, loginHint string) (*transport.OIDCStart, error) {
+	p, err := h.oidcProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := newOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate state: %w", ErrInternal)
+	}
+	nonce, err := newOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate nonce: %w", ErrInternal)
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate code verifier: %w", ErrInternal)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier, loginHint)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %v: %w", provider, err, ErrProviderUnavailable)
+	}
+
+	login := models.OIDCLogin{
+		Provider:     provider,
+		StateHash:    jwthelp.Sha256Hex(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	}
+	if linkUser != uuid.Nil {
+		login.UserID = &linkUser
+	}
+	if err := h.Repo.CreateOIDCLogin(ctx, &login); err != nil {
+		return nil, fmt.Errorf("store oidc login: %v: %w", err, ErrInternal)
+	}
+	return &transport.OIDCStart{URL: authURL, State: state, ExpiresAt: login.ExpiresAt}, nil
+}
+
+// CompleteOIDCLogin redeems the code the provider sent back. A sign-in
+// returns the session, or a challenge when the user has a second factor; a
+// link returns the new identity and no session.
+//
+// A first sign-in creates a user. An existing account is never taken over
+// by email: when the verified email is already registered, its owner has to
+// sign in and link the provider instead.
+func (h *AuthService) CompleteOIDCLogin(ctx context.Context, provider, state, code string) (*transport.LoginResult, *models.UserIdentity, error) {
+	if state == "" || code == "" {
+		return nil, nil, fmt.Errorf("state and code are required: %w", ErrValidation)
+	}
+	p, err := h.oidcProvider(provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	login, err := h.Repo.ConsumeOIDCLogin(ctx, provider, jwthelp.Sha256Hex(state))
+	if errors.Is(err, repo.ErrTokenInvalid) {
+		return nil, nil, fmt.Errorf("oidc state: %w", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("consume oidc login: %v: %w", err, ErrInternal)
+	}
+
+	id, err := p.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if errors.Is(err, oidc.ErrRejected) {
+		return nil, nil, fmt.Errorf("provider %s: %v: %w", provider, err, ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("provider %s: %v: %w", provider, err, ErrProviderUnavailable)
+	}
+
+	if login.UserID != nil {
+		identity, err := h.linkIdentity(ctx, *login.UserID, provider, id)
+		return nil, identity, err
+	}
+
+	user, err := h.oidcUser(ctx, provider, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Disabled() {
+		return nil, nil, fmt.Errorf("user %s: %w", user.ID, ErrAccountDisabled)
+	}
+
+	challenge, err := h.secondFactorChallenge(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return &transport.LoginResult{UserID: user.ID, Challenge: challenge}, nil, nil
+	}
+	res, err := h.issueSession(ctx, user)
+	return res, nil, err
+}
+
+// oidcUser returns the user linked to the identity, creating one on the
+// first sign-in.
+func (h *AuthService) oidcUser(ctx context.Context, provider string, id *oidc.Identity) (*models.User, error) {
+	identity, err := h.Repo.FindIdentity(ctx, provider, id.Subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return h.signUpWithIdentity(ctx, provider, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find identity: %v: %w", err, ErrInternal)
+	}
+
+	if err := h.Repo.TouchIdentity(ctx, identity.ID, id.Email); err != nil {
+		logging.FromContext(ctx).Warn("identity_touch_failed", "identity_id", identity.ID, "error", err)
+	}
+	user, err := h.Repo.GetUserById(ctx, identity.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("get user: %v: %w", err, ErrInternal)
+	}
+	return user, nil
+}
+
+func (h *AuthService) signUpWithIdentity(ctx context.Context, provider string, id *oidc.Identity) (*models.User, error) {
+	now := time.Now()
+	user := models.User{Role: RoleUser}
+	if id.EmailVerified {
+		if email, err := normalizeEmail(id.Email); err == nil {
+			_, err := h.Repo.GetUserByEmail(ctx, email)
+			if err == nil {
+				return nil, fmt.Errorf("email of %s account is registered, link it from that user: %w", provider, ErrConflict)
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("get user by email: %v: %w", err, ErrInternal)
+			}
+			user.Email = &email
+			user.EmailVerifiedAt = &now
+		}
+	}
+	identity := models.UserIdentity{
+		Provider:    provider,
+		Subject:     id.Subject,
+		Email:       id.Email,
+		LastLoginAt: &now,
+	}
+
+	// The preferred username may be taken; retry with a random suffix.
+	base := usernameFor(provider, id)
+	for attempt := range 3 {
+		user.Username = base
+		if attempt > 0 {
+			user.Username = base[:min(len(base), maxUsernameLength-7)] + "_" + strings.ToLower(rand.Text()[:6])
+		}
+		err := h.Repo.CreateUserWithIdentity(ctx, &user, &identity)
+		switch {
+		case errors.Is(err, repo.ErrUserAlreadyExist):
+			continue
+		case errors.Is(err, repo.ErrIdentityTaken):
+			return nil, fmt.Errorf("%s account is already linked: %w", provider, ErrConflict)
+		case err != nil:
+			return nil, fmt.Errorf("create user: %v: %w", err, ErrInternal)
+		}
+		logging.FromContext(ctx).Info("user_signed_up", "user_id", user.ID, "provider", provider)
+		return &user, nil
+	}
+	return nil, fmt.Errorf("no free username for %s account: %w", provider, ErrConflict)
+}
+
+// usernameFor picks a username from the provider's profile.
+func usernameFor(provider string, id *oidc.Identity) string {
+	candidate := id.PreferredUsername
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(id.Email, "@")
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, candidate)
+	if len(name) < 3 {
+		name = provider + "_user"
+	}
+	return name[:min(len(name), maxUsernameLength)]
+}
+
+func (h *AuthService) linkIdentity(ctx context.Context, userID uuid.UUID, provider string, id *oidc.Identity) (*models.UserIdentity, error) {
+	user, err := h.Repo.GetUserById(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user: %v: %w", err, ErrInternal)
+	}
+	if user.Disabled() {
+		return nil, fmt.Errorf("user %s: %w", user.ID, ErrAccountDisabled)
+	}
+
+	identity := models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  id.Subject,
+		Email:    id.Email,
+	}
+	info := clientinfo.FromContext(ctx)
+	event := models.SecurityEvent{
+		UserID:    &user.ID,
+		Type:      models.SecurityIdentityLinked,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Details:   "provider " + provider,
+	}
+	err = h.Repo.LinkIdentity(ctx, &identity, &event)
+	if errors.Is(err, repo.ErrIdentityTaken) {
+		return nil, fmt.Errorf("%s account is already linked: %w", provider, ErrConflict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("link identity: %v: %w", err, ErrInternal)
+	}
+	return &identity, nil
+}
+
+func (h *AuthService) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	identities, err := h.Repo.ListIdentities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list identities: %v: %w", err, ErrInternal)
+	}
+	return identities, nil
+}
+
+// SetPassword gives a user created by a social login a password, so they
+// can sign in without the provider and unlink it. Users who already have a
+// password change it through the reset flow.
+func (h *AuthService) SetPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	if password == "" {
+		return fmt.Errorf("password must not be empty: %w", ErrValidation)
+	}
+	user, err := h.Repo.GetUserById(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("get user: %v: %w", err, ErrInternal)
+	}
+	if user.HasPassword() {
+		return fmt.Errorf("user %s has a password, use the password reset: %w", userID, ErrConflict)
+	}
+	identifiers := []string{user.Username}
+	if user.Email != nil {
+		identifiers = append(identifiers, *user.Email)
+	}
+	if err := h.checkPassword(password, identifiers...); err != nil {
+		return err
+	}
+	pwHash, err := pkg_hash.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("cannot hash the password: %w", ErrInternal)
+	}
+
+	info := clientinfo.FromContext(ctx)
+	event := models.SecurityEvent{
+		UserID:    &userID,
+		Type:      models.SecurityPasswordSet,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+	}
+	err = h.Repo.SetFirstPassword(ctx, userID, pwHash, &event)
+	switch {
+	case errors.Is(err, repo.ErrPasswordSet):
+		return fmt.Errorf("user %s has a password, use the password reset: %w", userID, ErrConflict)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	case err != nil:
+		return fmt.Errorf("set password: %v: %w", err, ErrInternal)
+	}
+	return nil
+}
+
+// UnlinkIdentity removes the user's account at provider. The last linked
+// account of a user without a password stays.
+func (h *AuthService) UnlinkIdentity(ctx context.Context, userID uuid.UUID, provider string) error {
+	info := clientinfo.FromContext(ctx)
+	event := models.SecurityEvent{
+		UserID:    &userID,
+		Type:      models.SecurityIdentityUnlinked,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Details:   "provider " + provider,
+	}
+	removed, err := h.Repo.UnlinkIdentity(ctx, userID, provider, &event)
+	switch {
+	case errors.Is(err, repo.ErrLastSignInMethod):
+		return fmt.Errorf("%s is the only way to sign in, set a password first: %w", provider, ErrConflict)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	case err != nil:
+		return fmt.Errorf("unlink identity: %v: %w", err, ErrInternal)
+	case !removed:
+		return fmt.Errorf("%s account: %w", provider, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Skotchmaster/online_shop/pkg/events"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/revocation"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+)
+
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, topic, key string, event any) error
+}
+
+// LoadRevocations fills Revocations from the database, so revocations made
+// while this instance was down are enforced before any event arrives.
+func (h *AuthService) LoadRevocations(ctx context.Context) error {
+	if h.Revocations == nil {
+		return nil
+	}
+	denied, watermarks, err := h.Repo.ActiveRevocations(ctx, time.Now().Add(-revocation.Window))
+	if err != nil {
+		return fmt.Errorf("load revocations: %w", err)
+	}
+	for _, d := range denied {
+		h.Revocations.RevokeToken(d.JTI, d.ExpiresAt)
+	}
+	for _, w := range watermarks {
+		h.Revocations.RevokeUser(w.UserID.String(), w.NotBefore)
+	}
+	return nil
+}
+
+// RevokeAccessToken denies a still valid access token until it expires.
+// Tokens that are invalid or already expired are ignored.
+func (h *AuthService) RevokeAccessToken(ctx context.Context, accessToken string) error {
+	if accessToken == "" {
+		return nil
+	}
+	verifier := &tokens.Verifier{Secret: h.Repo.JWTSecret}
+	if h.Keys != nil {
+		verifier.Keys = h.Keys
+	}
+	claims, err := verifier.AccessClaims(ctx, accessToken)
+	if err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil
+	}
+
+	row := models.RevokedAccessToken{JTI: claims.ID, UserID: userID, ExpiresAt: claims.ExpiresAt.Time}
+	if err := h.Repo.RevokeAccessToken(ctx, &row); err != nil {
+		return fmt.Errorf("revoke access token: %v: %w", err, ErrInternal)
+	}
+	h.publishRevocation(ctx, events.AuthEvent{
+		Type:      events.AccessTokenRevoked,
+		UserID:    userID,
+		JTI:       row.JTI,
+		ExpiresAt: row.ExpiresAt,
+		Reason:    "logout",
+	})
+	return nil
+}
+
+// revokeUserTokens denies every access token of the user issued so far.
+// Sessions that are still valid pick up a new token on their next refresh.
+func (h *AuthService) revokeUserTokens(ctx context.Context, userID uuid.UUID, reason string) error {
+	now := time.Now().UTC()
+	if err := h.Repo.SetTokenWatermark(ctx, userID, now); err != nil {
+		return fmt.Errorf("revoke access tokens: %v: %w", err, ErrInternal)
+	}
+	h.publishRevocation(ctx, events.AuthEvent{
+		Type:      events.UserTokensRevoked,
+		UserID:    userID,
+		NotBefore: now,
+		Reason:    reason,
+	})
+	return nil
+}
+
+// publishRevocation applies e to this instance at once and sends it to the
+// other services in the background; the database copy is already written,
+// so a failed publish is only logged.
+func (h *AuthService) publishRevocation(ctx context.Context, e events.AuthEvent) {
+	e.EventID = uuid.New()
+	e.OccurredAt = time.Now().UTC()
+	if h.Revocations != nil {
+		h.Revocations.Apply(e)
+	}
+	if h.Events == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := h.Events.PublishEvent(ctx, events.AuthEventsTopic, e.UserID.String(), e); err != nil {
+			logging.FromContext(ctx).Error("auth_event_failed", "type", e.Type, "user_id", e.UserID, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/permissions"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/transport"
+)
+
+const (
+	RoleAdmin = "admin"
+	// RoleUser is given to every registered account.
+	RoleUser = "user"
+)
+
+var roleName = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// ErrNotGrantable rejects a role change that would hand out a permission the
+// actor does not hold.
+var ErrNotGrantable = errors.New("permission not held by the actor")
+
+// rolePermissions returns the permissions put into the access tokens of
+// role. admin always has every permission, including ones added after its
+// role was stored.
+func (h *AuthService) rolePermissions(ctx context.Context, role string) ([]string, error) {
+	if role == RoleAdmin {
+		return slices.Clone(permissions.All), nil
+	}
+	perms, err := h.Repo.RolePermissions(ctx, role)
+	if err != nil {
+		return nil, fmt.Errorf("role permissions: %v: %w", err, ErrInternal)
+	}
+	return perms, nil
+}
+
+// checkGrantable fails unless the actor's role holds every permission in
+// perms, so users:manage cannot be used to grant more than the actor has.
+func (h *AuthService) checkGrantable(ctx context.Context, actorID uuid.UUID, perms []string) error {
+	actor, err := h.Repo.GetUserById(ctx, actorID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("actor %s: %w", actorID, ErrNotGrantable)
+	}
+	if err != nil {
+		return fmt.Errorf("get actor: %v: %w", err, ErrInternal)
+	}
+	held, err := h.rolePermissions(ctx, actor.Role)
+	if err != nil {
+		return err
+	}
+	for _, p := range perms {
+		if !slices.Contains(held, p) {
+			return fmt.Errorf("%q: %w", p, ErrNotGrantable)
+		}
+	}
+	return nil
+}
+
+// checkRoleGrantable is checkGrantable for every permission of role.
+func (h *AuthService) checkRoleGrantable(ctx context.Context, actorID uuid.UUID, role string) error {
+	perms, err := h.rolePermissions(ctx, role)
+	if err != nil {
+		return err
+	}
+	return h.checkGrantable(ctx, actorID, perms)
+}
+
+func (h *AuthService) ListRoles(ctx context.Context) ([]transport.Role, error) {
+	roles, perms, err := h.Repo.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %v: %w", err, ErrInternal)
+	}
+
+	out := make([]transport.Role, 0, len(roles))
+	for _, role := range roles {
+		granted := perms[role.Name]
+		if role.Name == RoleAdmin {
+			granted = permissions.All
+		}
+		if granted == nil {
+			granted = []string{}
+		}
+		out = append(out, transport.Role{
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: granted,
+			BuiltIn:     role.Name == RoleAdmin || role.Name == RoleUser,
+		})
+	}
+	return out, nil
+}
+
+// SaveRole creates a role or replaces its permissions. Users of the role
+// get the new permissions with their next access token; the current ones
+// are revoked. The actor must hold both the old and the new permissions.
+func (h *AuthService) SaveRole(ctx context.Context, actorID uuid.UUID, name, description string, perms []string) (*transport.Role, error) {
+	if name == RoleAdmin {
+		return nil, fmt.Errorf("role %s always has every permission: %w", name, ErrForbidden)
+	}
+	if !roleName.MatchString(name) {
+		return nil, fmt.Errorf("invalid role name %q: %w", name, ErrValidation)
+	}
+	perms = slices.Compact(slices.Sorted(slices.Values(perms)))
+	if perms == nil {
+		perms = []string{}
+	}
+	for _, p := range perms {
+		if !slices.Contains(permissions.All, p) {
+			return nil, fmt.Errorf("unknown permission %q: %w", p, ErrValidation)
+		}
+	}
+	if err := h.checkRoleGrantable(ctx, actorID, name); err != nil {
+		return nil, err
+	}
+	if err := h.checkGrantable(ctx, actorID, perms); err != nil {
+		return nil, err
+	}
+
+	role := models.Role{Name: name, Description: description}
+	if err := h.Repo.SaveRole(ctx, &role, perms, newAuditEntry(ctx, actorID, models.AuditRoleSaved)); err != nil {
+		return nil, fmt.Errorf("save role: %v: %w", err, ErrInternal)
+	}
+
+	users, err := h.Repo.UsersWithRole(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("role users: %v: %w", err, ErrInternal)
+	}
+	for _, userID := range users {
+		if err := h.revokeUserTokens(ctx, userID, "role_permissions_changed"); err != nil {
+			logging.FromContext(ctx).Error("access_token_revoke_failed", "user_id", userID, "error", err)
+		}
+	}
+
+	return &transport.Role{
+		Name:        name,
+		Description: description,
+		Permissions: perms,
+		BuiltIn:     name == RoleUser,
+	}, nil
+}
+
+func (h *AuthService) DeleteRole(ctx context.Context, actorID uuid.UUID, name string) error {
+	if name == RoleAdmin || name == RoleUser {
+		return fmt.Errorf("role %s is built in: %w", name, ErrForbidden)
+	}
+	if err := h.checkRoleGrantable(ctx, actorID, name); err != nil {
+		return err
+	}
+	err := h.Repo.DeleteRole(ctx, name, newAuditEntry(ctx, actorID, models.AuditRoleDeleted))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("role %s: %w", name, ErrNotFound)
+	case errors.Is(err, repo.ErrRoleInUse):
+		return fmt.Errorf("role %s is assigned to users: %w", name, ErrConflict)
+	case err != nil:
+		return fmt.Errorf("delete role: %v: %w", err, ErrInternal)
+	}
+	return nil
+}
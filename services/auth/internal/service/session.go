@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/transport"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// currentSession returns the session the refresh token belongs to, or
+// uuid.Nil when the token is missing, invalid or not the user's.
+func (h *AuthService) currentSession(ctx context.Context, userID uuid.UUID, refreshToken string) uuid.UUID {
+	if refreshToken == "" {
+		return uuid.Nil
+	}
+	claims, err := tokens.RefreshClaimsFromToken(refreshToken, h.Repo.RefreshSecret)
+	if err != nil {
+		return uuid.Nil
+	}
+	row, err := h.Repo.FindRefreshByID(ctx, claims.ID)
+	if err != nil || row.UserID != userID {
+		return uuid.Nil
+	}
+	return row.SessionID
+}
+
+// ListSessions returns the active sessions of the user; the one behind
+// refreshToken is marked as current.
+func (h *AuthService) ListSessions(ctx context.Context, userID uuid.UUID, refreshToken string) ([]transport.Session, error) {
+	rows, err := h.Repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %v: %w", err, ErrInternal)
+	}
+	current := h.currentSession(ctx, userID, refreshToken)
+
+	sessions := make([]transport.Session, 0, len(rows))
+	for _, row := range rows {
+		lastUsed := row.CreatedAt
+		if row.LastUsedAt != nil {
+			lastUsed = *row.LastUsedAt
+		}
+		sessions = append(sessions, transport.Session{
+			ID:         row.SessionID,
+			Device:     clientinfo.Device(row.UserAgent),
+			IP:         row.IP,
+			UserAgent:  row.UserAgent,
+			CreatedAt:  row.CreatedAt,
+			LastUsedAt: lastUsed,
+			ExpiresAt:  row.ExpiresAt,
+			Current:    row.SessionID == current,
+		})
+	}
+	return sessions, nil
+}
+
+func (h *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	revoked, err := h.Repo.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("revoke session: %v: %w", err, ErrInternal)
+	}
+	if !revoked {
+		return fmt.Errorf("session %s: %w", sessionID, ErrNotFound)
+	}
+	return h.revokeUserTokens(ctx, userID, "session_revoked")
+}
+
+// RevokeOtherSessions signs the user out everywhere except the session
+// behind refreshToken.
+func (h *AuthService) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, refreshToken string) (int64, error) {
+	current := h.currentSession(ctx, userID, refreshToken)
+	if current == uuid.Nil {
+		return 0, fmt.Errorf("current session is unknown: %w", ErrValidation)
+	}
+	n, err := h.Repo.RevokeSessions(ctx, userID, current)
+	if err != nil {
+		return 0, fmt.Errorf("revoke sessions: %v: %w", err, ErrInternal)
+	}
+	return n, h.revokeUserTokens(ctx, userID, "sessions_revoked")
+}
+
+// RevokeUserSessions signs a user out of every session; used by admins and
+// recorded in the audit log.
+func (h *AuthService) RevokeUserSessions(ctx context.Context, actorID, userID uuid.UUID) (int64, error) {
+	n, err := h.Repo.RevokeUserSessions(ctx, userID, newAuditEntry(ctx, actorID, models.AuditSessionsRevoked))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("revoke sessions: %v: %w", err, ErrInternal)
+	}
+	return n, h.revokeUserTokens(ctx, userID, "admin_revoked")
+}
+
+const maxSecurityEvents = 200
+
+// refreshReused handles a refresh token presented after it was rotated: the
+// token was copied, so every token of its family is revoked and the event is
+// recorded. Reuse within RefreshReuseGrace is taken for a parallel refresh
+// by the same client and only rejected.
+func (h *AuthService) refreshReused(ctx context.Context, jti string) {
+	l := logging.FromContext(ctx)
+	row, err := h.Repo.FindRefreshByID(ctx, jti)
+	if err != nil {
+		l.Error("refresh_reuse_lookup_failed", "jti", jti, "error", err)
+		return
+	}
+	if row.RotatedAt != nil && time.Since(*row.RotatedAt) < h.RefreshReuseGrace {
+		l.Info("refresh_reuse_within_grace", "user_id", row.UserID, "session_id", row.SessionID)
+		return
+	}
+
+	revoked, err := h.Repo.RevokeFamily(ctx, row.SessionID)
+	if err != nil {
+		l.Error("refresh_family_revoke_failed", "session_id", row.SessionID, "error", err)
+	}
+	l.Warn("refresh_token_reuse", "user_id", row.UserID, "session_id", row.SessionID, "revoked", revoked)
+	if err := h.revokeUserTokens(ctx, row.UserID, "refresh_reuse"); err != nil {
+		l.Error("access_token_revoke_failed", "user_id", row.UserID, "error", err)
+	}
+
+	info := clientinfo.FromContext(ctx)
+	event := models.SecurityEvent{
+		UserID:    &row.UserID,
+		Type:      models.SecurityRefreshReuse,
+		SessionID: &row.SessionID,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Details:   fmt.Sprintf("token rotated at %s reused, %d tokens revoked", row.RotatedAt.UTC().Format(time.RFC3339), revoked),
+	}
+	if err := h.Repo.AddSecurityEvent(ctx, &event); err != nil {
+		l.Error("security_event_failed", "error", err)
+	}
+}
+
+func (h *AuthService) ListSecurityEvents(ctx context.Context, userID *uuid.UUID, limit int) ([]models.SecurityEvent, error) {
+	if limit <= 0 || limit > maxSecurityEvents {
+		limit = maxSecurityEvents
+	}
+	events, err := h.Repo.ListSecurityEvents(ctx, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list security events: %v: %w", err, ErrInternal)
+	}
+	return events, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+)
+
+var ErrTooManyAttempts = errors.New("too many login attempts")
+
+// LockedError rejects a login while its username or IP is locked.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("login locked, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Is(target error) bool { return target == ErrTooManyAttempts }
+
+type ThrottleRule struct {
+	// FreeAttempts failures pass without delay; each further one doubles it.
+	FreeAttempts int
+	// LockoutAfter failures lock the key for LockoutFor.
+	LockoutAfter int
+}
+
+// LoginThrottle slows down repeated failed logins per username and per IP.
+// State lives in Postgres, so limits hold across auth replicas.
+type LoginThrottle struct {
+	User ThrottleRule
+	IP   ThrottleRule
+
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// LockoutFor is the long lockout and also how long failures are counted.
+	LockoutFor time.Duration
+}
+
+// lockFor returns how long a key stays locked after its failures-th failure.
+func (t *LoginThrottle) lockFor(rule ThrottleRule, failures int) time.Duration {
+	if rule.LockoutAfter > 0 && failures >= rule.LockoutAfter {
+		return t.LockoutFor
+	}
+	if failures <= rule.FreeAttempts {
+		return 0
+	}
+
+	shift := failures - rule.FreeAttempts - 1
+	if shift > 30 {
+		return t.BackoffMax
+	}
+	d := t.BackoffBase << shift
+	if d <= 0 || d > t.BackoffMax {
+		d = t.BackoffMax
+	}
+	return d
+}
+
+func userThrottleKey(username string) string { return "user:" + username }
+func ipThrottleKey(ip string) string         { return "ip:" + ip }
+
+func throttleKeys(username, ip string) []string {
+	keys := []string{userThrottleKey(username)}
+	if ip != "" {
+		keys = append(keys, ipThrottleKey(ip))
+	}
+	return keys
+}
+
+// checkLoginLock fails with *LockedError while the username or IP is locked.
+func (h *AuthService) checkLoginLock(ctx context.Context, username string) error {
+	if h.Throttle == nil {
+		return nil
+	}
+	info := clientinfo.FromContext(ctx)
+
+	until, err := h.Repo.LoginLockedUntil(ctx, throttleKeys(username, info.IP))
+	if err != nil {
+		return fmt.Errorf("check login lock: %v: %w", err, ErrInternal)
+	}
+	if until == nil {
+		return nil
+	}
+
+	h.auditLogin(ctx, username, models.LoginFailedLocked)
+	return &LockedError{RetryAfter: time.Until(*until)}
+}
+
+// loginFailed records a failed password check against the username and IP.
+func (h *AuthService) loginFailed(ctx context.Context, username string) {
+	h.auditLogin(ctx, username, models.LoginFailedPassword)
+	h.recordLoginFailure(ctx, username)
+}
+
+// secondFactorFailed records a wrong TOTP or recovery code like a wrong
+// password, so guessing codes runs into the same backoff and lockout.
+func (h *AuthService) secondFactorFailed(ctx context.Context, username string) {
+	h.auditLogin(ctx, username, models.LoginFailedSecondFactor)
+	h.recordLoginFailure(ctx, username)
+}
+
+func (h *AuthService) recordLoginFailure(ctx context.Context, username string) {
+	if h.Throttle == nil {
+		return
+	}
+	l := logging.FromContext(ctx)
+	info := clientinfo.FromContext(ctx)
+
+	rules := map[string]ThrottleRule{userThrottleKey(username): h.Throttle.User}
+	if info.IP != "" {
+		rules[ipThrottleKey(info.IP)] = h.Throttle.IP
+	}
+
+	for key, rule := range rules {
+		failures, err := h.Repo.RecordLoginFailure(ctx, key, h.Throttle.LockoutFor)
+		if err != nil {
+			l.Error("login_failure_record_failed", "key", key, "error", err)
+			continue
+		}
+		lock := h.Throttle.lockFor(rule, failures)
+		if lock == 0 {
+			continue
+		}
+		if err := h.Repo.LockLogin(ctx, key, time.Now().Add(lock)); err != nil {
+			l.Error("login_lock_failed", "key", key, "error", err)
+			continue
+		}
+		l.Warn("login_locked", "key", key, "failures", failures, "for", lock.String())
+	}
+}
+
+// loginSucceeded resets the username failures once the login is complete,
+// that is after the second factor when one is asked for.
+func (h *AuthService) loginSucceeded(ctx context.Context, username string) {
+	if h.Throttle == nil {
+		return
+	}
+	if _, err := h.Repo.ResetLoginFailures(ctx, userThrottleKey(username)); err != nil {
+		logging.FromContext(ctx).Error("login_failure_reset_failed", "error", err)
+	}
+}
+
+func (h *AuthService) auditLogin(ctx context.Context, username, reason string) {
+	info := clientinfo.FromContext(ctx)
+	attempt := models.LoginAttempt{
+		Username:  username,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Reason:    reason,
+	}
+	if err := h.Repo.AddLoginAttempt(ctx, &attempt); err != nil {
+		logging.FromContext(ctx).Error("login_audit_failed", "error", err)
+	}
+}
+
+// UnlockLogin clears failures and locks of a username and/or IP and records
+// the unlock in the audit log.
+func (h *AuthService) UnlockLogin(ctx context.Context, actorID uuid.UUID, username, ip string) (int64, error) {
+	var keys []string
+	if username != "" {
+		keys = append(keys, userThrottleKey(username))
+	}
+	if ip != "" {
+		keys = append(keys, ipThrottleKey(ip))
+	}
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("username or ip is required: %w", ErrValidation)
+	}
+
+	entry := newAuditEntry(ctx, actorID, models.AuditLoginUnlocked)
+	entry.Details = fmt.Sprintf("username %q, ip %q", username, ip)
+	n, err := h.Repo.UnlockLogin(ctx, keys, entry)
+	if err != nil {
+		return 0, fmt.Errorf("unlock login: %v: %w", err, ErrInternal)
+	}
+	return n, nil
+}
+
+const maxLoginAttempts = 200
+
+func (h *AuthService) ListLoginAttempts(ctx context.Context, username, ip string, limit int) ([]models.LoginAttempt, error) {
+	if limit <= 0 || limit > maxLoginAttempts {
+		limit = maxLoginAttempts
+	}
+	attempts, err := h.Repo.ListLoginAttempts(ctx, username, ip, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list login attempts: %v: %w", err, ErrInternal)
+	}
+	return attempts, nil
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/clientinfo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/secretbox"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/totp"
+	"github.com/Skotchmaster/online_shop/services/auth/internal/transport"
+)
+
+var (
+	ErrForbidden    = errors.New("forbidden")
+	ErrInvalidCode  = errors.New("invalid two-factor code")
+	ErrTwoFactorOff = errors.New("two-factor authentication is not enabled")
+)
+
+const (
+	challengeTTL         = 5 * time.Minute
+	challengeMaxAttempts = 5
+	enrolLinkTTL         = 30 * time.Minute
+	recoveryCodeCount    = 10
+	totpSkew             = 1
+)
+
+type TwoFactorConfig struct {
+	// Box seals TOTP secrets at rest.
+	Box    *secretbox.Box
+	Issuer string
+	// RequiredPermissions must be guarded by TOTP: users whose role grants
+	// any of them are enrolled during login and cannot disable it.
+	RequiredPermissions []string
+}
+
+// twoFactorRequired reports whether the user's role grants a permission
+// that needs TOTP. It follows the role's permissions rather than its name,
+// so a custom role given users:manage is covered like admin.
+func (h *AuthService) twoFactorRequired(ctx context.Context, user *models.User) (bool, error) {
+	if h.TwoFactor == nil || len(h.TwoFactor.RequiredPermissions) == 0 {
+		return false, nil
+	}
+	granted, err := h.rolePermissions(ctx, user.Role)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(granted, func(p string) bool {
+		return slices.Contains(h.TwoFactor.RequiredPermissions, p)
+	}), nil
+}
+
+// secondFactorChallenge returns the challenge a user must answer before a
+// session is issued, or nil when the password is enough.
+func (h *AuthService) secondFactorChallenge(ctx context.Context, user *models.User) (*transport.TwoFactorChallenge, error) {
+	if h.TwoFactor == nil {
+		return nil, nil
+	}
+	setup := !user.TwoFactorEnabled()
+	if setup {
+		required, err := h.twoFactorRequired(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+	}
+
+	raw, err := newOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate challenge: %w", ErrInternal)
+	}
+	ch := models.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: jwthelp.Sha256Hex(raw),
+		Setup:     setup,
+		ExpiresAt: time.Now().Add(challengeTTL),
+	}
+	out := &transport.TwoFactorChallenge{Token: raw, ExpiresAt: ch.ExpiresAt, SetupRequired: setup}
+
+	if setup {
+		mailed, err := h.mailEnrolment(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if mailed {
+			out.EnrolmentMailed = true
+		} else {
+			secret, err := h.newPendingSecret(ctx, user)
+			if err != nil {
+				return nil, err
+			}
+			out.Secret = secret.Secret
+			out.URI = secret.URI
+		}
+	}
+
+	if err := h.Repo.CreateLoginChallenge(ctx, &ch); err != nil {
+		return nil, fmt.Errorf("cannot store challenge: %w", ErrInternal)
+	}
+	return out, nil
+}
+
+// mailEnrolment sends an enrolment link to the user's verified email, so the
+// password alone does not reveal the TOTP secret. It reports false when
+// there is no verified email or no mailer to send it with.
+func (h *AuthService) mailEnrolment(ctx context.Context, user *models.User) (bool, error) {
+	if h.Mailer == nil || user.Email == nil || user.EmailVerifiedAt == nil {
+		return false, nil
+	}
+	err := h.issueToken(ctx, user, models.TokenEnrolTOTP, enrolLinkTTL, "/setup-2fa",
+		"Set up two-factor authentication", "Your role requires two-factor authentication. Open the link to get the key for your authenticator app:")
+	if err != nil {
+		return false, fmt.Errorf("mail enrolment link: %v: %w", err, ErrInternal)
+	}
+	return true, nil
+}
+
+// EnrolTOTP opens a mailed enrolment link and returns the secret to add to
+// an authenticator. The first code from it answers the setup challenge.
+func (h *AuthService) EnrolTOTP(ctx context.Context, token string) (*transport.TOTPSetup, error) {
+	if h.TwoFactor == nil {
+		return nil, ErrTwoFactorOff
+	}
+	if token == "" {
+		return nil, fmt.Errorf("token must not be empty: %w", ErrValidation)
+	}
+	user, err := h.Repo.ConsumeUserToken(ctx, models.TokenEnrolTOTP, jwthelp.Sha256Hex(token))
+	if errors.Is(err, repo.ErrTokenInvalid) {
+		return nil, fmt.Errorf("enrolment token: %w", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("enrolment token: %v: %w", err, ErrInternal)
+	}
+	if user.Disabled() {
+		return nil, fmt.Errorf("user %s: %w", user.ID, ErrAccountDisabled)
+	}
+	return h.newPendingSecret(ctx, user)
+}
+
+// CompleteLogin answers a login challenge with a TOTP code or, for enrolled
+// users, a recovery code. For a setup challenge the code enables TOTP and the
+// new recovery codes are returned alongside the session.
+func (h *AuthService) CompleteLogin(ctx context.Context, challenge, code, recoveryCode string) (*transport.LoginResult, []string, error) {
+	if h.TwoFactor == nil {
+		return nil, nil, ErrTwoFactorOff
+	}
+	if challenge == "" || (code == "" && recoveryCode == "") {
+		return nil, nil, fmt.Errorf("challenge and code are required: %w", ErrValidation)
+	}
+
+	ch, err := h.Repo.OpenLoginChallenge(ctx, jwthelp.Sha256Hex(challenge), challengeMaxAttempts)
+	if errors.Is(err, repo.ErrTokenInvalid) {
+		return nil, nil, fmt.Errorf("login challenge: %w", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("open challenge: %v: %w", err, ErrInternal)
+	}
+
+	user, err := h.Repo.GetUserById(ctx, ch.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get user: %v: %w", err, ErrInternal)
+	}
+	if user.Disabled() {
+		return nil, nil, fmt.Errorf("user %s: %w", user.ID, ErrAccountDisabled)
+	}
+	if err := h.checkLoginLock(ctx, user.Username); err != nil {
+		return nil, nil, err
+	}
+
+	var recovery []string
+	if ch.Setup {
+		if user.TOTPSecret == nil {
+			return nil, nil, fmt.Errorf("open the mailed enrolment link first: %w", ErrValidation)
+		}
+		recovery, err = h.enable(ctx, user, code, "enrolled at login")
+	} else {
+		err = h.verifySecondFactor(ctx, user, code, recoveryCode)
+	}
+	if errors.Is(err, ErrInvalidCode) {
+		h.secondFactorFailed(ctx, user.Username)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closed, err := h.Repo.CloseLoginChallenge(ctx, ch.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("close challenge: %v: %w", err, ErrInternal)
+	}
+	if !closed {
+		return nil, nil, fmt.Errorf("login challenge already used: %w", ErrInvalidToken)
+	}
+
+	h.loginSucceeded(ctx, user.Username)
+	res, err := h.issueSession(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, recovery, nil
+}
+
+// SetupTOTP starts enrolment for a signed-in user; EnableTOTP finishes it.
+func (h *AuthService) SetupTOTP(ctx context.Context, userID uuid.UUID) (*transport.TOTPSetup, error) {
+	if h.TwoFactor == nil {
+		return nil, ErrTwoFactorOff
+	}
+	user, err := h.userForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, fmt.Errorf("two-factor authentication already enabled: %w", ErrConflict)
+	}
+	return h.newPendingSecret(ctx, user)
+}
+
+func (h *AuthService) EnableTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if h.TwoFactor == nil {
+		return nil, ErrTwoFactorOff
+	}
+	user, err := h.userForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, fmt.Errorf("two-factor authentication already enabled: %w", ErrConflict)
+	}
+	return h.enable(ctx, user, code, "enrolled in settings")
+}
+
+// DisableTOTP needs a current code or an unused recovery code.
+func (h *AuthService) DisableTOTP(ctx context.Context, userID uuid.UUID, code, recoveryCode string) error {
+	if h.TwoFactor == nil {
+		return ErrTwoFactorOff
+	}
+	user, err := h.userForTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	required, err := h.twoFactorRequired(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fmt.Errorf("two-factor authentication is mandatory for role %s: %w", user.Role, ErrForbidden)
+	}
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorOff
+	}
+	if err := h.verifySecondFactor(ctx, user, code, recoveryCode); err != nil {
+		return err
+	}
+	if err := h.Repo.DisableTOTP(ctx, user.ID); err != nil {
+		return fmt.Errorf("disable totp: %v: %w", err, ErrInternal)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a
+// current TOTP code.
+func (h *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if h.TwoFactor == nil {
+		return nil, ErrTwoFactorOff
+	}
+	user, err := h.userForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorOff
+	}
+	if err := h.verifySecondFactor(ctx, user, code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate recovery codes: %w", ErrInternal)
+	}
+	if err := h.Repo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, fmt.Errorf("store recovery codes: %v: %w", err, ErrInternal)
+	}
+	return codes, nil
+}
+
+func (h *AuthService) userForTwoFactor(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := h.Repo.GetUserById(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user not found: %w", ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user: %v: %w", err, ErrInternal)
+	}
+	return user, nil
+}
+
+func (h *AuthService) newPendingSecret(ctx context.Context, user *models.User) (*transport.TOTPSetup, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate secret: %w", ErrInternal)
+	}
+	sealed, err := h.TwoFactor.Box.Seal([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("cannot seal secret: %w", ErrInternal)
+	}
+	if err := h.Repo.SetPendingTOTP(ctx, user.ID, sealed); err != nil {
+		if errors.Is(err, repo.ErrTwoFactorEnabled) {
+			return nil, fmt.Errorf("two-factor authentication already enabled: %w", ErrConflict)
+		}
+		return nil, fmt.Errorf("store secret: %v: %w", err, ErrInternal)
+	}
+	user.TOTPSecret = &sealed
+
+	return &transport.TOTPSetup{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(h.TwoFactor.Issuer, user.Username, secret),
+	}, nil
+}
+
+func (h *AuthService) totpSecret(user *models.User) (string, error) {
+	if user.TOTPSecret == nil {
+		return "", ErrTwoFactorOff
+	}
+	secret, err := h.TwoFactor.Box.Open(*user.TOTPSecret)
+	if err != nil {
+		return "", fmt.Errorf("open totp secret: %v: %w", err, ErrInternal)
+	}
+	return string(secret), nil
+}
+
+// enable checks the first code against the pending secret and turns TOTP on.
+// details tells the security event where the user enrolled.
+func (h *AuthService) enable(ctx context.Context, user *models.User, code, details string) ([]string, error) {
+	secret, err := h.totpSecret(user)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate recovery codes: %w", ErrInternal)
+	}
+	info := clientinfo.FromContext(ctx)
+	event := models.SecurityEvent{
+		UserID:    &user.ID,
+		Type:      models.SecurityTwoFactorEnabled,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Details:   details,
+	}
+	if err := h.Repo.EnableTOTP(ctx, user.ID, step, hashes, &event); err != nil {
+		if errors.Is(err, repo.ErrTwoFactorEnabled) {
+			return nil, fmt.Errorf("two-factor authentication already enabled: %w", ErrConflict)
+		}
+		return nil, fmt.Errorf("enable totp: %v: %w", err, ErrInternal)
+	}
+	return codes, nil
+}
+
+// verifySecondFactor accepts a TOTP code not used before or an unused
+// recovery code.
+func (h *AuthService) verifySecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		ok, err := h.Repo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(recoveryCode))
+		if err != nil {
+			return fmt.Errorf("use recovery code: %v: %w", err, ErrInternal)
+		}
+		if !ok {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+
+	secret, err := h.totpSecret(user)
+	if err != nil {
+		return err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidCode
+	}
+	fresh, err := h.Repo.AdvanceTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return fmt.Errorf("record totp step: %v: %w", err, ErrInternal)
+	}
+	if !fresh {
+		return fmt.Errorf("code already used: %w", ErrInvalidCode)
+	}
+	return nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes formatted as xxxxx-xxxxx and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(raw)
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return jwthelp.Sha256Hex(code)
+}
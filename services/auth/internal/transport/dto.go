@@ -1,11 +1,71 @@
 package transport
 
-import "time"
+import (
+	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/Skotchmaster/online_shop/services/auth/internal/models"
+)
+
+// LoginResult holds either a session or, when a second factor is still
+// needed, only Challenge.
 type LoginResult struct {
+	UserID       uuid.UUID
+	Challenge    *TwoFactorChallenge
 	AccessToken  string
 	RefreshToken string
 	AccessExp    time.Time
 	RefreshExp   time.Time
 	IsAdmin      bool
-}
\ No newline at end of file
+}
+
+type TwoFactorChallenge struct {
+	Token     string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// SetupRequired asks the user to enrol first: the code sent back must
+	// come from an authenticator set up with Secret. When EnrolmentMailed is
+	// set, Secret is empty and the link mailed to the user's verified email
+	// reveals it instead.
+	SetupRequired   bool   `json:"setup_required"`
+	EnrolmentMailed bool   `json:"enrolment_mailed,omitempty"`
+	Secret          string `json:"secret,omitempty"`
+	URI             string `json:"otpauth_uri,omitempty"`
+}
+
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type UserPage struct {
+	Users []models.User `json:"users"`
+	Total int64         `json:"total"`
+}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	// BuiltIn roles cannot be deleted; admin cannot be changed either.
+	BuiltIn bool `json:"built_in"`
+}
+
+// OIDCStart is a sign-in sent to an identity provider: the browser goes to
+// URL and State has to come back with the callback.
+type OIDCStart struct {
+	URL       string
+	State     string
+	ExpiresAt time.Time
+}
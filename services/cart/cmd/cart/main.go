@@ -9,6 +9,11 @@ import (
 	"time"
 	
 	"github.com/Skotchmaster/online_shop/pkg/authclient"
+	"github.com/Skotchmaster/online_shop/pkg/events"
+	"github.com/Skotchmaster/online_shop/pkg/mykafka"
+	"github.com/Skotchmaster/online_shop/pkg/revocation"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/catalogclient"
 	"github.com/Skotchmaster/online_shop/services/cart/internal/config"
 	"github.com/Skotchmaster/online_shop/services/cart/internal/httpserver"
 	"github.com/Skotchmaster/online_shop/services/cart/internal/repo"
@@ -43,7 +48,24 @@ func main() {
 	}
 
 	cartService := &service.CartService{
-		Repo: Repo,
+		Repo:        Repo,
+		Catalog:     catalogclient.NewClient(cfg.CatalogURL),
+		MaxQuantity: cfg.MaxQuantity,
+		GuestSecret: cfg.GuestSecret,
+		GuestTTL:    cfg.GuestTTL,
+		AbandonAfter: cfg.AbandonAfter,
+		PurgeAfter:   cfg.PurgeAfter,
+	}
+
+	if len(cfg.KafkaBrokers) > 0 {
+		producer, err := mykafka.NewProducer(cfg.KafkaBrokers, []string{service.CartAbandonedTopic})
+		if err != nil {
+			log.Fatalf("kafka producer init error: %v", err)
+		}
+		defer producer.Close()
+		cartService.Events = producer
+	} else {
+		log.Println("KAFKA_BROKERS is empty, abandoned cart events are disabled")
 	}
 
 	cartHandler := &httpserver.CartHTTP{
@@ -52,12 +74,39 @@ func main() {
 
 	authClient := authclient.NewClient(cfg.AuthURL)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	verifier := tokens.NewVerifier(tokens.NewJWKSClient(cfg.JWKSURL, cfg.JWKSCacheTTL), cfg.JWTSecret)
+	follower, err := revocation.Attach(jobsCtx, verifier, cfg.KafkaBrokers)
+	if err != nil {
+		log.Fatalf("token revocation: %v", err)
+	}
+	defer follower.Close()
+
 	httpserver.Register(e, &httpserver.Deps{
 		CartHandler: cartHandler,
-		JWTSecret:   cfg.JWTSecret,
+		Verifier:    verifier,
 		AuthClient:  authClient,
+		InternalToken: cfg.InternalToken,
 	})
 
+	cartService.StartGuestCartPurge(jobsCtx, time.Hour)
+	cartService.StartCartLifecycle(jobsCtx, cfg.LifecycleInterval)
+
+	if len(cfg.KafkaBrokers) > 0 {
+		consumer, err := mykafka.NewConsumer(cfg.KafkaBrokers, events.ProductsTopic, cfg.KafkaGroupID)
+		if err != nil {
+			log.Fatalf("kafka consumer init error: %v", err)
+		}
+		defer consumer.Close()
+		go func() {
+			if err := consumer.Run(jobsCtx, cartService.HandleProductMessage); err != nil {
+				log.Printf("product events consumer stopped: %v", err)
+			}
+		}()
+	}
+
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
 		port = "8080"
@@ -0,0 +1,2 @@
+ALTER TABLE cart_items
+  ADD COLUMN IF NOT EXISTS price_snapshot bigint;
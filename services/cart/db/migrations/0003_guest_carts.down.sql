@@ -0,0 +1,4 @@
+DELETE FROM cart_items WHERE user_id IN (SELECT id FROM guest_carts);
+
+DROP INDEX IF EXISTS idx_guest_carts_expires_at;
+DROP TABLE IF EXISTS guest_carts;
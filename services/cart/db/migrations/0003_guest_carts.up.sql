@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS guest_carts (
+  id         uuid PRIMARY KEY,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_guest_carts_expires_at
+  ON guest_carts(expires_at);
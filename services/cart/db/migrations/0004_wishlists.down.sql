@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS ux_wishlist_items_list_product;
+DROP TABLE IF EXISTS wishlist_items;
+
+DROP INDEX IF EXISTS ux_wishlists_user_saved;
+DROP INDEX IF EXISTS ux_wishlists_user_name;
+DROP TABLE IF EXISTS wishlists;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS wishlists (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id     uuid NOT NULL,
+  name        text NOT NULL,
+  kind        text NOT NULL DEFAULT 'wishlist' CHECK (kind IN ('wishlist', 'saved')),
+  share_token text UNIQUE,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+-- The saved list is found by kind, so its fixed name must not collide with a
+-- list the user named the same way.
+CREATE UNIQUE INDEX IF NOT EXISTS ux_wishlists_user_name
+  ON wishlists(user_id, name) WHERE kind = 'wishlist';
+
+-- Every user has at most one "saved for later" list.
+CREATE UNIQUE INDEX IF NOT EXISTS ux_wishlists_user_saved
+  ON wishlists(user_id) WHERE kind = 'saved';
+
+CREATE TABLE IF NOT EXISTS wishlist_items (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  wishlist_id uuid NOT NULL REFERENCES wishlists(id) ON DELETE CASCADE,
+  product_id  uuid NOT NULL,
+  quantity    integer NOT NULL DEFAULT 1 CHECK (quantity > 0),
+  added_at    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_wishlist_items_list_product
+  ON wishlist_items(wishlist_id, product_id);
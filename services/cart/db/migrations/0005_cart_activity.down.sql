@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS cart_items_touch_cart ON cart_items;
+DROP FUNCTION IF EXISTS cart_items_touch_cart();
+DROP TRIGGER IF EXISTS cart_items_set_updated_at ON cart_items;
+DROP FUNCTION IF EXISTS cart_items_set_updated_at();
+
+DROP INDEX IF EXISTS idx_carts_updated_at;
+DROP TABLE IF EXISTS carts;
+
+ALTER TABLE cart_items
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS created_at;
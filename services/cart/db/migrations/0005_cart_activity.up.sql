@@ -0,0 +1,52 @@
+ALTER TABLE cart_items
+  ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();
+
+-- One row per cart owner (user or guest cart), kept up to date by triggers on
+-- cart_items so every write path counts as activity.
+CREATE TABLE IF NOT EXISTS carts (
+  owner_id              uuid PRIMARY KEY,
+  created_at            timestamptz NOT NULL DEFAULT now(),
+  updated_at            timestamptz NOT NULL DEFAULT now(),
+  abandoned_notified_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_carts_updated_at
+  ON carts(updated_at);
+
+INSERT INTO carts (owner_id)
+SELECT DISTINCT user_id FROM cart_items
+ON CONFLICT (owner_id) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION cart_items_set_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at := now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER cart_items_set_updated_at
+  BEFORE UPDATE ON cart_items
+  FOR EACH ROW EXECUTE FUNCTION cart_items_set_updated_at();
+
+CREATE OR REPLACE FUNCTION cart_items_touch_cart() RETURNS trigger AS $$
+DECLARE
+  owner uuid;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    owner := OLD.user_id;
+  ELSE
+    owner := NEW.user_id;
+  END IF;
+
+  INSERT INTO carts (owner_id) VALUES (owner)
+  ON CONFLICT (owner_id) DO UPDATE SET
+    updated_at            = now(),
+    abandoned_notified_at = NULL;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER cart_items_touch_cart
+  AFTER INSERT OR UPDATE OR DELETE ON cart_items
+  FOR EACH ROW EXECUTE FUNCTION cart_items_touch_cart();
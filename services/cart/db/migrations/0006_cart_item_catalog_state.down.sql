@@ -0,0 +1,26 @@
+CREATE OR REPLACE FUNCTION cart_items_touch_cart() RETURNS trigger AS $$
+DECLARE
+  owner uuid;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    owner := OLD.user_id;
+  ELSE
+    owner := NEW.user_id;
+  END IF;
+
+  INSERT INTO carts (owner_id) VALUES (owner)
+  ON CONFLICT (owner_id) DO UPDATE SET
+    updated_at            = now(),
+    abandoned_notified_at = NULL;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS cart_items_touch_cart ON cart_items;
+CREATE TRIGGER cart_items_touch_cart
+  AFTER INSERT OR UPDATE OR DELETE ON cart_items
+  FOR EACH ROW EXECUTE FUNCTION cart_items_touch_cart();
+
+ALTER TABLE cart_items
+  DROP COLUMN IF EXISTS unavailable,
+  DROP COLUMN IF EXISTS catalog_price;
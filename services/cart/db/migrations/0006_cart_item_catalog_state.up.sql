@@ -0,0 +1,34 @@
+ALTER TABLE cart_items
+  ADD COLUMN IF NOT EXISTS catalog_price bigint,
+  ADD COLUMN IF NOT EXISTS unavailable boolean NOT NULL DEFAULT false;
+
+-- Catalog updates of a line are not customer activity: only changes to the
+-- line itself touch the cart.
+DROP TRIGGER IF EXISTS cart_items_touch_cart ON cart_items;
+CREATE TRIGGER cart_items_touch_cart
+  AFTER INSERT OR DELETE OR UPDATE OF user_id, product_id, quantity, price_snapshot ON cart_items
+  FOR EACH ROW EXECUTE FUNCTION cart_items_touch_cart();
+
+-- Neither are lines removed because their product was deleted: catalog sync
+-- sets cart.catalog_sync for its transaction and the cart stays untouched.
+CREATE OR REPLACE FUNCTION cart_items_touch_cart() RETURNS trigger AS $$
+DECLARE
+  owner uuid;
+BEGIN
+  IF current_setting('cart.catalog_sync', true) = 'on' THEN
+    RETURN NULL;
+  END IF;
+
+  IF TG_OP = 'DELETE' THEN
+    owner := OLD.user_id;
+  ELSE
+    owner := NEW.user_id;
+  END IF;
+
+  INSERT INTO carts (owner_id) VALUES (owner)
+  ON CONFLICT (owner_id) DO UPDATE SET
+    updated_at            = now(),
+    abandoned_notified_at = NULL;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
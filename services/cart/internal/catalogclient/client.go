@@ -0,0 +1,101 @@
+package catalogclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxBatch is the largest number of ids the catalog accepts in one call.
+const MaxBatch = 100
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(catalogServiceURL string) *Client {
+	return &Client{
+		baseURL: catalogServiceURL,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+// Product is the part of a catalog product the cart relies on. Price is the
+// price the customer pays right now, including scheduled sales.
+type Product struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Price int64     `json:"current_price"`
+	Count uint      `json:"count"`
+}
+
+type batchResponse struct {
+	Data []Product `json:"data"`
+}
+
+// GetProducts loads the visible products among ids, keyed by id. Products that
+// were deleted or unpublished are missing from the map.
+func (c *Client) GetProducts(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Product, error) {
+	products := make(map[uuid.UUID]Product, len(ids))
+
+	for start := 0; start < len(ids); start += MaxBatch {
+		chunk := ids[start:min(start+MaxBatch, len(ids))]
+		if err := c.getBatch(ctx, chunk, products); err != nil {
+			return nil, err
+		}
+	}
+	return products, nil
+}
+
+func (c *Client) getBatch(ctx context.Context, ids []uuid.UUID, into map[uuid.UUID]Product) error {
+	batchURL, err := url.JoinPath(c.baseURL, "catalog", "products", "batch")
+	if err != nil {
+		return fmt.Errorf("build batch url: %w", err)
+	}
+
+	raw := make([]string, len(ids))
+	for i, id := range ids {
+		raw[i] = id.String()
+	}
+	query := url.Values{}
+	query.Set("ids", strings.Join(raw, ","))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, batchURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("products batch failed with status: %d", resp.StatusCode)
+	}
+
+	var result batchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	for _, p := range result.Data {
+		into[p.ID] = p
+	}
+	return nil
+}
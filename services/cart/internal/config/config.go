@@ -6,15 +6,44 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
+	pkgconfig "github.com/Skotchmaster/online_shop/pkg/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 type Config struct {
 	AuthURL       string
+	CatalogURL    string
+	// JWTSecret still accepts HS256 access tokens; empty rejects them.
 	JWTSecret     []byte
+	JWKSURL       string
+	JWKSCacheTTL  time.Duration
+	MaxQuantity   uint
+	InternalToken string
+	GuestSecret   []byte
+	GuestTTL      time.Duration
+
+	KafkaBrokers      []string
+	KafkaGroupID      string
+	AbandonAfter      time.Duration
+	PurgeAfter        time.Duration
+	LifecycleInterval time.Duration
+}
+
+// duration reads a non-negative duration from env, falling back to def.
+func duration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Fatalf("invalid %s %q", name, v)
+	}
+	return d
 }
 
 func must(v string, name string) string {
@@ -27,7 +56,36 @@ func must(v string, name string) string {
 func Load() *Config {
 	cfg := &Config{
 		AuthURL:    must(os.Getenv("AUTH_URL"), "AUTH_URL"),
-		JWTSecret:  []byte(must(os.Getenv("JWT_SECRET"), "JWT_SECRET")),
+		CatalogURL: must(os.Getenv("CATALOG_URL"), "CATALOG_URL"),
+		JWTSecret:  []byte(os.Getenv("JWT_SECRET")),
+		JWKSCacheTTL: duration("JWKS_CACHE_TTL", 5*time.Minute),
+		MaxQuantity: 99,
+		InternalToken: os.Getenv("INTERNAL_API_TOKEN"),
+		GuestSecret: []byte(must(os.Getenv("GUEST_CART_SECRET"), "GUEST_CART_SECRET")),
+		GuestTTL:    30 * 24 * time.Hour,
+		KafkaBrokers: pkgconfig.CSV(os.Getenv("KAFKA_BROKERS")),
+		KafkaGroupID: pkgconfig.EnvDefault("KAFKA_GROUP_ID", "cart"),
+		AbandonAfter: duration("CART_ABANDON_AFTER", 24*time.Hour),
+		PurgeAfter:   duration("CART_PURGE_AFTER", 90*24*time.Hour),
+		LifecycleInterval: duration("CART_LIFECYCLE_INTERVAL", 10*time.Minute),
+	}
+	cfg.JWKSURL = pkgconfig.EnvDefault("JWKS_URL", pkgconfig.JWKSURL(cfg.AuthURL))
+	if cfg.LifecycleInterval == 0 {
+		log.Fatalf("CART_LIFECYCLE_INTERVAL must be positive")
+	}
+	if v := os.Getenv("GUEST_CART_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			log.Fatalf("invalid GUEST_CART_TTL %q", v)
+		}
+		cfg.GuestTTL = ttl
+	}
+	if v := os.Getenv("CART_MAX_ITEM_QUANTITY"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || n == 0 {
+			log.Fatalf("invalid CART_MAX_ITEM_QUANTITY %q", v)
+		}
+		cfg.MaxQuantity = uint(n)
 	}
 	return cfg
 }
@@ -50,8 +108,9 @@ func InitDB(ctx context.Context) (*gorm.DB, error) {
 	dsn := os.Getenv("DATABASE_URL")
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		PrepareStmt: true,
-		NowFunc:     func() time.Time { return time.Now().UTC() },
+		PrepareStmt:    true,
+		TranslateError: true,
+		NowFunc:        func() time.Time { return time.Now().UTC() },
 	})
 	if err != nil {
 		return nil, fmt.Errorf("подключение к БД: %w", err)
@@ -0,0 +1,40 @@
+package guest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CookieName is shared with the auth service, which forwards the cookie when
+// a guest logs in.
+const CookieName = "guestCart"
+
+var ErrInvalidToken = errors.New("invalid guest token")
+
+// Sign returns "<id>.<mac>", binding the guest cart id to this service.
+func Sign(secret []byte, id uuid.UUID) string {
+	return id.String() + "." + mac(secret, id.String())
+}
+
+func Parse(secret []byte, token string) (uuid.UUID, error) {
+	raw, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(mac(secret, raw))) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return id, nil
+}
+
+func mac(secret []byte, value string) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,50 @@
+package guest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("guest-secret")
+	id := uuid.New()
+	token := Sign(secret, id)
+	raw, sig, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", secret: secret, token: token},
+		{name: "other secret", secret: []byte("other"), token: token, wantErr: true},
+		{name: "swapped id", secret: secret, token: uuid.NewString() + "." + sig, wantErr: true},
+		{name: "tampered mac", secret: secret, token: raw + "." + strings.ToUpper(sig), wantErr: true},
+		{name: "no mac", secret: secret, token: raw, wantErr: true},
+		{name: "empty", secret: secret, token: "", wantErr: true},
+		{name: "signed garbage", secret: secret, token: "cart." + mac(secret, "cart"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(tt.secret, tt.token)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidToken)
+				assert.Equal(t, uuid.Nil, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, id, got)
+		})
+	}
+}
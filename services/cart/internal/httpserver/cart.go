@@ -35,30 +35,34 @@ func (h *CartHTTP) GetCart(c echo.Context) error {
 	ctx := c.Request().Context()
 	l := logging.FromContext(ctx).With("handler", "get.cart")
 	
-	userID, err := h.GetID(c)
-	if err != nil{
-		l.Error("get_cart_error", "status", 401, "reason", "unauthorized", "error", err)
-		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	userID, err := h.cartOwner(c, false)
+	if err != nil {
+		l.Error("get_cart_error", "status", 500, "reason", "cannot resolve cart", "error", err)
+		return c.JSON(http.StatusInternalServerError, "internal error")
 	}
 
-	items, err := h.Svc.GetCart(ctx, userID)
+	cart, err := h.Svc.GetCart(ctx, userID)
 	if err != nil {
+		if errors.Is(err, service.ErrUnavailable) {
+			l.Error("get_cart_error", "status", 503, "reason", "catalog unavailable", "error", err)
+			return c.JSON(http.StatusServiceUnavailable, "catalog unavailable")
+		}
 		l.Error("get_cart_error", "status", 500, "reason", "internal server error", "error", err)
 		return c.JSON(http.StatusInternalServerError, "internal server error")
 	}
 
 	l.Info("cart successfully got")
-	return c.JSON(http.StatusOK, items)
+	return c.JSON(http.StatusOK, cart)
 }
 
 func (h *CartHTTP) AddToCart(c echo.Context) error {
 	ctx := c.Request().Context()
 	l := logging.FromContext(ctx).With("handler", "add.cart")
 
-	userID, err := h.GetID(c)
+	userID, err := h.cartOwner(c, true)
 	if err != nil {
-		l.Error("add_cart_error", "status", 401, "reason", "unauthorized", "error", err)
-		return c.JSON(http.StatusUnauthorized, "unauthorized")
+		l.Error("add_cart_error", "status", 500, "reason", "cannot resolve cart", "error", err)
+		return c.JSON(http.StatusInternalServerError, "internal error")
 	}
 
 	var req struct {
@@ -81,6 +85,18 @@ func (h *CartHTTP) AddToCart(c echo.Context) error {
 			l.Warn("add_to_cart_error", "status", 400, "reason", "invalid body", "error", err)
 			return c.JSON(http.StatusBadRequest, "invalid body")
 		}
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("add_to_cart_error", "status", 404, "reason", "product not found", "error", err)
+			return c.JSON(http.StatusNotFound, "product not found")
+		}
+		if errors.Is(err, service.ErrInsufficientStock) {
+			l.Warn("add_to_cart_error", "status", 409, "reason", "insufficient stock", "error", err)
+			return c.JSON(http.StatusConflict, "insufficient stock")
+		}
+		if errors.Is(err, service.ErrUnavailable) {
+			l.Error("add_to_cart_error", "status", 503, "reason", "catalog unavailable", "error", err)
+			return c.JSON(http.StatusServiceUnavailable, "catalog unavailable")
+		}
 		l.Error("add_to_cart_error", "status", 500, "reason", "internal error", "error", err)
 		return c.JSON(http.StatusInternalServerError, "internal error")
 	}
@@ -93,10 +109,10 @@ func (h *CartHTTP) DeleteOneFromCart(c echo.Context) error {
     ctx := c.Request().Context()
     l := logging.FromContext(ctx).With("handler", "delete.one.from.cart")
 
-    userID, err := h.GetID(c)
+    userID, err := h.cartOwner(c, false)
     if err != nil {
-        l.Error("delete_one_from_cart_error", "status", 401, "reason", "unauthorized", "error", err)
-        return c.JSON(http.StatusUnauthorized, "unauthorized")
+        l.Error("delete_one_from_cart_error", "status", 500, "reason", "cannot resolve cart", "error", err)
+        return c.JSON(http.StatusInternalServerError, "internal error")
     }
 
     var req struct {
@@ -141,10 +157,10 @@ func (h *CartHTTP) DeleteAllFromCart(c echo.Context) error {
 	ctx := c.Request().Context()
 	l := logging.FromContext(ctx).With("handler", "delete.all.from.cart")
 
-	userID, err := h.GetID(c)
+	userID, err := h.cartOwner(c, false)
 	if err != nil {
-		l.Error("delete_all_from_cart_error", "status", 401, "reason", "unauthorized", "error", err)
-		return c.JSON(http.StatusUnauthorized, "unauthorized")
+		l.Error("delete_all_from_cart_error", "status", 500, "reason", "cannot resolve cart", "error", err)
+		return c.JSON(http.StatusInternalServerError, "internal error")
 	}
 
 	if err := h.Svc.DeleteAllFromCart(ctx, userID); err != nil {
@@ -0,0 +1,71 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	jwthelp "github.com/Skotchmaster/online_shop/pkg/jwt"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/guest"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// cartOwner returns the id owning the cart of this request: the user id, or
+// the guest cart id from the signed guest cookie. Without a live guest cart it
+// returns uuid.Nil, or starts a new guest cart when create is set.
+func (h *CartHTTP) cartOwner(c echo.Context, create bool) (uuid.UUID, error) {
+	if userID, err := h.GetID(c); err == nil {
+		return userID, nil
+	}
+	ctx := c.Request().Context()
+
+	if cookie, err := c.Cookie(guest.CookieName); err == nil && cookie.Value != "" {
+		sess, err := h.Svc.ResolveGuest(ctx, cookie.Value)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if sess != nil {
+			c.SetCookie(jwthelp.CreateCookie(guest.CookieName, sess.Token, "/", sess.ExpiresAt))
+			return sess.ID, nil
+		}
+		c.SetCookie(jwthelp.DeleteCookie(guest.CookieName, "/"))
+	}
+
+	if !create {
+		return uuid.Nil, nil
+	}
+	sess, err := h.Svc.NewGuestCart(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	c.SetCookie(jwthelp.CreateCookie(guest.CookieName, sess.Token, "/", sess.ExpiresAt))
+	return sess.ID, nil
+}
+
+func (h *CartHTTP) MergeGuestCart(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "merge.guest.cart")
+
+	var req struct {
+		UserID     uuid.UUID `json:"user_id"`
+		GuestToken string    `json:"guest_token"`
+	}
+	if err := c.Bind(&req); err != nil {
+		l.Warn("merge_guest_cart_error", "status", 400, "reason", "invalid body", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid body")
+	}
+
+	if err := h.Svc.MergeGuestCart(ctx, req.UserID, req.GuestToken); err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("merge_guest_cart_error", "status", 400, "reason", "invalid guest token", "error", err)
+			return c.JSON(http.StatusBadRequest, "invalid guest token")
+		}
+		l.Error("merge_guest_cart_error", "status", 500, "reason", "internal error", "error", err)
+		return c.JSON(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("guest cart merged successfully", "user_id", req.UserID)
+	return c.NoContent(http.StatusNoContent)
+}
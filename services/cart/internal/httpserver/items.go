@@ -0,0 +1,137 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/service"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/transport"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// itemErrorStatus maps service errors of item changes to a status and a
+// client-facing reason.
+func itemErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusBadRequest, "invalid body"
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound, "product not found"
+	case errors.Is(err, service.ErrInsufficientStock):
+		return http.StatusConflict, "insufficient stock"
+	case errors.Is(err, service.ErrUnavailable):
+		return http.StatusServiceUnavailable, "catalog unavailable"
+	default:
+		return http.StatusInternalServerError, "internal error"
+	}
+}
+
+func (h *CartHTTP) SetQuantity(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "set.quantity")
+
+	userID, err := h.cartOwner(c, true)
+	if err != nil {
+		l.Error("set_quantity_error", "status", 500, "reason", "cannot resolve cart", "error", err)
+		return c.JSON(http.StatusInternalServerError, "internal error")
+	}
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		l.Warn("set_quantity_error", "status", 400, "reason", "invalid product id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid product id")
+	}
+
+	var req transport.SetQuantityRequest
+	if err := c.Bind(&req); err != nil || req.Quantity == nil {
+		l.Warn("set_quantity_error", "status", 400, "reason", "invalid body", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid body")
+	}
+
+	item, err := h.Svc.SetQuantity(ctx, userID, productID, *req.Quantity)
+	if err != nil {
+		status, reason := itemErrorStatus(err)
+		if status == http.StatusInternalServerError || status == http.StatusServiceUnavailable {
+			l.Error("set_quantity_error", "status", status, "reason", reason, "error", err)
+		} else {
+			l.Warn("set_quantity_error", "status", status, "reason", reason, "error", err)
+		}
+		return c.JSON(status, reason)
+	}
+
+	l.Info("quantity set successfully")
+	if item == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusOK, item)
+}
+
+func (h *CartHTTP) RemoveItem(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "remove.item")
+
+	userID, err := h.cartOwner(c, false)
+	if err != nil {
+		l.Error("remove_item_error", "status", 500, "reason", "cannot resolve cart", "error", err)
+		return c.JSON(http.StatusInternalServerError, "internal error")
+	}
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		l.Warn("remove_item_error", "status", 400, "reason", "invalid product id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid product id")
+	}
+
+	if err := h.Svc.RemoveItem(ctx, userID, productID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("remove_item_error", "status", 404, "reason", "item not found", "error", err)
+			return c.JSON(http.StatusNotFound, "item not found")
+		}
+		l.Error("remove_item_error", "status", 500, "reason", "internal error", "error", err)
+		return c.JSON(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("item removed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *CartHTTP) BatchUpdate(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "batch.update")
+
+	userID, err := h.cartOwner(c, true)
+	if err != nil {
+		l.Error("batch_update_error", "status", 500, "reason", "cannot resolve cart", "error", err)
+		return c.JSON(http.StatusInternalServerError, "internal error")
+	}
+
+	var req transport.BatchCartRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("batch_update_error", "status", 400, "reason", "invalid body", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid body")
+	}
+
+	cart, err := h.Svc.ApplyBatch(ctx, userID, req.Items)
+	if err != nil {
+		status, reason := itemErrorStatus(err)
+		if status == http.StatusInternalServerError || status == http.StatusServiceUnavailable {
+			l.Error("batch_update_error", "status", status, "reason", reason, "error", err)
+		} else {
+			l.Warn("batch_update_error", "status", status, "reason", reason, "error", err)
+		}
+
+		var itemErr *service.ItemError
+		if errors.As(err, &itemErr) {
+			return c.JSON(status, map[string]any{
+				"message":    reason,
+				"product_id": itemErr.ProductID,
+			})
+		}
+		return c.JSON(status, reason)
+	}
+
+	l.Info("cart batch applied successfully")
+	return c.JSON(http.StatusOK, cart)
+}
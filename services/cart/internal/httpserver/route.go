@@ -5,26 +5,50 @@ import (
 
 	"github.com/Skotchmaster/online_shop/pkg/authclient"
 	middleware "github.com/Skotchmaster/online_shop/pkg/middleware/auth"
+	"github.com/Skotchmaster/online_shop/pkg/middleware/servicetoken"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
 	"github.com/labstack/echo/v4"
 )
 
 type Deps struct {
 	CartHandler *CartHTTP
-	JWTSecret  []byte
+	Verifier    *tokens.Verifier
 	AuthClient  *authclient.Client
+	InternalToken string
 }
 
 func Register(e *echo.Echo, d *Deps) {
 	e.GET("/health/live", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
 	e.GET("/health/ready", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
 
-	authMW := middleware.NewAutoRefreshMiddleware(d.JWTSecret, d.AuthClient)
+	authMW := middleware.NewAutoRefreshMiddleware(d.Verifier, d.AuthClient)
 
 	cart := e.Group("/cart")
-	cart.Use(authMW.RequireAuth)
+	cart.Use(authMW.OptionalAuth)
 
 	cart.GET("", d.CartHandler.GetCart)
 	cart.POST("", d.CartHandler.AddToCart)
 	cart.DELETE("", d.CartHandler.DeleteAllFromCart)
 	cart.DELETE("/items", d.CartHandler.DeleteOneFromCart)
+	cart.POST("/items/batch", d.CartHandler.BatchUpdate)
+	cart.PUT("/items/:product_id", d.CartHandler.SetQuantity)
+	cart.DELETE("/items/:product_id", d.CartHandler.RemoveItem)
+
+	cart.GET("/saved", d.CartHandler.GetSavedList)
+	cart.POST("/items/:product_id/save-for-later", d.CartHandler.SaveForLater)
+
+	e.GET("/wishlists/shared/:token", d.CartHandler.GetSharedWishlist)
+
+	wishlists := e.Group("/wishlists", authMW.RequireAuth)
+	wishlists.GET("", d.CartHandler.ListWishlists)
+	wishlists.POST("", d.CartHandler.CreateWishlist)
+	wishlists.GET("/:id", d.CartHandler.GetWishlist)
+	wishlists.PATCH("/:id", d.CartHandler.PatchWishlist)
+	wishlists.DELETE("/:id", d.CartHandler.DeleteWishlist)
+	wishlists.POST("/:id/items", d.CartHandler.AddWishlistItem)
+	wishlists.DELETE("/:id/items/:product_id", d.CartHandler.RemoveWishlistItem)
+	wishlists.POST("/:id/items/:product_id/move-to-cart", d.CartHandler.MoveToCart)
+
+	internal := e.Group("/internal/cart", servicetoken.Require(d.InternalToken))
+	internal.POST("/merge", d.CartHandler.MergeGuestCart)
 }
\ No newline at end of file
@@ -0,0 +1,295 @@
+package httpserver
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/service"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/transport"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// wishlistError logs a failed wishlist request and writes the matching status.
+func wishlistError(c echo.Context, l *slog.Logger, event string, err error) error {
+	status, reason := http.StatusInternalServerError, "internal error"
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		status, reason = http.StatusBadRequest, "invalid body"
+	case errors.Is(err, service.ErrNotFound):
+		status, reason = http.StatusNotFound, "not found"
+	case errors.Is(err, service.ErrConflict):
+		status, reason = http.StatusConflict, "list already exists"
+	case errors.Is(err, service.ErrInsufficientStock):
+		status, reason = http.StatusConflict, "insufficient stock"
+	case errors.Is(err, service.ErrUnavailable):
+		status, reason = http.StatusServiceUnavailable, "catalog unavailable"
+	}
+
+	if status >= http.StatusInternalServerError {
+		l.Error(event, "status", status, "reason", reason, "error", err)
+	} else {
+		l.Warn(event, "status", status, "reason", reason, "error", err)
+	}
+	return c.JSON(status, reason)
+}
+
+func (h *CartHTTP) ListWishlists(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "list.wishlists")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Error("list_wishlists_error", "status", 401, "reason", "unauthorized", "error", err)
+		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	}
+
+	lists, err := h.Svc.ListWishlists(ctx, userID)
+	if err != nil {
+		return wishlistError(c, l, "list_wishlists_error", err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": lists})
+}
+
+func (h *CartHTTP) CreateWishlist(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "create.wishlist")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Error("create_wishlist_error", "status", 401, "reason", "unauthorized", "error", err)
+		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var req transport.CreateWishlistRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("create_wishlist_error", "status", 400, "reason", "invalid body", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid body")
+	}
+
+	list, err := h.Svc.CreateWishlist(ctx, userID, req)
+	if err != nil {
+		return wishlistError(c, l, "create_wishlist_error", err)
+	}
+
+	l.Info("wishlist created successfully", "wishlist_id", list.ID)
+	return c.JSON(http.StatusCreated, list)
+}
+
+func (h *CartHTTP) GetWishlist(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "get.wishlist")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Error("get_wishlist_error", "status", 401, "reason", "unauthorized", "error", err)
+		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("get_wishlist_error", "status", 400, "reason", "invalid list id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid list id")
+	}
+
+	list, err := h.Svc.GetWishlist(ctx, userID, id)
+	if err != nil {
+		return wishlistError(c, l, "get_wishlist_error", err)
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+func (h *CartHTTP) PatchWishlist(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "patch.wishlist")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Error("patch_wishlist_error", "status", 401, "reason", "unauthorized", "error", err)
+		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("patch_wishlist_error", "status", 400, "reason", "invalid list id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid list id")
+	}
+
+	var req transport.PatchWishlistRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("patch_wishlist_error", "status", 400, "reason", "invalid body", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid body")
+	}
+
+	list, err := h.Svc.PatchWishlist(ctx, userID, id, req)
+	if err != nil {
+		return wishlistError(c, l, "patch_wishlist_error", err)
+	}
+
+	l.Info("wishlist updated successfully", "wishlist_id", list.ID)
+	return c.JSON(http.StatusOK, list)
+}
+
+func (h *CartHTTP) DeleteWishlist(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "delete.wishlist")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Error("delete_wishlist_error", "status", 401, "reason", "unauthorized", "error", err)
+		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("delete_wishlist_error", "status", 400, "reason", "invalid list id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid list id")
+	}
+
+	if err := h.Svc.DeleteWishlist(ctx, userID, id); err != nil {
+		return wishlistError(c, l, "delete_wishlist_error", err)
+	}
+
+	l.Info("wishlist deleted successfully", "wishlist_id", id)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *CartHTTP) AddWishlistItem(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "add.wishlist.item")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Error("add_wishlist_item_error", "status", 401, "reason", "unauthorized", "error", err)
+		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("add_wishlist_item_error", "status", 400, "reason", "invalid list id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid list id")
+	}
+
+	var req transport.WishlistItemRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("add_wishlist_item_error", "status", 400, "reason", "invalid body", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid body")
+	}
+
+	item, err := h.Svc.AddWishlistItem(ctx, userID, id, req)
+	if err != nil {
+		return wishlistError(c, l, "add_wishlist_item_error", err)
+	}
+
+	l.Info("wishlist item added successfully", "wishlist_id", id)
+	return c.JSON(http.StatusCreated, item)
+}
+
+func (h *CartHTTP) RemoveWishlistItem(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "remove.wishlist.item")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Error("remove_wishlist_item_error", "status", 401, "reason", "unauthorized", "error", err)
+		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("remove_wishlist_item_error", "status", 400, "reason", "invalid list id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid list id")
+	}
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		l.Warn("remove_wishlist_item_error", "status", 400, "reason", "invalid product id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid product id")
+	}
+
+	if err := h.Svc.RemoveWishlistItem(ctx, userID, id, productID); err != nil {
+		return wishlistError(c, l, "remove_wishlist_item_error", err)
+	}
+
+	l.Info("wishlist item removed successfully", "wishlist_id", id)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *CartHTTP) MoveToCart(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "move.to.cart")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Error("move_to_cart_error", "status", 401, "reason", "unauthorized", "error", err)
+		return c.JSON(http.StatusUnauthorized, "unauthorized")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("move_to_cart_error", "status", 400, "reason", "invalid list id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid list id")
+	}
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		l.Warn("move_to_cart_error", "status", 400, "reason", "invalid product id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid product id")
+	}
+
+	item, err := h.Svc.MoveToCart(ctx, userID, id, productID)
+	if err != nil {
+		return wishlistError(c, l, "move_to_cart_error", err)
+	}
+
+	l.Info("wishlist item moved to cart successfully", "wishlist_id", id)
+	return c.JSON(http.StatusOK, item)
+}
+
+func (h *CartHTTP) SaveForLater(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "save.for.later")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Warn("save_for_later_error", "status", 401, "reason", "login required", "error", err)
+		return c.JSON(http.StatusUnauthorized, "login required")
+	}
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		l.Warn("save_for_later_error", "status", 400, "reason", "invalid product id", "error", err)
+		return c.JSON(http.StatusBadRequest, "invalid product id")
+	}
+
+	item, err := h.Svc.SaveForLater(ctx, userID, productID)
+	if err != nil {
+		return wishlistError(c, l, "save_for_later_error", err)
+	}
+
+	l.Info("item saved for later successfully")
+	return c.JSON(http.StatusOK, item)
+}
+
+func (h *CartHTTP) GetSavedList(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "get.saved.list")
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Warn("get_saved_list_error", "status", 401, "reason", "login required", "error", err)
+		return c.JSON(http.StatusUnauthorized, "login required")
+	}
+
+	list, err := h.Svc.GetSavedList(ctx, userID)
+	if err != nil {
+		return wishlistError(c, l, "get_saved_list_error", err)
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+func (h *CartHTTP) GetSharedWishlist(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "get.shared.wishlist")
+
+	list, err := h.Svc.GetSharedWishlist(ctx, c.Param("token"))
+	if err != nil {
+		return wishlistError(c, l, "get_shared_wishlist_error", err)
+	}
+	// The link grants read access only; do not echo the token back.
+	list.ShareToken = nil
+	return c.JSON(http.StatusOK, list)
+}
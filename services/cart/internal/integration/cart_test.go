@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/repo"
+)
+
+func newTestRepo(t *testing.T) *repo.GormRepo {
+	t.Helper()
+
+	dsn := os.Getenv("CART_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("CART_TEST_DATABASE_URL is required for tests")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.CartItem{}, &models.Cart{}, &models.Wishlist{}, &models.WishlistItem{}))
+	// AutoMigrate knows nothing about the partial indexes from the migrations.
+	require.NoError(t, db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS ux_wishlists_user_name ON wishlists(user_id, name) WHERE kind = 'wishlist'").Error)
+	require.NoError(t, db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS ux_wishlists_user_saved ON wishlists(user_id) WHERE kind = 'saved'").Error)
+
+	t.Cleanup(func() {
+		db.Exec("TRUNCATE TABLE wishlist_items, wishlists, carts, cart_items RESTART IDENTITY CASCADE")
+	})
+	return &repo.GormRepo{DB: db}
+}
+
+// installActivityTriggers runs the migrations that keep carts up to date,
+// which AutoMigrate knows nothing about.
+func installActivityTriggers(t *testing.T, rp *repo.GormRepo) {
+	t.Helper()
+
+	require.NoError(t, rp.DB.Exec("DROP TRIGGER IF EXISTS cart_items_set_updated_at ON cart_items").Error)
+	require.NoError(t, rp.DB.Exec("DROP TRIGGER IF EXISTS cart_items_touch_cart ON cart_items").Error)
+	for _, name := range []string{"0005_cart_activity.up.sql", "0006_cart_item_catalog_state.up.sql"} {
+		migration, err := os.ReadFile(filepath.Join("..", "..", "db", "migrations", name))
+		require.NoError(t, err)
+		require.NoError(t, rp.DB.Exec(string(migration)).Error, name)
+	}
+}
+
+func TestRepo_AddToCartConcurrentLimit(t *testing.T) {
+	rp := newTestRepo(t)
+	ctx := context.Background()
+	userID, productID := uuid.New(), uuid.New()
+	price := int64(100)
+
+	const (
+		limit   = 5
+		callers = 20
+	)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		added    int
+		rejected int
+		failures []error
+	)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			item := models.CartItem{UserID: userID, ProductID: productID, Quantity: 1, PriceSnapshot: &price}
+			err := rp.AddToCart(ctx, &item, limit)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				added++
+			case errors.Is(err, repo.ErrQuantityLimit):
+				rejected++
+			default:
+				failures = append(failures, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Empty(t, failures, "concurrent adds must not collide on the new line")
+	assert.Equal(t, limit, added)
+	assert.Equal(t, callers-limit, rejected)
+
+	items, err := rp.GetCart(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, uint(limit), items[0].Quantity)
+}
+
+func TestRepo_AddToCartReturnsLine(t *testing.T) {
+	rp := newTestRepo(t)
+	ctx := context.Background()
+	userID, productID := uuid.New(), uuid.New()
+	first, second := int64(100), int64(90)
+
+	item := models.CartItem{UserID: userID, ProductID: productID, Quantity: 2, PriceSnapshot: &first}
+	require.NoError(t, rp.AddToCart(ctx, &item, 10))
+	id := item.ID
+
+	item = models.CartItem{UserID: userID, ProductID: productID, Quantity: 3, PriceSnapshot: &second}
+	require.NoError(t, rp.AddToCart(ctx, &item, 10))
+	assert.Equal(t, id, item.ID, "the existing line is incremented")
+	assert.Equal(t, uint(5), item.Quantity)
+	assert.Equal(t, second, *item.PriceSnapshot)
+
+	item = models.CartItem{UserID: userID, ProductID: productID, Quantity: 6, PriceSnapshot: &second}
+	require.ErrorIs(t, rp.AddToCart(ctx, &item, 10), repo.ErrQuantityLimit)
+
+	items, err := rp.GetCart(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, uint(5), items[0].Quantity, "a rejected add leaves the line alone")
+}
+
+func TestRepo_SaveForLaterNameTaken(t *testing.T) {
+	rp := newTestRepo(t)
+	ctx := context.Background()
+	userID, productID := uuid.New(), uuid.New()
+
+	own := models.Wishlist{UserID: userID, Name: "Saved for later"}
+	require.NoError(t, rp.DB.Create(&own).Error)
+
+	item := models.CartItem{UserID: userID, ProductID: productID, Quantity: 2}
+	require.NoError(t, rp.AddToCart(ctx, &item, 10))
+
+	saved, err := rp.SaveForLater(ctx, userID, productID)
+	require.NoError(t, err, "a regular list may share the saved list name")
+	assert.NotEqual(t, own.ID, saved.WishlistID)
+	assert.Equal(t, uint(2), saved.Quantity)
+
+	require.NoError(t, rp.AddToCart(ctx, &item, 10))
+	again, err := rp.SaveForLater(ctx, userID, productID)
+	require.NoError(t, err)
+	assert.Equal(t, saved.WishlistID, again.WishlistID, "the saved list is reused")
+	assert.Equal(t, uint(4), again.Quantity)
+}
+
+func TestRepo_RemoveProductLinesIsNotActivity(t *testing.T) {
+	rp := newTestRepo(t)
+	installActivityTriggers(t, rp)
+	ctx := context.Background()
+	userID, deleted, kept := uuid.New(), uuid.New(), uuid.New()
+
+	for _, productID := range []uuid.UUID{deleted, kept} {
+		item := models.CartItem{UserID: userID, ProductID: productID, Quantity: 1}
+		require.NoError(t, rp.AddToCart(ctx, &item, 10))
+	}
+	idleSince := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Microsecond)
+	require.NoError(t, rp.DB.Model(&models.Cart{}).Where("owner_id = ?", userID).
+		Updates(map[string]any{"updated_at": idleSince, "abandoned_notified_at": idleSince}).Error)
+
+	removed, err := rp.RemoveProductLines(ctx, deleted)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, removed)
+
+	var cart models.Cart
+	require.NoError(t, rp.DB.Where("owner_id = ?", userID).First(&cart).Error)
+	assert.True(t, idleSince.Equal(cart.UpdatedAt), "a deleted product does not reset the idle timer")
+	require.NotNil(t, cart.AbandonedNotifiedAt, "nor the abandonment report")
+
+	require.NoError(t, rp.RemoveItem(ctx, userID, kept))
+	require.NoError(t, rp.DB.Where("owner_id = ?", userID).First(&cart).Error)
+	assert.True(t, cart.UpdatedAt.After(idleSince), "the customer removing a line is activity")
+	assert.Nil(t, cart.AbandonedNotifiedAt)
+}
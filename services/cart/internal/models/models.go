@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -10,6 +12,23 @@ type CartItem struct {
 	UserID    uuid.UUID `gorm:"uniqueIndex:idx_user_product;not null"  json:"user_id"`
 	ProductID uuid.UUID `gorm:"uniqueIndex:idx_user_product;not null"   json:"product_id"`
 	Quantity  uint      `gorm:"default:1;check:quantity>0"              json:"quantity"`
+	// PriceSnapshot is the price the customer saw when the line was last added.
+	PriceSnapshot *int64 `json:"price_snapshot,omitempty"`
+	// CatalogPrice and Unavailable mirror the last known catalog state of the
+	// product, kept current by catalog events.
+	CatalogPrice  *int64    `json:"catalog_price,omitempty"`
+	Unavailable   bool      `gorm:"not null;default:false" json:"unavailable"`
+	CreatedAt     time.Time `gorm:"type:timestamptz;not null" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"type:timestamptz;not null" json:"updated_at"`
+}
+
+// Cart records activity of one cart owner (user or guest cart). Rows are
+// maintained by triggers on cart_items.
+type Cart struct {
+	OwnerID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"owner_id"`
+	CreatedAt           time.Time  `gorm:"type:timestamptz;not null" json:"created_at"`
+	UpdatedAt           time.Time  `gorm:"type:timestamptz;not null" json:"updated_at"`
+	AbandonedNotifiedAt *time.Time `gorm:"type:timestamptz" json:"abandoned_notified_at,omitempty"`
 }
 
 
@@ -17,5 +36,58 @@ func (c *CartItem) BeforeCreate(tx *gorm.DB) error {
     if c.ID == uuid.Nil {
         c.ID = uuid.New()
     }
+    if c.CatalogPrice == nil {
+        c.CatalogPrice = c.PriceSnapshot
+    }
     return nil
-}
\ No newline at end of file
+}
+// GuestCart owns the cart_items of an anonymous visitor: their rows use the
+// guest cart id as user_id until the cart is merged or expires.
+type GuestCart struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"type:timestamptz;not null" json:"created_at"`
+	ExpiresAt time.Time `gorm:"type:timestamptz;not null" json:"expires_at"`
+}
+
+type WishlistKind string
+
+const (
+	WishlistKindWishlist WishlistKind = "wishlist"
+	// WishlistKindSaved is the per-user "saved for later" list fed from the cart.
+	WishlistKindSaved WishlistKind = "saved"
+)
+
+type Wishlist struct {
+	ID         uuid.UUID    `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID    `gorm:"type:uuid;not null" json:"-"`
+	Name       string       `gorm:"type:text;not null" json:"name"`
+	Kind       WishlistKind `gorm:"type:text;not null" json:"kind"`
+	ShareToken *string      `gorm:"type:text" json:"share_token,omitempty"`
+	CreatedAt  time.Time    `gorm:"type:timestamptz;not null" json:"created_at"`
+	UpdatedAt  time.Time    `gorm:"type:timestamptz;not null" json:"updated_at"`
+}
+
+func (w *Wishlist) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	if w.Kind == "" {
+		w.Kind = WishlistKindWishlist
+	}
+	return nil
+}
+
+type WishlistItem struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	WishlistID uuid.UUID `gorm:"type:uuid;not null" json:"wishlist_id"`
+	ProductID  uuid.UUID `gorm:"type:uuid;not null" json:"product_id"`
+	Quantity   uint      `gorm:"not null;default:1" json:"quantity"`
+	AddedAt    time.Time `gorm:"type:timestamptz;not null;autoCreateTime" json:"added_at"`
+}
+
+func (i *WishlistItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
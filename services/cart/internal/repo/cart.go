@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"errors"
 
 	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
 	"gorm.io/gorm"
@@ -17,23 +18,39 @@ func (r *GormRepo) GetCart(ctx context.Context, userID uuid.UUID) ([]models.Cart
 	return items, nil
 }
 
-func (r *GormRepo) AddToCart(ctx context.Context, item *models.CartItem) error {
-    return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-        res := tx.Model(&models.CartItem{}).
-            Where("user_id = ? AND product_id = ?", item.UserID, item.ProductID).
-            Update("quantity", gorm.Expr("quantity + ?", item.Quantity))
-        if res.Error != nil {
-            return res.Error
-        }
-        if res.RowsAffected > 0 {
-            return tx.Where("user_id = ? AND product_id = ?", item.UserID, item.ProductID).First(item).Error
-        }
+var ErrQuantityLimit = errors.New("quantity limit exceeded")
 
-        if err := tx.Create(item).Error; err != nil {
-            return err
-        }
-        return nil
-    })
+// AddToCart increments the line, refusing to let its quantity exceed limit.
+// Insert, increment and limit check are a single statement, so concurrent
+// adds can neither overshoot the limit nor race each other into creating the
+// same line twice.
+func (r *GormRepo) AddToCart(ctx context.Context, item *models.CartItem, limit uint) error {
+	if item.Quantity > limit {
+		return ErrQuantityLimit
+	}
+
+	res := r.DB.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "product_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"quantity":       gorm.Expr("cart_items.quantity + excluded.quantity"),
+				"price_snapshot": gorm.Expr("excluded.price_snapshot"),
+				"catalog_price":  gorm.Expr("excluded.catalog_price"),
+				"unavailable":    false,
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				gorm.Expr("cart_items.quantity + excluded.quantity <= ?", limit),
+			}},
+		},
+		clause.Returning{},
+	).Create(item)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrQuantityLimit
+	}
+	return nil
 }
 
 func (r *GormRepo) DeleteOneFromCart(ctx context.Context, productID uuid.UUID, userID uuid.UUID) (bool, *models.CartItem, error) {
@@ -66,4 +83,79 @@ func (r *GormRepo) DeleteOneFromCart(ctx context.Context, productID uuid.UUID, u
 
 func (r *GormRepo) DeleteAllFromCart(ctx context.Context, userID uuid.UUID) error {
     return r.DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.CartItem{}).Error
-}
\ No newline at end of file
+}
+
+// SetQuantity stores the exact quantity of a line, creating it if needed.
+func (r *GormRepo) SetQuantity(ctx context.Context, item *models.CartItem) error {
+	return upsertItem(r.DB.WithContext(ctx), item)
+}
+
+func (r *GormRepo) RemoveItem(ctx context.Context, userID, productID uuid.UUID) error {
+	res := r.DB.WithContext(ctx).Where("user_id = ? AND product_id = ?", userID, productID).Delete(&models.CartItem{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ApplyChanges sets every line to the given quantity in one transaction; a
+// zero quantity removes the line.
+func (r *GormRepo) ApplyChanges(ctx context.Context, items []models.CartItem) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range items {
+			item := &items[i]
+			if item.Quantity == 0 {
+				if err := tx.Where("user_id = ? AND product_id = ?", item.UserID, item.ProductID).Delete(&models.CartItem{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := upsertItem(tx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func upsertItem(db *gorm.DB, item *models.CartItem) error {
+	return db.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"quantity", "price_snapshot", "catalog_price", "unavailable"}),
+		},
+		clause.Returning{},
+	).Create(item).Error
+}
+
+// RemoveProductLines deletes the product from every cart. The deletes do not
+// count as cart activity, so idle and abandonment timers keep running.
+func (r *GormRepo) RemoveProductLines(ctx context.Context, productID uuid.UUID) (int64, error) {
+	var removed int64
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL cart.catalog_sync = 'on'").Error; err != nil {
+			return err
+		}
+		res := tx.Where("product_id = ?", productID).Delete(&models.CartItem{})
+		removed = res.RowsAffected
+		return res.Error
+	})
+	return removed, err
+}
+
+// UpdateProductLines records the catalog state of the product on every line:
+// a line is unavailable when the product is hidden or its stock no longer
+// covers the line quantity.
+func (r *GormRepo) UpdateProductLines(ctx context.Context, productID uuid.UUID, price int64, count uint, available bool) (int64, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.CartItem{}).
+		Where("product_id = ?", productID).
+		UpdateColumns(map[string]any{
+			"catalog_price": price,
+			"unavailable":   gorm.Expr("NOT ? OR quantity > ?", available, count),
+		})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const mergeCartSQL = `
+INSERT INTO cart_items (id, user_id, product_id, quantity, price_snapshot, catalog_price, unavailable)
+SELECT gen_random_uuid(), ?, product_id, LEAST(quantity, ?), price_snapshot, catalog_price, unavailable
+FROM cart_items
+WHERE user_id = ?
+ON CONFLICT (user_id, product_id) DO UPDATE SET
+  quantity = LEAST(cart_items.quantity + EXCLUDED.quantity, ?)`
+
+func (r *GormRepo) CreateGuestCart(ctx context.Context, cart *models.GuestCart) error {
+	return r.DB.WithContext(ctx).Create(cart).Error
+}
+
+// TouchGuestCart extends a live guest cart. It reports false when the cart is
+// unknown or already expired.
+func (r *GormRepo) TouchGuestCart(ctx context.Context, id uuid.UUID, expiresAt time.Time) (bool, error) {
+	res := r.DB.WithContext(ctx).Model(&models.GuestCart{}).
+		Where("id = ? AND expires_at > now()", id).
+		Update("expires_at", expiresAt)
+	return res.RowsAffected > 0, res.Error
+}
+
+// MergeGuestCart moves the guest lines into the user cart, summing quantities
+// up to maxQuantity, and drops the guest cart. Merging an unknown or expired
+// guest cart is a no-op.
+func (r *GormRepo) MergeGuestCart(ctx context.Context, guestID, userID uuid.UUID, maxQuantity uint) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND expires_at > now()", guestID).Delete(&models.GuestCart{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			if err := tx.Exec(mergeCartSQL, userID, maxQuantity, guestID, maxQuantity).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ?", guestID).Delete(&models.CartItem{}).Error
+	})
+}
+
+// PurgeExpiredGuestCarts deletes expired guest carts with their lines.
+func (r *GormRepo) PurgeExpiredGuestCarts(ctx context.Context) (int64, error) {
+	var purged int64
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var carts []models.GuestCart
+		res := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+			Where("expires_at <= now()").
+			Delete(&carts)
+		if res.Error != nil || len(carts) == 0 {
+			return res.Error
+		}
+		purged = res.RowsAffected
+
+		ids := make([]uuid.UUID, len(carts))
+		for i, c := range carts {
+			ids[i] = c.ID
+		}
+		return tx.Where("user_id IN ?", ids).Delete(&models.CartItem{}).Error
+	})
+	return purged, err
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RunExclusive runs fn in a transaction holding the advisory lock key, so a
+// background job runs on one replica at a time. It reports false without
+// calling fn when another session holds the lock.
+func (r *GormRepo) RunExclusive(ctx context.Context, key int64, fn func(tx *GormRepo) error) (bool, error) {
+	var acquired bool
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", key).Scan(&acquired).Error; err != nil {
+			return err
+		}
+		if !acquired {
+			return nil
+		}
+		return fn(&GormRepo{DB: tx})
+	})
+	return acquired, err
+}
+
+// PurgeIdleCarts deletes up to limit carts, with their lines, that have not
+// changed since before.
+func (r *GormRepo) PurgeIdleCarts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var purged int64
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		err := tx.Model(&models.Cart{}).
+			Where("updated_at < ?", before).
+			Order("updated_at").
+			Limit(limit).
+			Pluck("owner_id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		if err := tx.Where("user_id IN ?", ids).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&models.GuestCart{}).Error; err != nil {
+			return err
+		}
+		// Deleting the lines touched the carts rows again, so remove them last.
+		res := tx.Where("owner_id IN ?", ids).Delete(&models.Cart{})
+		purged = res.RowsAffected
+		return res.Error
+	})
+	return purged, err
+}
+
+// AbandonedCarts returns non-empty user carts idle since before that have not
+// been reported yet. Guest carts are skipped: there is nobody to contact.
+func (r *GormRepo) AbandonedCarts(ctx context.Context, before time.Time, limit int) ([]models.Cart, error) {
+	var carts []models.Cart
+	err := r.DB.WithContext(ctx).
+		Where("updated_at < ? AND abandoned_notified_at IS NULL", before).
+		Where("EXISTS (SELECT 1 FROM cart_items ci WHERE ci.user_id = carts.owner_id)").
+		Where("NOT EXISTS (SELECT 1 FROM guest_carts g WHERE g.id = carts.owner_id)").
+		Order("updated_at").
+		Limit(limit).
+		Find(&carts).Error
+	return carts, err
+}
+
+func (r *GormRepo) MarkAbandonedNotified(ctx context.Context, ownerID uuid.UUID, at time.Time) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.Cart{}).
+		Where("owner_id = ?", ownerID).
+		UpdateColumn("abandoned_notified_at", at).Error
+}
+
+// UnmarkAbandonedNotified clears marks set at at, leaving carts that were
+// reported again since then alone.
+func (r *GormRepo) UnmarkAbandonedNotified(ctx context.Context, ownerIDs []uuid.UUID, at time.Time) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.Cart{}).
+		Where("owner_id IN ? AND abandoned_notified_at = ?", ownerIDs, at).
+		UpdateColumn("abandoned_notified_at", nil).Error
+}
@@ -0,0 +1,200 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const savedListName = "Saved for later"
+
+var ErrNotInCart = errors.New("product is not in the cart")
+
+type WishlistSummary struct {
+	models.Wishlist
+	ItemsCount int64
+}
+
+func (r *GormRepo) ListWishlists(ctx context.Context, userID uuid.UUID) ([]WishlistSummary, error) {
+	var lists []WishlistSummary
+	err := r.DB.WithContext(ctx).
+		Model(&models.Wishlist{}).
+		Select("wishlists.*, (SELECT count(*) FROM wishlist_items wi WHERE wi.wishlist_id = wishlists.id) AS items_count").
+		Where("user_id = ?", userID).
+		Order("kind DESC, created_at ASC").
+		Scan(&lists).Error
+	if err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+func (r *GormRepo) CountWishlists(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.DB.WithContext(ctx).Model(&models.Wishlist{}).Where("user_id = ?", userID).Count(&n).Error
+	return n, err
+}
+
+func (r *GormRepo) CreateWishlist(ctx context.Context, list *models.Wishlist) error {
+	return r.DB.WithContext(ctx).Create(list).Error
+}
+
+func (r *GormRepo) GetWishlist(ctx context.Context, id, userID uuid.UUID) (*models.Wishlist, error) {
+	var list models.Wishlist
+	if err := r.DB.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&list).Error; err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *GormRepo) GetWishlistByShareToken(ctx context.Context, token string) (*models.Wishlist, error) {
+	var list models.Wishlist
+	if err := r.DB.WithContext(ctx).Where("share_token = ?", token).First(&list).Error; err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// SavedList returns the user's "saved for later" list, or gorm.ErrRecordNotFound
+// while nothing has been saved yet.
+func (r *GormRepo) SavedList(ctx context.Context, userID uuid.UUID) (*models.Wishlist, error) {
+	var list models.Wishlist
+	err := r.DB.WithContext(ctx).Where("user_id = ? AND kind = ?", userID, models.WishlistKindSaved).First(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *GormRepo) WishlistItems(ctx context.Context, wishlistID uuid.UUID) ([]models.WishlistItem, error) {
+	var items []models.WishlistItem
+	err := r.DB.WithContext(ctx).Where("wishlist_id = ?", wishlistID).Order("added_at DESC, id").Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *GormRepo) UpdateWishlist(ctx context.Context, id, userID uuid.UUID, updates map[string]any) (*models.Wishlist, error) {
+	var list models.Wishlist
+	updates["updated_at"] = time.Now().UTC()
+	res := r.DB.WithContext(ctx).Model(&list).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(updates)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &list, nil
+}
+
+func (r *GormRepo) DeleteWishlist(ctx context.Context, id, userID uuid.UUID) error {
+	res := r.DB.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.Wishlist{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PutWishlistItem adds a product to a list or replaces its quantity.
+func (r *GormRepo) PutWishlistItem(ctx context.Context, item *models.WishlistItem) error {
+	return r.DB.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "wishlist_id"}, {Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"quantity"}),
+		},
+		clause.Returning{},
+	).Create(item).Error
+}
+
+func (r *GormRepo) RemoveWishlistItem(ctx context.Context, wishlistID, productID uuid.UUID) error {
+	res := r.DB.WithContext(ctx).Where("wishlist_id = ? AND product_id = ?", wishlistID, productID).Delete(&models.WishlistItem{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SaveForLater moves a cart line into the user's saved list, creating the list
+// on first use. Quantities add up if the product is already saved.
+func (r *GormRepo) SaveForLater(ctx context.Context, userID, productID uuid.UUID) (*models.WishlistItem, error) {
+	var saved models.WishlistItem
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var line models.CartItem
+		res := tx.Clauses(clause.Returning{}).
+			Where("user_id = ? AND product_id = ?", userID, productID).
+			Delete(&line)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotInCart
+		}
+
+		// The saved list is unique per user by kind; a concurrent save may
+		// create it first.
+		list := models.Wishlist{UserID: userID, Name: savedListName, Kind: models.WishlistKindSaved}
+		res = tx.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "user_id"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{gorm.Expr("kind = 'saved'")}},
+			DoNothing:   true,
+		}).Create(&list)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if err := tx.Where("user_id = ? AND kind = ?", userID, models.WishlistKindSaved).First(&list).Error; err != nil {
+				return err
+			}
+		}
+
+		saved = models.WishlistItem{WishlistID: list.ID, ProductID: productID, Quantity: line.Quantity}
+		return tx.Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "wishlist_id"}, {Name: "product_id"}},
+				DoUpdates: clause.Assignments(map[string]any{"quantity": gorm.Expr("wishlist_items.quantity + EXCLUDED.quantity")}),
+			},
+			clause.Returning{},
+		).Create(&saved).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// MoveToCart adds a list item to the cart without letting the line exceed
+// limit. The item leaves the list when remove is set.
+func (r *GormRepo) MoveToCart(ctx context.Context, wishlistID uuid.UUID, cartItem *models.CartItem, limit uint, remove bool) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var item models.WishlistItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("wishlist_id = ? AND product_id = ?", wishlistID, cartItem.ProductID).
+			First(&item).Error; err != nil {
+			return err
+		}
+		cartItem.Quantity = item.Quantity
+
+		if err := (&GormRepo{DB: tx}).AddToCart(ctx, cartItem, limit); err != nil {
+			return err
+		}
+		if remove {
+			return tx.Delete(&item).Error
+		}
+		return nil
+	})
+}
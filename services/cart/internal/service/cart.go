@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/Skotchmaster/online_shop/services/cart/internal/catalogclient"
 	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
 	"github.com/Skotchmaster/online_shop/services/cart/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/transport"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -14,16 +18,127 @@ import (
 var (
 	ErrValidation = errors.New("validation")
 	ErrNotFound = errors.New("not found")
+	ErrUnavailable = errors.New("unavailable")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrConflict = errors.New("conflict")
 )
 
 type CartService struct {
 	Repo        *repo.GormRepo
+	Catalog     *catalogclient.Client
+	// MaxQuantity caps the quantity of a single cart line.
+	MaxQuantity uint
+
+	GuestSecret []byte
+	GuestTTL    time.Duration
+
+	// Events receives cart.abandoned events; nil disables reporting.
+	Events       EventPublisher
+	AbandonAfter time.Duration
+	PurgeAfter   time.Duration
 }
 
-func (h *CartService) GetCart(ctx context.Context, userID uuid.UUID) ([]models.CartItem, error) {
+func (h *CartService) GetCart(ctx context.Context, userID uuid.UUID) (*transport.CartResponse, error) {
 	items, err := h.Repo.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.enrich(ctx, items)
+	if errors.Is(err, ErrUnavailable) {
+		slog.Warn("cart_served_from_stored_state", "error", err)
+		return storedCart(items), nil
+	}
+	return resp, err
+}
+
+// storedCart builds the cart from the catalog state recorded on the lines
+// when catalog itself cannot be reached. Stock levels are unknown then.
+func storedCart(items []models.CartItem) *transport.CartResponse {
+	resp := &transport.CartResponse{Items: make([]transport.CartLine, 0, len(items)), Stale: true}
+	for _, item := range items {
+		line := transport.CartLine{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Available: !item.Unavailable,
+		}
+
+		price := item.CatalogPrice
+		if price == nil {
+			price = item.PriceSnapshot
+		}
+		if price != nil {
+			line.Price = *price
+			line.LineTotal = *price * int64(item.Quantity)
+			if item.PriceSnapshot != nil && *item.PriceSnapshot != *price {
+				line.PriceChanged = true
+				line.PreviousPrice = item.PriceSnapshot
+			}
+		}
+
+		if line.Available {
+			resp.Subtotal += line.LineTotal
+			resp.ItemsCount += item.Quantity
+		}
+		if !line.Available || line.PriceChanged {
+			resp.HasIssues = true
+		}
+		resp.Items = append(resp.Items, line)
+	}
+	return resp
+}
+
+// enrich joins cart items with current catalog data in one batched call.
+func (h *CartService) enrich(ctx context.Context, items []models.CartItem) (*transport.CartResponse, error) {
+	resp := &transport.CartResponse{Items: make([]transport.CartLine, 0, len(items))}
+	if len(items) == 0 {
+		return resp, nil
+	}
+
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	products, err := h.Catalog.GetProducts(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %v: %w", err, ErrUnavailable)
+	}
+
+	for _, item := range items {
+		line := transport.CartLine{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		}
+
+		product, ok := products[item.ProductID]
+		if !ok {
+			line.Missing = true
+			resp.HasIssues = true
+			resp.Items = append(resp.Items, line)
+			continue
+		}
 
-	return items, err
+		line.Name = product.Name
+		line.Price = product.Price
+		line.LineTotal = product.Price * int64(item.Quantity)
+		line.InStock = product.Count
+		line.Available = product.Count >= item.Quantity
+		if item.PriceSnapshot != nil && *item.PriceSnapshot != product.Price {
+			line.PriceChanged = true
+			line.PreviousPrice = item.PriceSnapshot
+		}
+
+		if line.Available {
+			resp.Subtotal += line.LineTotal
+			resp.ItemsCount += item.Quantity
+		}
+		if !line.Available || line.PriceChanged {
+			resp.HasIssues = true
+		}
+		resp.Items = append(resp.Items, line)
+	}
+
+	return resp, nil
 }
 
 func (h *CartService) AddToCart(ctx context.Context, item *models.CartItem) error {
@@ -34,8 +149,20 @@ func (h *CartService) AddToCart(ctx context.Context, item *models.CartItem) erro
 		return fmt.Errorf("quantity must be more than zero: %w", ErrValidation)
 	}
 
-	err := h.Repo.AddToCart(ctx, item)
+	products, err := h.Catalog.GetProducts(ctx, []uuid.UUID{item.ProductID})
+	if err != nil {
+		return fmt.Errorf("catalog: %v: %w", err, ErrUnavailable)
+	}
+	product, ok := products[item.ProductID]
+	if !ok {
+		return fmt.Errorf("product not found: %w", ErrNotFound)
+	}
+	item.PriceSnapshot = &product.Price
 
+	err = h.Repo.AddToCart(ctx, item, h.quantityLimit(product))
+	if errors.Is(err, repo.ErrQuantityLimit) {
+		return h.limitError(product)
+	}
 	return err
 }
 
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/catalogclient"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+)
+
+// catalogServer answers the products batch call with products.
+func catalogServer(t *testing.T, products ...catalogclient.Product) *catalogclient.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalog/products/batch" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": products})
+	}))
+	t.Cleanup(srv.Close)
+	return catalogclient.NewClient(srv.URL)
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestCartService_Enrich(t *testing.T) {
+	t.Parallel()
+
+	lamp := catalogclient.Product{ID: uuid.New(), Name: "Lamp", Price: 1000, Count: 10}
+	desk := catalogclient.Product{ID: uuid.New(), Name: "Desk", Price: 5000, Count: 1}
+	gone := uuid.New()
+
+	svc := &CartService{Catalog: catalogServer(t, lamp, desk)}
+	resp, err := svc.enrich(context.Background(), []models.CartItem{
+		{ProductID: lamp.ID, Quantity: 2, PriceSnapshot: ptr(int64(1200))},
+		{ProductID: desk.ID, Quantity: 3, PriceSnapshot: ptr(int64(5000))},
+		{ProductID: gone, Quantity: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 3)
+
+	tests := []struct {
+		name         string
+		line         int
+		available    bool
+		missing      bool
+		priceChanged bool
+		lineTotal    int64
+	}{
+		{name: "price dropped", line: 0, available: true, priceChanged: true, lineTotal: 2000},
+		{name: "short on stock", line: 1, lineTotal: 15000},
+		{name: "gone from catalog", line: 2, missing: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := resp.Items[tt.line]
+			assert.Equal(t, tt.available, line.Available)
+			assert.Equal(t, tt.missing, line.Missing)
+			assert.Equal(t, tt.priceChanged, line.PriceChanged)
+			assert.Equal(t, tt.lineTotal, line.LineTotal)
+		})
+	}
+
+	assert.Equal(t, int64(1200), *resp.Items[0].PreviousPrice)
+	assert.Equal(t, int64(2000), resp.Subtotal, "only available lines count")
+	assert.Equal(t, uint(2), resp.ItemsCount)
+	assert.True(t, resp.HasIssues)
+	assert.False(t, resp.Stale)
+}
+
+func TestCartService_EnrichCatalogDown(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	svc := &CartService{Catalog: catalogclient.NewClient(srv.URL)}
+	_, err := svc.enrich(context.Background(), []models.CartItem{{ProductID: uuid.New(), Quantity: 1}})
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	resp, err := svc.enrich(context.Background(), nil)
+	require.NoError(t, err, "an empty cart never calls catalog")
+	assert.Empty(t, resp.Items)
+}
+
+func TestStoredCart(t *testing.T) {
+	t.Parallel()
+
+	resp := storedCart([]models.CartItem{
+		{ProductID: uuid.New(), Quantity: 2, PriceSnapshot: ptr(int64(100)), CatalogPrice: ptr(int64(80))},
+		{ProductID: uuid.New(), Quantity: 1, PriceSnapshot: ptr(int64(300))},
+		{ProductID: uuid.New(), Quantity: 4, PriceSnapshot: ptr(int64(50)), Unavailable: true},
+		{ProductID: uuid.New(), Quantity: 1},
+	})
+	require.Len(t, resp.Items, 4)
+
+	tests := []struct {
+		name         string
+		line         int
+		available    bool
+		priceChanged bool
+		price        int64
+		lineTotal    int64
+	}{
+		{name: "catalog price changed", line: 0, available: true, priceChanged: true, price: 80, lineTotal: 160},
+		{name: "snapshot only", line: 1, available: true, price: 300, lineTotal: 300},
+		{name: "unavailable", line: 2, price: 50, lineTotal: 200},
+		{name: "no prices known", line: 3, available: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			line := resp.Items[tt.line]
+			assert.Equal(t, tt.available, line.Available)
+			assert.Equal(t, tt.priceChanged, line.PriceChanged)
+			assert.Equal(t, tt.price, line.Price)
+			assert.Equal(t, tt.lineTotal, line.LineTotal)
+		})
+	}
+
+	assert.True(t, resp.Stale)
+	assert.Equal(t, int64(100), *resp.Items[0].PreviousPrice)
+	assert.Equal(t, int64(460), resp.Subtotal, "only available lines count")
+	assert.Equal(t, uint(4), resp.ItemsCount)
+	assert.True(t, resp.HasIssues)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/Skotchmaster/online_shop/pkg/events"
+	"github.com/segmentio/kafka-go"
+)
+
+// HandleProductMessage keeps cart lines in step with catalog: lines of deleted
+// products are removed, the others get the current price and availability so
+// the cart can warn about them before checkout.
+func (h *CartService) HandleProductMessage(ctx context.Context, msg kafka.Message) error {
+	var event events.ProductEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return fmt.Errorf("decode product event: %w", err)
+	}
+
+	switch event.Type {
+	case events.ProductDeleted:
+		removed, err := h.Repo.RemoveProductLines(ctx, event.ProductID)
+		if err != nil {
+			return err
+		}
+		if removed > 0 {
+			slog.Info("cart_lines_removed", "product_id", event.ProductID, "lines", removed)
+		}
+	case events.ProductUpdated:
+		if _, err := h.Repo.UpdateProductLines(ctx, event.ProductID, event.Price, event.Count, event.Available); err != nil {
+			return err
+		}
+	default:
+		slog.Warn("product_event_unknown", "type", event.Type, "product_id", event.ProductID)
+	}
+	return nil
+}
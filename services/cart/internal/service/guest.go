@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/guest"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+	"github.com/google/uuid"
+)
+
+// GuestSession identifies the cart of an anonymous visitor.
+type GuestSession struct {
+	ID        uuid.UUID
+	Token     string
+	ExpiresAt time.Time
+}
+
+// ResolveGuest checks a guest cookie and slides the cart expiry. A nil session
+// means the token is invalid or its cart has expired.
+func (h *CartService) ResolveGuest(ctx context.Context, token string) (*GuestSession, error) {
+	id, err := guest.Parse(h.GuestSecret, token)
+	if err != nil {
+		return nil, nil
+	}
+
+	expiresAt := time.Now().UTC().Add(h.GuestTTL)
+	ok, err := h.Repo.TouchGuestCart(ctx, id, expiresAt)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &GuestSession{ID: id, Token: token, ExpiresAt: expiresAt}, nil
+}
+
+func (h *CartService) NewGuestCart(ctx context.Context) (*GuestSession, error) {
+	cart := models.GuestCart{
+		ID:        uuid.New(),
+		ExpiresAt: time.Now().UTC().Add(h.GuestTTL),
+	}
+	if err := h.Repo.CreateGuestCart(ctx, &cart); err != nil {
+		return nil, err
+	}
+	return &GuestSession{
+		ID:        cart.ID,
+		Token:     guest.Sign(h.GuestSecret, cart.ID),
+		ExpiresAt: cart.ExpiresAt,
+	}, nil
+}
+
+// MergeGuestCart moves a guest cart into the user cart after login. Quantities
+// of lines present in both are summed and capped at MaxQuantity; stock is not
+// checked here, the enriched cart flags lines that exceed it.
+func (h *CartService) MergeGuestCart(ctx context.Context, userID uuid.UUID, token string) error {
+	if userID == uuid.Nil {
+		return fmt.Errorf("user id must be not nil: %w", ErrValidation)
+	}
+	guestID, err := guest.Parse(h.GuestSecret, token)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, ErrValidation)
+	}
+
+	return h.Repo.MergeGuestCart(ctx, guestID, userID, h.MaxQuantity)
+}
+
+// StartGuestCartPurge removes expired guest carts every interval until ctx is
+// cancelled.
+func (h *CartService) StartGuestCartPurge(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := h.Repo.PurgeExpiredGuestCarts(ctx)
+				if err != nil {
+					slog.Error("guest_cart_purge_failed", "error", err)
+					continue
+				}
+				if purged > 0 {
+					slog.Info("guest_cart_purge_done", "purged", purged)
+				}
+			}
+		}
+	}()
+}
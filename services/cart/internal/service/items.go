@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/catalogclient"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/transport"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxBatchChanges = catalogclient.MaxBatch
+
+// ItemError ties a validation failure to the cart line that caused it.
+type ItemError struct {
+	ProductID uuid.UUID
+	Err       error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("product %s: %v", e.ProductID, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// quantityLimit is the largest quantity a line of product may hold.
+func (h *CartService) quantityLimit(product catalogclient.Product) uint {
+	return min(h.MaxQuantity, product.Count)
+}
+
+func (h *CartService) limitError(product catalogclient.Product) error {
+	if product.Count < h.MaxQuantity {
+		return fmt.Errorf("only %d in stock: %w", product.Count, ErrInsufficientStock)
+	}
+	return fmt.Errorf("at most %d per item: %w", h.MaxQuantity, ErrValidation)
+}
+
+func (h *CartService) checkQuantity(products map[uuid.UUID]catalogclient.Product, productID uuid.UUID, quantity uint) (*catalogclient.Product, error) {
+	product, ok := products[productID]
+	if !ok {
+		return nil, fmt.Errorf("product not found: %w", ErrNotFound)
+	}
+	if quantity > h.quantityLimit(product) {
+		return nil, h.limitError(product)
+	}
+	return &product, nil
+}
+
+// SetQuantity sets the exact quantity of a line. Zero removes the line and
+// returns a nil item.
+func (h *CartService) SetQuantity(ctx context.Context, userID, productID uuid.UUID, quantity uint) (*models.CartItem, error) {
+	if productID == uuid.Nil {
+		return nil, fmt.Errorf("ID product must be not nil: %w", ErrValidation)
+	}
+	if quantity == 0 {
+		err := h.RemoveItem(ctx, userID, productID)
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	products, err := h.Catalog.GetProducts(ctx, []uuid.UUID{productID})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %v: %w", err, ErrUnavailable)
+	}
+	product, err := h.checkQuantity(products, productID, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	item := models.CartItem{
+		UserID:        userID,
+		ProductID:     productID,
+		Quantity:      quantity,
+		PriceSnapshot: &product.Price,
+	}
+	if err := h.Repo.SetQuantity(ctx, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (h *CartService) RemoveItem(ctx context.Context, userID, productID uuid.UUID) error {
+	err := h.Repo.RemoveItem(ctx, userID, productID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("item not found: %w", ErrNotFound)
+	}
+	return err
+}
+
+// ApplyBatch validates all changes against the catalog first and then applies
+// them in one transaction, so either every change lands or none does.
+func (h *CartService) ApplyBatch(ctx context.Context, userID uuid.UUID, changes []transport.CartChange) (*transport.CartResponse, error) {
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no changes: %w", ErrValidation)
+	}
+	if len(changes) > maxBatchChanges {
+		return nil, fmt.Errorf("at most %d changes allowed: %w", maxBatchChanges, ErrValidation)
+	}
+
+	ids := make([]uuid.UUID, 0, len(changes))
+	seen := make(map[uuid.UUID]bool, len(changes))
+	for _, ch := range changes {
+		if ch.ProductID == uuid.Nil {
+			return nil, fmt.Errorf("ID product must be not nil: %w", ErrValidation)
+		}
+		if ch.Quantity == nil {
+			return nil, &ItemError{ProductID: ch.ProductID, Err: fmt.Errorf("quantity is required: %w", ErrValidation)}
+		}
+		if seen[ch.ProductID] {
+			return nil, &ItemError{ProductID: ch.ProductID, Err: fmt.Errorf("duplicate product: %w", ErrValidation)}
+		}
+		seen[ch.ProductID] = true
+		if *ch.Quantity > 0 {
+			ids = append(ids, ch.ProductID)
+		}
+	}
+
+	var products map[uuid.UUID]catalogclient.Product
+	if len(ids) > 0 {
+		var err error
+		products, err = h.Catalog.GetProducts(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("catalog: %v: %w", err, ErrUnavailable)
+		}
+	}
+
+	items := make([]models.CartItem, 0, len(changes))
+	for _, ch := range changes {
+		item := models.CartItem{
+			UserID:    userID,
+			ProductID: ch.ProductID,
+			Quantity:  *ch.Quantity,
+		}
+		if item.Quantity > 0 {
+			product, err := h.checkQuantity(products, ch.ProductID, item.Quantity)
+			if err != nil {
+				return nil, &ItemError{ProductID: ch.ProductID, Err: err}
+			}
+			item.PriceSnapshot = &product.Price
+		}
+		items = append(items, item)
+	}
+
+	if err := h.Repo.ApplyChanges(ctx, items); err != nil {
+		return nil, err
+	}
+	return h.GetCart(ctx, userID)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/transport"
+	"github.com/google/uuid"
+)
+
+const (
+	CartAbandonedTopic = "cart.abandoned"
+
+	// cartLifecycleLock is the advisory lock key shared by all cart replicas.
+	cartLifecycleLock int64 = 0x63617274
+	lifecycleBatch          = 500
+)
+
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, topic, key string, event any) error
+}
+
+// StartCartLifecycle reports abandoned carts and purges idle ones every
+// interval until ctx is cancelled. A zero AbandonAfter or PurgeAfter disables
+// the corresponding step, as does a nil Events publisher for reporting.
+func (h *CartService) StartCartLifecycle(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.runCartLifecycle(ctx)
+			}
+		}
+	}()
+}
+
+// runCartLifecycle runs each step in its own transaction, so a failed purge
+// does not undo the marks of carts that were already reported.
+func (h *CartService) runCartLifecycle(ctx context.Context) {
+	// Postgres keeps microseconds; the truncated time matches the stored mark.
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	if h.Events != nil && h.AbandonAfter > 0 {
+		acquired, reported, err := h.reportAbandoned(ctx, now)
+		switch {
+		case err != nil:
+			slog.Error("cart_abandoned_report_failed", "error", err, "reported", reported)
+		case !acquired:
+			slog.Debug("cart_lifecycle_skipped", "reason", "locked by another replica")
+			return
+		case reported > 0:
+			slog.Info("cart_abandoned_reported", "carts", reported)
+		}
+	}
+
+	if h.PurgeAfter > 0 {
+		var purged int64
+		acquired, err := h.Repo.RunExclusive(ctx, cartLifecycleLock, func(tx *repo.GormRepo) error {
+			var err error
+			purged, err = tx.PurgeIdleCarts(ctx, now.Add(-h.PurgeAfter), lifecycleBatch)
+			return err
+		})
+		switch {
+		case err != nil:
+			slog.Error("cart_lifecycle_failed", "error", err)
+		case !acquired:
+			slog.Debug("cart_lifecycle_skipped", "reason", "locked by another replica")
+		case purged > 0:
+			slog.Info("cart_purge_done", "purged", purged)
+		}
+	}
+}
+
+// reportAbandoned marks abandoned carts and publishes one event per cart once
+// the marks are committed, so a cart is reported again only after new
+// activity. Carts whose events were not published are unmarked for the next
+// run to retry.
+func (h *CartService) reportAbandoned(ctx context.Context, now time.Time) (bool, int, error) {
+	var events []transport.CartAbandonedEvent
+	acquired, err := h.Repo.RunExclusive(ctx, cartLifecycleLock, func(tx *repo.GormRepo) error {
+		carts, err := tx.AbandonedCarts(ctx, now.Add(-h.AbandonAfter), lifecycleBatch)
+		if err != nil {
+			return err
+		}
+		for _, cart := range carts {
+			items, err := tx.GetCart(ctx, cart.OwnerID)
+			if err != nil {
+				return err
+			}
+			if err := tx.MarkAbandonedNotified(ctx, cart.OwnerID, now); err != nil {
+				return err
+			}
+			events = append(events, abandonedEvent(cart, items, now))
+		}
+		return nil
+	})
+	if err != nil || !acquired {
+		return acquired, 0, err
+	}
+
+	for i, event := range events {
+		if err := h.Events.PublishEvent(ctx, CartAbandonedTopic, event.UserID.String(), event); err != nil {
+			pending := make([]uuid.UUID, 0, len(events)-i)
+			for _, e := range events[i:] {
+				pending = append(pending, e.UserID)
+			}
+			if err := h.Repo.UnmarkAbandonedNotified(context.WithoutCancel(ctx), pending, now); err != nil {
+				slog.Error("cart_abandoned_unmark_failed", "error", err, "carts", len(pending))
+			}
+			return true, i, err
+		}
+	}
+	return true, len(events), nil
+}
+
+func abandonedEvent(cart models.Cart, items []models.CartItem, now time.Time) transport.CartAbandonedEvent {
+	event := transport.CartAbandonedEvent{
+		EventID:        uuid.New(),
+		Type:           CartAbandonedTopic,
+		UserID:         cart.OwnerID,
+		Items:          make([]transport.AbandonedCartItem, len(items)),
+		LastActivityAt: cart.UpdatedAt,
+		OccurredAt:     now,
+	}
+	for i, item := range items {
+		event.Items[i] = transport.AbandonedCartItem{
+			ProductID:     item.ProductID,
+			Quantity:      item.Quantity,
+			PriceSnapshot: item.PriceSnapshot,
+		}
+		if item.PriceSnapshot != nil {
+			event.Subtotal += *item.PriceSnapshot * int64(item.Quantity)
+		}
+	}
+	return event
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+)
+
+func TestAbandonedEvent(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cart := models.Cart{OwnerID: uuid.New(), UpdatedAt: now.Add(-48 * time.Hour)}
+	lamp, desk := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		items    []models.CartItem
+		subtotal int64
+	}{
+		{name: "no lines", items: nil, subtotal: 0},
+		{
+			name: "priced lines",
+			items: []models.CartItem{
+				{ProductID: lamp, Quantity: 2, PriceSnapshot: ptr(int64(1000))},
+				{ProductID: desk, Quantity: 1, PriceSnapshot: ptr(int64(5000))},
+			},
+			subtotal: 7000,
+		},
+		{
+			name: "line without snapshot",
+			items: []models.CartItem{
+				{ProductID: lamp, Quantity: 3, PriceSnapshot: ptr(int64(1000))},
+				{ProductID: desk, Quantity: 1},
+			},
+			subtotal: 3000,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			event := abandonedEvent(cart, tt.items, now)
+			assert.NotEqual(t, uuid.Nil, event.EventID)
+			assert.Equal(t, CartAbandonedTopic, event.Type)
+			assert.Equal(t, cart.OwnerID, event.UserID)
+			assert.Equal(t, cart.UpdatedAt, event.LastActivityAt)
+			assert.Equal(t, now, event.OccurredAt)
+			assert.Equal(t, tt.subtotal, event.Subtotal)
+			assert.Len(t, event.Items, len(tt.items))
+			for i, item := range tt.items {
+				assert.Equal(t, item.ProductID, event.Items[i].ProductID)
+				assert.Equal(t, item.Quantity, event.Items[i].Quantity)
+				assert.Equal(t, item.PriceSnapshot, event.Items[i].PriceSnapshot)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Skotchmaster/online_shop/services/cart/internal/models"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/cart/internal/transport"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxWishlists       = 20
+	maxWishlistNameLen = 100
+)
+
+func newShareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func validateWishlistName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxWishlistNameLen {
+		return "", fmt.Errorf("name must be 1..%d characters: %w", maxWishlistNameLen, ErrValidation)
+	}
+	return name, nil
+}
+
+func (h *CartService) ListWishlists(ctx context.Context, userID uuid.UUID) ([]transport.WishlistSummary, error) {
+	lists, err := h.Repo.ListWishlists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]transport.WishlistSummary, len(lists))
+	for i, l := range lists {
+		out[i] = transport.WishlistSummary{
+			ID:         l.ID,
+			Name:       l.Name,
+			Kind:       string(l.Kind),
+			ShareToken: l.ShareToken,
+			ItemsCount: l.ItemsCount,
+			CreatedAt:  l.CreatedAt,
+			UpdatedAt:  l.UpdatedAt,
+		}
+	}
+	return out, nil
+}
+
+func (h *CartService) CreateWishlist(ctx context.Context, userID uuid.UUID, req transport.CreateWishlistRequest) (*models.Wishlist, error) {
+	name, err := validateWishlistName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := h.Repo.CountWishlists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n >= maxWishlists {
+		return nil, fmt.Errorf("at most %d lists allowed: %w", maxWishlists, ErrValidation)
+	}
+
+	list := models.Wishlist{UserID: userID, Name: name}
+	err = h.Repo.CreateWishlist(ctx, &list)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fmt.Errorf("list %q already exists: %w", name, ErrConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (h *CartService) GetWishlist(ctx context.Context, userID, id uuid.UUID) (*transport.WishlistResponse, error) {
+	list, err := h.Repo.GetWishlist(ctx, id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("list not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.wishlistResponse(ctx, list)
+}
+
+// GetSharedWishlist serves a list by its public link.
+func (h *CartService) GetSharedWishlist(ctx context.Context, token string) (*transport.WishlistResponse, error) {
+	list, err := h.Repo.GetWishlistByShareToken(ctx, token)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("list not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.wishlistResponse(ctx, list)
+}
+
+// GetSavedList returns the "saved for later" list, empty until the first save.
+func (h *CartService) GetSavedList(ctx context.Context, userID uuid.UUID) (*transport.WishlistResponse, error) {
+	list, err := h.Repo.SavedList(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &transport.WishlistResponse{Kind: string(models.WishlistKindSaved), Items: []transport.WishlistLine{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.wishlistResponse(ctx, list)
+}
+
+func (h *CartService) wishlistResponse(ctx context.Context, list *models.Wishlist) (*transport.WishlistResponse, error) {
+	items, err := h.Repo.WishlistItems(ctx, list.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &transport.WishlistResponse{
+		ID:         list.ID,
+		Name:       list.Name,
+		Kind:       string(list.Kind),
+		ShareToken: list.ShareToken,
+		Items:      make([]transport.WishlistLine, 0, len(items)),
+		CreatedAt:  list.CreatedAt,
+		UpdatedAt:  list.UpdatedAt,
+	}
+	if len(items) == 0 {
+		return resp, nil
+	}
+
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	products, err := h.Catalog.GetProducts(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %v: %w", err, ErrUnavailable)
+	}
+
+	for _, item := range items {
+		line := transport.WishlistLine{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			AddedAt:   item.AddedAt,
+		}
+		if product, ok := products[item.ProductID]; ok {
+			line.Name = product.Name
+			line.Price = product.Price
+			line.Available = product.Count >= item.Quantity
+		} else {
+			line.Missing = true
+		}
+		resp.Items = append(resp.Items, line)
+	}
+	return resp, nil
+}
+
+func (h *CartService) PatchWishlist(ctx context.Context, userID, id uuid.UUID, req transport.PatchWishlistRequest) (*models.Wishlist, error) {
+	updates := map[string]any{}
+	if req.Name != nil {
+		name, err := validateWishlistName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		updates["name"] = name
+	}
+	if req.Shared != nil {
+		if *req.Shared {
+			token, err := newShareToken()
+			if err != nil {
+				return nil, err
+			}
+			updates["share_token"] = token
+		} else {
+			updates["share_token"] = nil
+		}
+	}
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("empty patch: %w", ErrValidation)
+	}
+
+	list, err := h.Repo.UpdateWishlist(ctx, id, userID, updates)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("list not found: %w", ErrNotFound)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fmt.Errorf("list with this name already exists: %w", ErrConflict)
+	}
+	return list, err
+}
+
+func (h *CartService) DeleteWishlist(ctx context.Context, userID, id uuid.UUID) error {
+	err := h.Repo.DeleteWishlist(ctx, id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("list not found: %w", ErrNotFound)
+	}
+	return err
+}
+
+func (h *CartService) AddWishlistItem(ctx context.Context, userID, id uuid.UUID, req transport.WishlistItemRequest) (*models.WishlistItem, error) {
+	if req.ProductID == uuid.Nil {
+		return nil, fmt.Errorf("ID product must be not nil: %w", ErrValidation)
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity > h.MaxQuantity {
+		return nil, fmt.Errorf("at most %d per item: %w", h.MaxQuantity, ErrValidation)
+	}
+
+	if _, err := h.Repo.GetWishlist(ctx, id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("list not found: %w", ErrNotFound)
+		}
+		return nil, err
+	}
+	products, err := h.Catalog.GetProducts(ctx, []uuid.UUID{req.ProductID})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %v: %w", err, ErrUnavailable)
+	}
+	if _, ok := products[req.ProductID]; !ok {
+		return nil, fmt.Errorf("product not found: %w", ErrNotFound)
+	}
+
+	item := models.WishlistItem{WishlistID: id, ProductID: req.ProductID, Quantity: req.Quantity}
+	if err := h.Repo.PutWishlistItem(ctx, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (h *CartService) RemoveWishlistItem(ctx context.Context, userID, id, productID uuid.UUID) error {
+	if _, err := h.Repo.GetWishlist(ctx, id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("list not found: %w", ErrNotFound)
+		}
+		return err
+	}
+
+	err := h.Repo.RemoveWishlistItem(ctx, id, productID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("item not found: %w", ErrNotFound)
+	}
+	return err
+}
+
+// MoveToCart adds a list item to the cart. Items of the "saved for later" list
+// leave it; wishlist items stay, so a wishlist can be bought from repeatedly.
+func (h *CartService) MoveToCart(ctx context.Context, userID, id, productID uuid.UUID) (*models.CartItem, error) {
+	list, err := h.Repo.GetWishlist(ctx, id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("list not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := h.Catalog.GetProducts(ctx, []uuid.UUID{productID})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %v: %w", err, ErrUnavailable)
+	}
+	product, ok := products[productID]
+	if !ok {
+		return nil, fmt.Errorf("product not found: %w", ErrNotFound)
+	}
+
+	item := models.CartItem{
+		UserID:        userID,
+		ProductID:     productID,
+		PriceSnapshot: &product.Price,
+	}
+	err = h.Repo.MoveToCart(ctx, list.ID, &item, h.quantityLimit(product), list.Kind == models.WishlistKindSaved)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("item not found: %w", ErrNotFound)
+	}
+	if errors.Is(err, repo.ErrQuantityLimit) {
+		return nil, h.limitError(product)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (h *CartService) SaveForLater(ctx context.Context, userID, productID uuid.UUID) (*models.WishlistItem, error) {
+	item, err := h.Repo.SaveForLater(ctx, userID, productID)
+	if errors.Is(err, repo.ErrNotInCart) {
+		return nil, fmt.Errorf("item not found: %w", ErrNotFound)
+	}
+	return item, err
+}
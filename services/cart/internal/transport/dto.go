@@ -1,9 +1,116 @@
 package transport
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type DeleteOneFromCartResponse struct {
 	ProductID uuid.UUID `json:"product_id"`
 	Deleted   bool      `json:"deleted"`
 	Quantity  uint      `json:"quantity"`
 }
+
+// CartLine is a cart item enriched with catalog data. When the product is gone
+// from the catalog only ProductID, Quantity and Missing are meaningful.
+type CartLine struct {
+	ProductID     uuid.UUID `json:"product_id"`
+	Quantity      uint      `json:"quantity"`
+	Name          string    `json:"name,omitempty"`
+	Price         int64     `json:"price"`
+	PreviousPrice *int64    `json:"previous_price,omitempty"`
+	LineTotal     int64     `json:"line_total"`
+	InStock       uint      `json:"in_stock"`
+	Available     bool      `json:"available"`
+	Missing       bool      `json:"missing"`
+	PriceChanged  bool      `json:"price_changed"`
+}
+
+type CartResponse struct {
+	Items      []CartLine `json:"items"`
+	ItemsCount uint       `json:"items_count"`
+	Subtotal   int64      `json:"subtotal"`
+	HasIssues  bool       `json:"has_issues"`
+	// Stale is set when catalog was unreachable and the cart was built from
+	// the state last received through catalog events.
+	Stale bool `json:"stale,omitempty"`
+}
+
+type SetQuantityRequest struct {
+	Quantity *uint `json:"quantity"`
+}
+
+// CartChange sets the quantity of one line; zero removes it.
+type CartChange struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  *uint     `json:"quantity"`
+}
+
+type BatchCartRequest struct {
+	Items []CartChange `json:"items"`
+}
+
+type CreateWishlistRequest struct {
+	Name string `json:"name"`
+}
+
+// PatchWishlistRequest renames a list and toggles its public link. Turning
+// sharing on again issues a new link.
+type PatchWishlistRequest struct {
+	Name   *string `json:"name"`
+	Shared *bool   `json:"shared"`
+}
+
+type WishlistItemRequest struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  uint      `json:"quantity"`
+}
+
+type WishlistSummary struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Kind       string    `json:"kind"`
+	ShareToken *string   `json:"share_token,omitempty"`
+	ItemsCount int64     `json:"items_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WishlistLine struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  uint      `json:"quantity"`
+	Name      string    `json:"name,omitempty"`
+	Price     int64     `json:"price"`
+	Available bool      `json:"available"`
+	Missing   bool      `json:"missing"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+type WishlistResponse struct {
+	ID         uuid.UUID      `json:"id"`
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"`
+	ShareToken *string        `json:"share_token,omitempty"`
+	Items      []WishlistLine `json:"items"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+type AbandonedCartItem struct {
+	ProductID     uuid.UUID `json:"product_id"`
+	Quantity      uint      `json:"quantity"`
+	PriceSnapshot *int64    `json:"price_snapshot,omitempty"`
+}
+
+// CartAbandonedEvent is published when a user's cart has been idle past the
+// abandonment threshold. Subtotal uses the price snapshots of the lines.
+type CartAbandonedEvent struct {
+	EventID        uuid.UUID           `json:"event_id"`
+	Type           string              `json:"type"`
+	UserID         uuid.UUID           `json:"user_id"`
+	Items          []AbandonedCartItem `json:"items"`
+	Subtotal       int64               `json:"subtotal"`
+	LastActivityAt time.Time           `json:"last_activity_at"`
+	OccurredAt     time.Time           `json:"occurred_at"`
+}
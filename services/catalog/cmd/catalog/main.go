@@ -15,12 +15,18 @@ import (
 	echomw "github.com/labstack/echo/v4/middleware"
 
 	"github.com/Skotchmaster/online_shop/pkg/authclient"
+	"github.com/Skotchmaster/online_shop/pkg/events"
 	pkgdb "github.com/Skotchmaster/online_shop/pkg/db"
 	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/mykafka"
 	loggingmw "github.com/Skotchmaster/online_shop/pkg/middleware/logging"
+	"github.com/Skotchmaster/online_shop/pkg/revocation"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
 
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/cache"
 	catalogcfg "github.com/Skotchmaster/online_shop/services/catalog/internal/config"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/httpserver"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/orderclient"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/repo"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/service"
 )
@@ -33,7 +39,7 @@ func main() {
 	cfg := catalogcfg.Load()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	db, err := pkgdb.Open(ctx, cfg.DatabaseURL)
+	db, err := pkgdb.Open(ctx, cfg.DatabaseURL, pkgdb.TranslateErrors)
 	cancel()
 	if err != nil {
 		log.Fatalf("db open: %v", err)
@@ -42,9 +48,42 @@ func main() {
 	logger := logging.New(os.Getenv("LOG_LEVEL")).With("service", cfg.ServiceName)
 	slog.SetDefault(logger)
 
+	var respCache cache.Cache
+	switch cfg.CacheBackend {
+	case "redis":
+		rc, err := cache.NewRedis(cfg.RedisURL, "catalog:")
+		if err != nil {
+			log.Fatalf("redis: %v", err)
+		}
+		defer rc.Close()
+		respCache = rc
+	case "none":
+	default:
+		respCache = cache.NewLRU(cfg.CacheSize)
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
 	repo := &repo.GormRepo{DB: db}
-	svc := &service.CatalogService{Repo: repo}
-	handler := &httpserver.CatalogHTTP{Svc: svc}
+	svc := &service.CatalogService{
+		Repo:     repo,
+		Orders:   orderclient.NewClient(cfg.OrderHTTPURL, cfg.InternalAPIToken),
+		Cache:    respCache,
+		CacheTTL: cfg.CacheTTL,
+		JobsCtx:  jobsCtx,
+	}
+	if len(cfg.KafkaBrokers) > 0 {
+		producer, err := mykafka.NewProducer(cfg.KafkaBrokers, []string{events.ProductsTopic})
+		if err != nil {
+			log.Fatalf("kafka producer: %v", err)
+		}
+		defer producer.Close()
+		svc.Events = producer
+	} else {
+		logger.Warn("product_events_disabled", "reason", "KAFKA_BROKERS is not set")
+	}
+	handler := &httpserver.CatalogHTTP{Svc: svc, MaxAge: cfg.HTTPMaxAge}
 
 	e := echo.New()
 	e.Use(echomw.Recover())
@@ -54,12 +93,32 @@ func main() {
 
 	authclient := authclient.NewClient(cfg.AuthHTTPURL)
 
+	verifier := tokens.NewVerifier(tokens.NewJWKSClient(cfg.JWKSURL, cfg.JWKSCacheTTL), cfg.JWTAccessSecret)
+	follower, err := revocation.Attach(jobsCtx, verifier, cfg.KafkaBrokers)
+	if err != nil {
+		log.Fatalf("token revocation: %v", err)
+	}
+	defer follower.Close()
+
 	httpserver.Register(e, &httpserver.Deps{
 		CatalogHandler: handler,
-		JWTSecret:      cfg.JWTAccessSecret,
+		Verifier:       verifier,
 		AuthClient:     authclient,
 	})
 
+	svc.StartScheduleEvents(jobsCtx, cfg.ScheduleEventsInterval)
+
+	if cfg.InternalAPIToken != "" {
+		svc.StartBoughtTogetherJob(jobsCtx, service.BoughtTogetherConfig{
+			Interval:   cfg.BoughtTogetherInterval,
+			WindowDays: cfg.BoughtTogetherWindowDays,
+			MinOrders:  cfg.BoughtTogetherMinOrders,
+			PerProduct: 20,
+		})
+	} else {
+		logger.Warn("bought_together_disabled", "reason", "INTERNAL_API_TOKEN is not set")
+	}
+
 	srv := &http.Server{
 		Addr:              ":" + os.Getenv("SERVER_PORT"),
 		Handler:           e,
@@ -87,6 +146,11 @@ func main() {
 	defer shutdownCancel()
 
 	_ = srv.Shutdown(shutdownCtx)
+	stopJobs()
+
+	if err := svc.Wait(shutdownCtx); err != nil {
+		log.Printf("background jobs still running: %v", err)
+	}
 
 	if sqlDB, err := db.DB(); err == nil {
 		_ = sqlDB.Close()
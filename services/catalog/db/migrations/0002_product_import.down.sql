@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_import_jobs_created_at;
+DROP TABLE IF EXISTS import_jobs;
+
+DROP INDEX IF EXISTS ux_products_sku;
+
+ALTER TABLE products
+  DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,28 @@
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS sku text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_products_sku
+  ON products (sku);
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  format         text NOT NULL,
+  status         text NOT NULL,
+  total_rows     integer NOT NULL DEFAULT 0,
+  processed_rows integer NOT NULL DEFAULT 0,
+  created_rows   integer NOT NULL DEFAULT 0,
+  updated_rows   integer NOT NULL DEFAULT 0,
+  failed_rows    integer NOT NULL DEFAULT 0,
+  errors         jsonb NOT NULL DEFAULT '[]'::jsonb,
+  message        text NOT NULL DEFAULT '',
+  created_by     uuid,
+  created_at     timestamptz NOT NULL DEFAULT now(),
+  started_at     timestamptz,
+  finished_at    timestamptz,
+
+  CONSTRAINT chk_import_jobs_status
+    CHECK (status IN ('PENDING', 'RUNNING', 'DONE', 'FAILED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_created_at
+  ON import_jobs (created_at DESC);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_products_status_visible;
+DROP INDEX IF EXISTS idx_products_deleted_at;
+
+ALTER TABLE products
+  DROP CONSTRAINT IF EXISTS chk_products_status;
+
+ALTER TABLE products
+  DROP COLUMN IF EXISTS deleted_at,
+  DROP COLUMN IF EXISTS unpublish_at,
+  DROP COLUMN IF EXISTS publish_at,
+  DROP COLUMN IF EXISTS status;
@@ -0,0 +1,16 @@
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS status       text NOT NULL DEFAULT 'active',
+  ADD COLUMN IF NOT EXISTS publish_at   timestamptz,
+  ADD COLUMN IF NOT EXISTS unpublish_at timestamptz,
+  ADD COLUMN IF NOT EXISTS deleted_at   timestamptz;
+
+ALTER TABLE products
+  ADD CONSTRAINT chk_products_status
+    CHECK (status IN ('draft', 'active', 'archived'));
+
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at
+  ON products (deleted_at);
+
+CREATE INDEX IF NOT EXISTS idx_products_status_visible
+  ON products (status, publish_at, unpublish_at)
+  WHERE deleted_at IS NULL;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS price_schedules;
+
+DROP TRIGGER IF EXISTS trg_products_price_history ON products;
+DROP FUNCTION IF EXISTS products_price_history();
+
+DROP TABLE IF EXISTS price_history;
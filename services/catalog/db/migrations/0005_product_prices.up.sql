@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS price_history (
+  id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id uuid NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  old_price  bigint,
+  new_price  bigint NOT NULL,
+  changed_by uuid,
+  changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_product_changed_at
+  ON price_history (product_id, changed_at DESC, id DESC);
+
+-- The writer may pass the acting user with set_config('app.actor_id', ..., true).
+CREATE OR REPLACE FUNCTION products_price_history()
+RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' OR NEW.price IS DISTINCT FROM OLD.price THEN
+    INSERT INTO price_history (product_id, old_price, new_price, changed_by)
+    VALUES (
+      NEW.id,
+      CASE WHEN TG_OP = 'UPDATE' THEN OLD.price END,
+      NEW.price,
+      NULLIF(current_setting('app.actor_id', true), '')::uuid
+    );
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_products_price_history ON products;
+CREATE TRIGGER trg_products_price_history
+AFTER INSERT OR UPDATE OF price ON products
+FOR EACH ROW EXECUTE FUNCTION products_price_history();
+
+INSERT INTO price_history (product_id, old_price, new_price)
+SELECT id, NULL, price FROM products;
+
+CREATE TABLE IF NOT EXISTS price_schedules (
+  id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id uuid NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  price      bigint NOT NULL CHECK (price >= 0),
+  starts_at  timestamptz NOT NULL,
+  ends_at    timestamptz,
+  created_by uuid,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT chk_price_schedules_range CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_schedules_product_starts_at
+  ON price_schedules (product_id, starts_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_products_rating;
+
+ALTER TABLE products
+  DROP COLUMN IF EXISTS rating_count,
+  DROP COLUMN IF EXISTS rating_avg;
+
+DROP TABLE IF EXISTS product_reviews;
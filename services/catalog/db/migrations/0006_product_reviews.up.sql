@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS product_reviews (
+  id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id      uuid NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  user_id         uuid NOT NULL,
+  rating          smallint NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  title           text NOT NULL DEFAULT '',
+  body            text NOT NULL DEFAULT '',
+  status          text NOT NULL DEFAULT 'pending'
+                  CHECK (status IN ('pending', 'approved', 'rejected')),
+  moderation_note text NOT NULL DEFAULT '',
+  moderated_by    uuid,
+  moderated_at    timestamptz,
+  created_at      timestamptz NOT NULL DEFAULT now(),
+  updated_at      timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT ux_product_reviews_product_user UNIQUE (product_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_reviews_product_status_created
+  ON product_reviews (product_id, status, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_product_reviews_status_created
+  ON product_reviews (status, created_at);
+
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS rating_avg   double precision NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS rating_count integer NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_products_rating
+  ON products (rating_avg DESC, id DESC)
+  WHERE deleted_at IS NULL;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS product_bought_together;
+
+DROP INDEX IF EXISTS idx_products_categories;
+
+ALTER TABLE products
+  DROP COLUMN IF EXISTS categories;
@@ -0,0 +1,13 @@
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS categories text[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_products_categories
+  ON products USING gin (categories);
+
+CREATE TABLE IF NOT EXISTS product_bought_together (
+  product_id uuid NOT NULL,
+  related_id uuid NOT NULL,
+  orders     integer NOT NULL CHECK (orders > 0),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (product_id, related_id)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_price_schedules_starts_at;
+DROP INDEX IF EXISTS idx_price_schedules_ends_at;
+DROP INDEX IF EXISTS idx_products_unpublish_at;
+DROP INDEX IF EXISTS idx_products_publish_at;
+DROP TABLE IF EXISTS catalog_job_state;
@@ -0,0 +1,18 @@
+-- Background jobs record how far they got, so a restart picks up where the
+-- previous run stopped.
+CREATE TABLE IF NOT EXISTS catalog_job_state (
+  name      text PRIMARY KEY,
+  ran_until timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_products_publish_at
+  ON products(publish_at) WHERE publish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_unpublish_at
+  ON products(unpublish_at) WHERE unpublish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_price_schedules_ends_at
+  ON price_schedules(ends_at) WHERE ends_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_price_schedules_starts_at
+  ON price_schedules(starts_at);
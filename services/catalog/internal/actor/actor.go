@@ -0,0 +1,20 @@
+package actor
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type ctxKey struct{}
+
+func IntoContext(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) *uuid.UUID {
+	if id, ok := ctx.Value(ctxKey{}).(uuid.UUID); ok {
+		return &id
+	}
+	return nil
+}
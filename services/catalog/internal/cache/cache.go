@@ -0,0 +1,19 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores serialized responses. Keys that depend on many rows (listings,
+// search pages) embed the current generation, so BumpGeneration invalidates
+// them all at once without tracking individual keys.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// GetMany returns the entries found among keys.
+	GetMany(ctx context.Context, keys ...string) (map[string][]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Generation(ctx context.Context) (int64, error)
+	BumpGeneration(ctx context.Context) error
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, c *LRU)
+	}{
+		{
+			name: "miss",
+			run: func(t *testing.T, c *LRU) {
+				_, ok, err := c.Get(ctx, "a")
+				require.NoError(t, err)
+				assert.False(t, ok)
+			},
+		},
+		{
+			name: "set and overwrite",
+			run: func(t *testing.T, c *LRU) {
+				require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+				require.NoError(t, c.Set(ctx, "a", []byte("2"), time.Minute))
+				v, ok, _ := c.Get(ctx, "a")
+				assert.True(t, ok)
+				assert.Equal(t, []byte("2"), v)
+				assert.Equal(t, 1, c.ll.Len())
+			},
+		},
+		{
+			name: "expired",
+			run: func(t *testing.T, c *LRU) {
+				require.NoError(t, c.Set(ctx, "a", []byte("1"), -time.Second))
+				_, ok, _ := c.Get(ctx, "a")
+				assert.False(t, ok)
+				assert.Empty(t, c.items, "expired entries are dropped on read")
+			},
+		},
+		{
+			name: "evicts least recently used",
+			run: func(t *testing.T, c *LRU) {
+				require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+				require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Minute))
+				_, _, _ = c.Get(ctx, "a")
+				require.NoError(t, c.Set(ctx, "c", []byte("3"), time.Minute))
+
+				_, ok, _ := c.Get(ctx, "b")
+				assert.False(t, ok, "b was the oldest")
+				_, ok, _ = c.Get(ctx, "a")
+				assert.True(t, ok)
+				_, ok, _ = c.Get(ctx, "c")
+				assert.True(t, ok)
+			},
+		},
+		{
+			name: "delete",
+			run: func(t *testing.T, c *LRU) {
+				require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+				require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Minute))
+				require.NoError(t, c.Delete(ctx, "a", "missing"))
+				_, ok, _ := c.Get(ctx, "a")
+				assert.False(t, ok)
+				_, ok, _ = c.Get(ctx, "b")
+				assert.True(t, ok)
+			},
+		},
+		{
+			name: "generation",
+			run: func(t *testing.T, c *LRU) {
+				gen, err := c.Generation(ctx)
+				require.NoError(t, err)
+				require.NoError(t, c.BumpGeneration(ctx))
+				next, _ := c.Generation(ctx)
+				assert.Equal(t, gen+1, next)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.run(t, NewLRU(2))
+		})
+	}
+}
@@ -1,17 +1,74 @@
 package config
 
-import "github.com/Skotchmaster/online_shop/pkg/config"
+import (
+	"os"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/pkg/config"
+)
 
 type ServiceConfig struct {
 	config.Config
+
+	OrderHTTPURL string
+
+	BoughtTogetherInterval   time.Duration
+	BoughtTogetherWindowDays int
+	BoughtTogetherMinOrders  int
+
+	ScheduleEventsInterval time.Duration
+
+	CacheBackend string
+	CacheSize    int
+	CacheTTL     time.Duration
+	RedisURL     string
+	HTTPMaxAge   int
 }
 
 func Load() ServiceConfig {
 	cfg := config.Load()
 
 	config.MustNonEmpty(cfg.DatabaseURL, "DATABASE_URL")
-	config.MustNonEmptyBytes(cfg.JWTAccessSecret, "JWT_SECRET")
 	config.MustNonEmpty(cfg.AuthHTTPURL, "AUTH_URL")
 
-	return ServiceConfig{Config: cfg}
+	orderURL := os.Getenv("ORDER_URL")
+	config.MustNonEmpty(orderURL, "ORDER_URL")
+
+	interval, err := time.ParseDuration(config.EnvDefault("BOUGHT_TOGETHER_INTERVAL", "1h"))
+	if err != nil || interval <= 0 {
+		interval = time.Hour
+	}
+
+	scheduleInterval, err := time.ParseDuration(config.EnvDefault("SCHEDULE_EVENTS_INTERVAL", "1m"))
+	if err != nil || scheduleInterval <= 0 {
+		scheduleInterval = time.Minute
+	}
+
+	cacheBackend := config.EnvDefault("CACHE_BACKEND", "memory")
+	redisURL := os.Getenv("REDIS_URL")
+	if cacheBackend == "redis" {
+		config.MustNonEmpty(redisURL, "REDIS_URL")
+	}
+
+	cacheTTL, err := time.ParseDuration(config.EnvDefault("CACHE_TTL", "60s"))
+	if err != nil || cacheTTL <= 0 {
+		cacheTTL = time.Minute
+	}
+
+	return ServiceConfig{
+		Config:       cfg,
+		OrderHTTPURL: orderURL,
+
+		BoughtTogetherInterval:   interval,
+		BoughtTogetherWindowDays: config.EnvIntDefault("BOUGHT_TOGETHER_WINDOW_DAYS", 90),
+		BoughtTogetherMinOrders:  config.EnvIntDefault("BOUGHT_TOGETHER_MIN_ORDERS", 2),
+
+		ScheduleEventsInterval: scheduleInterval,
+
+		CacheBackend: cacheBackend,
+		CacheSize:    config.EnvIntDefault("CACHE_SIZE", 10000),
+		CacheTTL:     cacheTTL,
+		RedisURL:     redisURL,
+		HTTPMaxAge:   config.EnvIntDefault("HTTP_CACHE_MAX_AGE", 30),
+	}
 }
@@ -0,0 +1,98 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/util"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/service"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *CatalogHTTP) ListProductsAdmin(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.list_products_admin")
+
+	page := util.ParseIntDefault(c.QueryParam("page"), 1)
+	size := util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize)
+	offset, limit := util.Calculate(page, size)
+
+	filter := transport.AdminProductFilter{
+		Status:  c.QueryParam("status"),
+		Deleted: c.QueryParam("deleted"),
+	}
+
+	total, items, err := h.Svc.ListProductsAdmin(ctx, filter, offset, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("list_products_admin_error", "status", 400, "reason", "invalid filter", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid filter")
+		}
+		l.Error("list_products_admin_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("list_products_admin_success")
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": items,
+		"meta": map[string]any{
+			"page":        page,
+			"size":        limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+			"has_prev":    page > 1,
+			"has_next":    int64(offset+limit) < total,
+		},
+	})
+}
+
+func (h *CatalogHTTP) GetProductAdmin(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.get_product_admin")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("get_product_admin_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	product, err := h.Svc.GetProductAdmin(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("get_product_admin_error", "status", 404, "reason", "product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "product not found")
+		}
+		l.Error("get_product_admin_error", "status", 500, "reason", "cannot get product", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "cannot get product")
+	}
+
+	c.Response().Header().Set("ETag", productETag(product))
+	return c.JSON(http.StatusOK, product)
+}
+
+func (h *CatalogHTTP) RestoreProduct(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "restore_product")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("product_restore_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	product, err := h.Svc.RestoreProduct(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("product_restore_error", "status", 404, "reason", "deleted product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "deleted product not found")
+		}
+		l.Error("product_restore_error", "status", 500, "reason", "cannot restore product", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "cannot restore product")
+	}
+
+	l.Info("restore_product_success", "product_id", id)
+	return c.JSON(http.StatusOK, product)
+}
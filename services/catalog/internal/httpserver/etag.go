@@ -0,0 +1,95 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// productETag is "<version>-<hash of the body>". The hash makes the tag change
+// when a scheduled price starts or ends or a review moves the rating, none of
+// which bumps the version.
+func productETag(p *models.Product) string {
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return `"` + strconv.FormatInt(p.Version, 10) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// parseIfMatch returns the product versions listed in an If-Match header. Only
+// the version part of a tag is compared, so a tag taken before a scheduled
+// price kicked in is still accepted. A nil result with ok=true means the header
+// is absent or "*". Weak and malformed tags never match, as If-Match requires
+// strong comparison.
+func parseIfMatch(header string) (versions []int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		v, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, len(versions) > 0
+}
+
+// matchIfNoneMatch reports whether etag is listed in an If-None-Match header.
+// The comparison is weak, so W/ prefixes are ignored.
+func matchIfNoneMatch(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheableJSON writes a public response with ETag and Cache-Control, or 304
+// when the client already holds it. Without an explicit etag the tag is a hash
+// of the body, which suits listings that have no version of their own.
+func (h *CatalogHTTP) cacheableJSON(c echo.Context, etag string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if etag == "" {
+		sum := sha256.Sum256(data)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	if h.MaxAge > 0 {
+		header.Set(echo.HeaderCacheControl, "public, max-age="+strconv.Itoa(h.MaxAge))
+	} else {
+		header.Set(echo.HeaderCacheControl, "no-cache")
+	}
+
+	if matchIfNoneMatch(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSONBlob(http.StatusOK, data)
+}
@@ -0,0 +1,104 @@
+package httpserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+)
+
+func TestProductETag(t *testing.T) {
+	t.Parallel()
+
+	base := models.Product{Name: "Lamp", Description: "Desk lamp", Price: 1999, CurrentPrice: 1999, Version: 3}
+	tag := productETag(&base)
+	assert.Equal(t, tag, productETag(&base), "stable for the same body")
+
+	versions, ok := parseIfMatch(tag)
+	require.True(t, ok)
+	assert.Equal(t, []int64{3}, versions, "If-Match still reads the version")
+
+	tests := []struct {
+		name   string
+		change func(p *models.Product)
+	}{
+		{name: "edit", change: func(p *models.Product) { p.Version++ }},
+		{name: "scheduled price", change: func(p *models.Product) { p.CurrentPrice = 1499 }},
+		{name: "review approved", change: func(p *models.Product) { p.RatingAvg, p.RatingCount = 4.5, 2 }},
+		{name: "review removed", change: func(p *models.Product) { p.RatingCount = 1 }},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			changed := base
+			tt.change(&changed)
+			assert.NotEqual(t, tag, productETag(&changed))
+			assert.False(t, matchIfNoneMatch(tag, productETag(&changed)), "a stale body must not get 304")
+		})
+	}
+}
+
+func TestMatchIfNoneMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		etag   string
+		want   bool
+	}{
+		{name: "absent", header: "", etag: `"a"`},
+		{name: "any", header: "*", etag: `"a"`, want: true},
+		{name: "same", header: `"a"`, etag: `"a"`, want: true},
+		{name: "weak header", header: `W/"a"`, etag: `"a"`, want: true},
+		{name: "weak etag", header: `"a"`, etag: `W/"a"`, want: true},
+		{name: "in list", header: `"x", "a"`, etag: `"a"`, want: true},
+		{name: "other", header: `"b"`, etag: `"a"`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, matchIfNoneMatch(tt.header, tt.etag))
+		})
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		want   []int64
+		wantOK bool
+	}{
+		{name: "absent", header: "", wantOK: true},
+		{name: "any", header: " * ", wantOK: true},
+		{name: "single", header: `"7-1999"`, want: []int64{7}, wantOK: true},
+		{name: "version only", header: `"7"`, want: []int64{7}, wantOK: true},
+		{name: "list", header: `"3-100", "4-90"`, want: []int64{3, 4}, wantOK: true},
+		{name: "weak never matches", header: `W/"7-1999"`},
+		{name: "unquoted", header: `7-1999`},
+		{name: "not a version", header: `"abc-1"`},
+		{name: "malformed skipped", header: `W/"1-1", "2-1"`, want: []int64{2}, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := parseIfMatch(tt.header)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package httpserver
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxImportBody    = 32 << 20
+	exportFlushEvery = 500
+	// gatewayPrefix is stripped by the gateway before requests reach us, so
+	// links handed to clients have to put it back.
+	gatewayPrefix = "/api/v1"
+)
+
+func (h *CatalogHTTP) ImportProducts(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.import_products")
+
+	format := importFormat(c)
+	if format == "" {
+		l.Warn("import_products_error", "status", 415, "reason", "unsupported format")
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "unsupported format, use csv or jsonl")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxImportBody+1))
+	if err != nil {
+		l.Warn("import_products_error", "status", 400, "reason", "cannot read body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "cannot read body")
+	}
+	if len(data) > maxImportBody {
+		l.Warn("import_products_error", "status", 413, "reason", "body too large")
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "import file is too large")
+	}
+
+	job, err := h.Svc.StartImport(ctx, format, data)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("import_products_error", "status", 400, "reason", "invalid body", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+		}
+		l.Error("import_products_error", "status", 500, "reason", "cannot start import", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "cannot start import")
+	}
+
+	l.Info("import_products_started", "import_id", job.ID)
+	c.Response().Header().Set(echo.HeaderLocation, gatewayPrefix+"/catalog/products/imports/"+job.ID.String())
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func (h *CatalogHTTP) GetImportJob(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.get_import_job")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("get_import_job_error", "status", 400, "reason", "invalid import id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid import id")
+	}
+
+	job, err := h.Svc.GetImportJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("get_import_job_error", "status", 404, "reason", "import job not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "import job not found")
+		}
+		l.Error("get_import_job_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+func (h *CatalogHTTP) ExportProducts(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.export_products")
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = service.ImportFormatCSV
+	}
+
+	res := c.Response()
+	var write func(models.Product) error
+	var flush func() error
+
+	switch format {
+	case service.ImportFormatCSV:
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="products.csv"`)
+		res.WriteHeader(http.StatusOK)
+
+		w := csv.NewWriter(res)
+		if err := w.Write([]string{"id", "sku", "name", "description", "price", "count"}); err != nil {
+			return err
+		}
+		write = func(p models.Product) error {
+			sku := ""
+			if p.SKU != nil {
+				sku = *p.SKU
+			}
+			return w.Write([]string{
+				p.ID.String(),
+				sku,
+				p.Name,
+				p.Description,
+				strconv.FormatInt(p.Price, 10),
+				strconv.FormatUint(uint64(p.Count), 10),
+			})
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	case service.ImportFormatJSONL:
+		res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+		res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="products.jsonl"`)
+		res.WriteHeader(http.StatusOK)
+
+		enc := json.NewEncoder(res)
+		write = func(p models.Product) error { return enc.Encode(p) }
+		flush = func() error { return nil }
+	default:
+		l.Warn("export_products_error", "status", 400, "reason", "unsupported format", "format", format)
+		return echo.NewHTTPError(http.StatusBadRequest, "unsupported format, use csv or jsonl")
+	}
+
+	n := 0
+	err := h.Svc.ExportProducts(ctx, func(p models.Product) error {
+		if err := write(p); err != nil {
+			return err
+		}
+		n++
+		if n%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			res.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated file.
+		l.Error("export_products_error", "reason", "export interrupted", "exported", n, "error", err)
+		return nil
+	}
+
+	res.Flush()
+	l.Info("export_products_success", "exported", n)
+	return nil
+}
+
+func importFormat(c echo.Context) string {
+	switch c.QueryParam("format") {
+	case service.ImportFormatCSV:
+		return service.ImportFormatCSV
+	case service.ImportFormatJSONL:
+		return service.ImportFormatJSONL
+	case "":
+	default:
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	switch mediaType {
+	case "text/csv":
+		return service.ImportFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return service.ImportFormatJSONL
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/util"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/service"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *CatalogHTTP) ListPriceHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.list_price_history")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("list_price_history_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	page := util.ParseIntDefault(c.QueryParam("page"), 1)
+	size := util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize)
+	offset, limit := util.Calculate(page, size)
+
+	total, items, err := h.Svc.ListPriceHistory(ctx, id, offset, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("list_price_history_error", "status", 404, "reason", "product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "product not found")
+		}
+		l.Error("list_price_history_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": items,
+		"meta": map[string]any{
+			"page":        page,
+			"size":        limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+			"has_prev":    page > 1,
+			"has_next":    int64(offset+limit) < total,
+		},
+	})
+}
+
+func (h *CatalogHTTP) ListPriceSchedules(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.list_price_schedules")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("list_price_schedules_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	items, err := h.Svc.ListPriceSchedules(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("list_price_schedules_error", "status", 404, "reason", "product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "product not found")
+		}
+		l.Error("list_price_schedules_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"data": items})
+}
+
+func (h *CatalogHTTP) CreatePriceSchedule(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.create_price_schedule")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("create_price_schedule_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	var req transport.CreatePriceScheduleRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("create_price_schedule_error", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	schedule, err := h.Svc.CreatePriceSchedule(ctx, id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			l.Warn("create_price_schedule_error", "status", 400, "reason", "invalid body", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+		case errors.Is(err, service.ErrNotFound):
+			l.Warn("create_price_schedule_error", "status", 404, "reason", "product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "product not found")
+		case errors.Is(err, service.ErrConflict):
+			l.Warn("create_price_schedule_error", "status", 409, "reason", "schedule overlaps", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "schedule overlaps an existing one")
+		}
+		l.Error("create_price_schedule_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("create_price_schedule_success", "product_id", id, "schedule_id", schedule.ID)
+	return c.JSON(http.StatusCreated, schedule)
+}
+
+func (h *CatalogHTTP) DeletePriceSchedule(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.delete_price_schedule")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("delete_price_schedule_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+	scheduleID, err := uuid.Parse(c.Param("schedule_id"))
+	if err != nil {
+		l.Warn("delete_price_schedule_error", "status", 400, "reason", "invalid schedule id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid schedule id")
+	}
+
+	if err := h.Svc.DeletePriceSchedule(ctx, id, scheduleID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("delete_price_schedule_error", "status", 404, "reason", "price schedule not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "price schedule not found")
+		}
+		l.Error("delete_price_schedule_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("delete_price_schedule_success", "product_id", id, "schedule_id", scheduleID)
+	return c.NoContent(http.StatusNoContent)
+}
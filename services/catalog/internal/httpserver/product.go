@@ -3,6 +3,7 @@ package httpserver
 import (
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/Skotchmaster/online_shop/pkg/logging"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/service"
@@ -13,6 +14,8 @@ import (
 )
 type CatalogHTTP struct {
 	Svc *service.CatalogService
+	// MaxAge is the Cache-Control max-age, in seconds, of public reads.
+	MaxAge int
 }
 
 func (h *CatalogHTTP) GetProduct(c echo.Context) error {
@@ -37,26 +40,84 @@ func (h *CatalogHTTP) GetProduct(c echo.Context) error {
 		}
 	}
 
-	return c.JSON(http.StatusOK, product)
+	return h.cacheableJSON(c, productETag(product), product)
+}
+
+// GetProductsBatch serves ?ids=a,b,c for clients that need several products at
+// once, e.g. the cart service.
+func (h *CatalogHTTP) GetProductsBatch(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.get_products_batch")
+
+	var ids []uuid.UUID
+	for _, raw := range strings.Split(c.QueryParam("ids"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			l.Warn("get_products_batch_error", "status", 400, "reason", "invalid product id", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+		}
+		ids = append(ids, id)
+	}
+
+	items, err := h.Svc.GetProductsBatch(ctx, ids)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("get_products_batch_error", "status", 400, "reason", "too many ids", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "too many ids")
+		}
+		l.Error("get_products_batch_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"data": items})
 }
 
 func (h *CatalogHTTP) GetProducts(c echo.Context) error {
 	ctx := c.Request().Context()
 	l := logging.FromContext(ctx).With("handler", "product.get_products")
 
+	if c.QueryParams().Has("cursor") {
+		limit := util.CursorLimit(util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize))
+		withTotal := util.ParseBoolDefault(c.QueryParam("with_total"), false)
+
+		page, err := h.Svc.ListProductsCursor(ctx, c.QueryParam("cursor"), c.QueryParam("sort"), limit, withTotal)
+		if err != nil {
+			if errors.Is(err, service.ErrValidation) {
+				l.Warn("get_products_error", "status", 400, "reason", "invalid cursor or sort", "error", err)
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor or sort")
+			}
+			l.Error("get_products_error", "status", 500, "reason", "internal error", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+
+		l.Info("get_products_success")
+		return h.cacheableJSON(c, "", map[string]any{
+			"data": page.Items,
+			"meta": page.Meta(limit),
+		})
+	}
+
 	page := util.ParseIntDefault(c.QueryParam("page"), 1)
 	size := util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize)
 
 	offset, limit := util.Calculate(page,size)
 
-	total, items, err := h.Svc.GetProducts(ctx, offset, limit)
+	total, items, err := h.Svc.GetProducts(ctx, c.QueryParam("sort"), offset, limit)
 	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("get_products_error", "status", 400, "reason", "invalid sort", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid sort")
+		}
 		l.Error("get_products_error", "status", 500, "reason", "internal error", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
 	}
 
 	l.Info("get_products_success")
-	return c.JSON(http.StatusOK, map[string]any{
+	return h.cacheableJSON(c, "", map[string]any{
 		"data": items,
 		"meta": map[string]any{
 			"page":        page,
@@ -86,6 +147,10 @@ func (h *CatalogHTTP) CreateProduct(c echo.Context) error {
 			l.Warn("product_create_error", "status", 400, "reason", "invalid body", "error", err)
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
 		}
+		if errors.Is(err, service.ErrConflict) {
+			l.Warn("product_create_error", "status", 409, "reason", "sku already exists", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "sku already exists")
+		}
 		l.Error("product_create_error", "status", 500, "reason", "cannot add product to db", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "cannot add product to db")
 	}
@@ -105,6 +170,12 @@ func (h *CatalogHTTP) PatchProduct(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
 	}
 
+	versions, ok := parseIfMatch(c.Request().Header.Get("If-Match"))
+	if !ok {
+		l.Warn("product_patch_error", "status", 412, "reason", "unusable If-Match")
+		return echo.NewHTTPError(http.StatusPreconditionFailed, "product was modified")
+	}
+
 	var req transport.PatchProductRequest
 
 	if err := c.Bind(&req); err != nil {
@@ -112,12 +183,28 @@ func (h *CatalogHTTP) PatchProduct(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
 	}
 
-	prod, err := h.Svc.PatchProduct(ctx, req, id)
+	prod, err := h.Svc.PatchProduct(ctx, req, id, versions)
 	if err != nil {
+		if errors.Is(err, service.ErrPreconditionFailed) {
+			l.Warn("product_patch_error", "status", 412, "reason", "version mismatch", "error", err)
+			return echo.NewHTTPError(http.StatusPreconditionFailed, "product was modified")
+		}
+		if errors.Is(err, service.ErrInsufficientStock) {
+			l.Warn("product_patch_error", "status", 409, "reason", "insufficient stock", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "insufficient stock")
+		}
+		if errors.Is(err, service.ErrConcurrentUpdate) {
+			l.Warn("product_patch_error", "status", 409, "reason", "concurrent update", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "product was modified concurrently, retry")
+		}
 		if errors.Is(err, service.ErrNotFound){
 			l.Warn("product_patch_error", "status", 404, "reason", "product not found", "error", err)
 			return echo.NewHTTPError(http.StatusNotFound, "product not found")
 		}
+		if errors.Is(err, service.ErrConflict) {
+			l.Warn("product_patch_error", "status", 409, "reason", "sku already exists", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "sku already exists")
+		}
 		if errors.Is(err,  service.ErrValidation){
 			l.Warn("product_patch_error", "status", 400, "reason", "invalid body", "error", err)
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
@@ -128,6 +215,7 @@ func (h *CatalogHTTP) PatchProduct(c echo.Context) error {
 	}
 
 	l.Info("patch_product_success")
+	c.Response().Header().Set("ETag", productETag(prod))
 	return c.JSON(http.StatusOK, prod)
 }
 
@@ -159,6 +247,31 @@ func (h *CatalogHTTP) SearchProducts(c echo.Context) error {
 	l := logging.FromContext(ctx).With("handler", "product.search_products")
 
 	q := c.QueryParam("q")
+
+	if c.QueryParams().Has("cursor") {
+		limit := util.CursorLimit(util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize))
+		withTotal := util.ParseBoolDefault(c.QueryParam("with_total"), false)
+
+		page, err := h.Svc.SearchProductsCursor(ctx, q, c.QueryParam("cursor"), limit, withTotal)
+		if err != nil {
+			if errors.Is(err, service.ErrValidation) {
+				l.Warn("search_products_error", "status", 400, "reason", "invalid cursor", "error", err)
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+			}
+			l.Error("search_products_error", "status", 500, "reason", "internal error", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+
+		meta := page.Meta(limit)
+		meta["query"] = q
+
+		l.Info("search_products_success")
+		return h.cacheableJSON(c, "", map[string]any{
+			"data": page.Items,
+			"meta": meta,
+		})
+	}
+
 	page := util.ParseIntDefault(c.QueryParam("page"), 1)
 	size := util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize)
 	offset, limit := util.Calculate(page, size)
@@ -170,7 +283,7 @@ func (h *CatalogHTTP) SearchProducts(c echo.Context) error {
 	}
 
 	l.Info("search_products_success")
-	return c.JSON(http.StatusOK, map[string]any{
+	return h.cacheableJSON(c, "", map[string]any{
 		"data": items,
 		"meta": map[string]any{
 			"query":       q,
@@ -0,0 +1,58 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/util"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *CatalogHTTP) GetRelatedProducts(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.get_related_products")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("get_related_products_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	items, err := h.Svc.RelatedProducts(ctx, id, util.ParseIntDefault(c.QueryParam("limit"), 10))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("get_related_products_error", "status", 404, "reason", "product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "product not found")
+		}
+		l.Error("get_related_products_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"data": items})
+}
+
+func (h *CatalogHTTP) GetBoughtTogether(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "product.get_bought_together")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("get_bought_together_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	items, err := h.Svc.BoughtTogether(ctx, id, util.ParseIntDefault(c.QueryParam("limit"), 10))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("get_bought_together_error", "status", 404, "reason", "product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "product not found")
+		}
+		l.Error("get_bought_together_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"data": items})
+}
@@ -0,0 +1,269 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/util"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/service"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func userID(c echo.Context) (uuid.UUID, error) {
+	s, ok := c.Get("user_id").(string)
+	if !ok || s == "" {
+		return uuid.Nil, errors.New("unauthorized")
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, errors.New("unauthorized")
+	}
+	return id, nil
+}
+
+func (h *CatalogHTTP) ListProductReviews(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "review.list_product_reviews")
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("list_product_reviews_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	page := util.ParseIntDefault(c.QueryParam("page"), 1)
+	size := util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize)
+	offset, limit := util.Calculate(page, size)
+
+	total, items, err := h.Svc.ListProductReviews(ctx, productID, offset, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("list_product_reviews_error", "status", 404, "reason", "product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "product not found")
+		}
+		l.Error("list_product_reviews_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": items,
+		"meta": map[string]any{
+			"page":        page,
+			"size":        limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+			"has_prev":    page > 1,
+			"has_next":    int64(offset+limit) < total,
+		},
+	})
+}
+
+func (h *CatalogHTTP) CreateReview(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "review.create_review")
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("create_review_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	uid, err := userID(c)
+	if err != nil {
+		l.Warn("create_review_error", "status", 401, "reason", "unauthorized", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var req transport.ReviewRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("create_review_error", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	token, _ := c.Get("access_token").(string)
+	review, err := h.Svc.CreateReview(ctx, productID, uid, token, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			l.Warn("create_review_error", "status", 400, "reason", "invalid body", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+		case errors.Is(err, service.ErrNotFound):
+			l.Warn("create_review_error", "status", 404, "reason", "product not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "product not found")
+		case errors.Is(err, service.ErrForbidden):
+			l.Warn("create_review_error", "status", 403, "reason", "product not purchased", "error", err)
+			return echo.NewHTTPError(http.StatusForbidden, "only buyers can review this product")
+		case errors.Is(err, service.ErrConflict):
+			l.Warn("create_review_error", "status", 409, "reason", "review already exists", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, "review already exists")
+		case errors.Is(err, service.ErrUnavailable):
+			l.Error("create_review_error", "status", 503, "reason", "cannot verify purchase", "error", err)
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "cannot verify purchase")
+		}
+		l.Error("create_review_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("create_review_success", "product_id", productID, "review_id", review.ID)
+	return c.JSON(http.StatusCreated, review)
+}
+
+func (h *CatalogHTTP) UpdateMyReview(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "review.update_my_review")
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("update_review_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	uid, err := userID(c)
+	if err != nil {
+		l.Warn("update_review_error", "status", 401, "reason", "unauthorized", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var req transport.ReviewRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("update_review_error", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	review, err := h.Svc.UpdateMyReview(ctx, productID, uid, req)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("update_review_error", "status", 400, "reason", "invalid body", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("update_review_error", "status", 404, "reason", "review not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "review not found")
+		}
+		l.Error("update_review_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("update_review_success", "product_id", productID, "review_id", review.ID)
+	return c.JSON(http.StatusOK, review)
+}
+
+func (h *CatalogHTTP) DeleteMyReview(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "review.delete_my_review")
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("delete_review_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	uid, err := userID(c)
+	if err != nil {
+		l.Warn("delete_review_error", "status", 401, "reason", "unauthorized", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err := h.Svc.DeleteMyReview(ctx, productID, uid); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("delete_review_error", "status", 404, "reason", "review not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "review not found")
+		}
+		l.Error("delete_review_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("delete_review_success", "product_id", productID)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *CatalogHTTP) ListReviewsAdmin(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "review.list_reviews_admin")
+
+	page := util.ParseIntDefault(c.QueryParam("page"), 1)
+	size := util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize)
+	offset, limit := util.Calculate(page, size)
+
+	total, items, err := h.Svc.ListReviewsAdmin(ctx, c.QueryParam("status"), offset, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("list_reviews_admin_error", "status", 400, "reason", "invalid filter", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid filter")
+		}
+		l.Error("list_reviews_admin_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": items,
+		"meta": map[string]any{
+			"page":        page,
+			"size":        limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+			"has_prev":    page > 1,
+			"has_next":    int64(offset+limit) < total,
+		},
+	})
+}
+
+func (h *CatalogHTTP) ModerateReview(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "review.moderate_review")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("moderate_review_error", "status", 400, "reason", "invalid review id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid review id")
+	}
+
+	var req transport.ModerateReviewRequest
+	if err := c.Bind(&req); err != nil {
+		l.Warn("moderate_review_error", "status", 400, "reason", "invalid body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	review, err := h.Svc.ModerateReview(ctx, id, req)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("moderate_review_error", "status", 400, "reason", "invalid body", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("moderate_review_error", "status", 404, "reason", "review not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "review not found")
+		}
+		l.Error("moderate_review_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("moderate_review_success", "review_id", id, "review_status", review.Status)
+	return c.JSON(http.StatusOK, review)
+}
+
+func (h *CatalogHTTP) DeleteReview(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "review.delete_review")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		l.Warn("delete_review_error", "status", 400, "reason", "invalid review id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid review id")
+	}
+
+	if err := h.Svc.DeleteReview(ctx, id); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			l.Warn("delete_review_error", "status", 404, "reason", "review not found", "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, "review not found")
+		}
+		l.Error("delete_review_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("delete_review_success", "review_id", id)
+	return c.NoContent(http.StatusNoContent)
+}
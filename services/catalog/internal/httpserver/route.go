@@ -5,12 +5,16 @@ import (
 
 	"github.com/Skotchmaster/online_shop/pkg/authclient"
 	middleware "github.com/Skotchmaster/online_shop/pkg/middleware/auth"
+	"github.com/Skotchmaster/online_shop/pkg/permissions"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/actor"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
 type Deps struct {
 	CatalogHandler *CatalogHTTP
-	JWTSecret      []byte
+	Verifier       *tokens.Verifier
 	AuthClient     *authclient.Client
 }
 
@@ -18,15 +22,54 @@ func Register(e *echo.Echo, d *Deps) {
 	e.GET("/health/live", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
 	e.GET("/health/ready", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
 
-	authMW := middleware.NewAutoRefreshMiddleware(d.JWTSecret, d.AuthClient)
+	authMW := middleware.NewAutoRefreshMiddleware(d.Verifier, d.AuthClient)
 
 	products := e.Group("/catalog/products")
 	products.GET("/search", d.CatalogHandler.SearchProducts)
+	products.GET("/batch", d.CatalogHandler.GetProductsBatch)
 	products.GET("", d.CatalogHandler.GetProducts)
 	products.GET("/:id", d.CatalogHandler.GetProduct)
+	products.GET("/:id/reviews", d.CatalogHandler.ListProductReviews)
+	products.GET("/:id/related", d.CatalogHandler.GetRelatedProducts)
+	products.GET("/:id/bought-together", d.CatalogHandler.GetBoughtTogether)
 
-	admin := products.Group("", authMW.RequireAdmin)
+	reviews := products.Group("/:id/reviews", authMW.RequireAuth)
+	reviews.POST("", d.CatalogHandler.CreateReview)
+	reviews.PATCH("", d.CatalogHandler.UpdateMyReview)
+	reviews.DELETE("", d.CatalogHandler.DeleteMyReview)
+
+	admin := products.Group("", authMW.RequirePermission(permissions.CatalogWrite), withActor)
 	admin.POST("", d.CatalogHandler.CreateProduct)
+	admin.POST("/import", d.CatalogHandler.ImportProducts)
+	admin.GET("/imports/:id", d.CatalogHandler.GetImportJob)
+	admin.GET("/export", d.CatalogHandler.ExportProducts)
 	admin.PATCH("/:id", d.CatalogHandler.PatchProduct)
 	admin.DELETE("/:id", d.CatalogHandler.DeleteProduct)
+	admin.POST("/:id/restore", d.CatalogHandler.RestoreProduct)
+	admin.GET("/:id/prices", d.CatalogHandler.ListPriceHistory)
+	admin.GET("/:id/price-schedules", d.CatalogHandler.ListPriceSchedules)
+	admin.POST("/:id/price-schedules", d.CatalogHandler.CreatePriceSchedule)
+	admin.DELETE("/:id/price-schedules/:schedule_id", d.CatalogHandler.DeletePriceSchedule)
+
+	adminProducts := e.Group("/catalog/admin/products", authMW.RequirePermission(permissions.CatalogWrite), withActor)
+	adminProducts.GET("", d.CatalogHandler.ListProductsAdmin)
+	adminProducts.GET("/:id", d.CatalogHandler.GetProductAdmin)
+
+	adminReviews := e.Group("/catalog/admin/reviews", authMW.RequirePermission(permissions.ReviewsModerate), withActor)
+	adminReviews.GET("", d.CatalogHandler.ListReviewsAdmin)
+	adminReviews.POST("/:id/moderate", d.CatalogHandler.ModerateReview)
+	adminReviews.DELETE("/:id", d.CatalogHandler.DeleteReview)
+}
+
+// withActor puts the authenticated staff member into the request context, so writes
+// can record who made them.
+func withActor(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, _ := c.Get("user_id").(string)
+		if id, err := uuid.Parse(userID); err == nil {
+			req := c.Request()
+			c.SetRequest(req.WithContext(actor.IntoContext(req.Context(), id)))
+		}
+		return next(c)
+	}
 }
@@ -1,22 +1,312 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+type ProductStatus string
+
+const (
+	ProductStatusDraft    ProductStatus = "draft"
+	ProductStatusActive   ProductStatus = "active"
+	ProductStatusArchived ProductStatus = "archived"
+)
+
+func (s ProductStatus) Valid() bool {
+	switch s {
+	case ProductStatusDraft, ProductStatusActive, ProductStatusArchived:
+		return true
+	}
+	return false
+}
+
 type Product struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `gorm:"not null" json:"description"`
-	Price       int64     `gorm:"not null" json:"price"`
-	Count       uint      `json:"count"`
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	SKU         *string        `gorm:"type:text;uniqueIndex" json:"sku,omitempty"`
+	Name        string         `gorm:"not null" json:"name"`
+	Description string         `gorm:"not null" json:"description"`
+	Price       int64          `gorm:"not null" json:"price"`
+	Count       uint           `json:"count"`
+	Status      ProductStatus  `gorm:"type:text;not null;default:active" json:"status"`
+	PublishAt   *time.Time     `gorm:"type:timestamptz" json:"publish_at,omitempty"`
+	UnpublishAt *time.Time     `gorm:"type:timestamptz" json:"unpublish_at,omitempty"`
+	DeletedAt   gorm.DeletedAt `gorm:"type:timestamptz;index" json:"deleted_at"`
+	Version     int64          `gorm:"not null;default:1" json:"version"`
+	RatingAvg   float64        `gorm:"not null;default:0" json:"rating_avg"`
+	RatingCount int            `gorm:"not null;default:0" json:"rating_count"`
+	Categories  StringArray    `gorm:"type:text[];not null;default:'{}'" json:"categories"`
+
+	CurrentPrice   int64  `gorm:"-" json:"current_price"`
+	CompareAtPrice *int64 `gorm:"-" json:"compare_at_price,omitempty"`
 }
 
 func (p *Product) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == uuid.Nil {
 		p.ID = uuid.New()
 	}
+	if p.Status == "" {
+		p.Status = ProductStatusActive
+	}
+
+	return nil
+}
+
+// StringArray maps a Postgres text[] column.
+type StringArray []string
+
+func (a StringArray) Value() (driver.Value, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, s := range a {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('"')
+		for _, r := range s {
+			if r == '"' || r == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+func (a *StringArray) Scan(src any) error {
+	var literal string
+	switch v := src.(type) {
+	case nil:
+		*a = StringArray{}
+		return nil
+	case string:
+		literal = v
+	case []byte:
+		literal = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for text array", src)
+	}
 
+	items, err := parseTextArray(literal)
+	if err != nil {
+		return err
+	}
+	*a = items
+	return nil
+}
+
+// parseTextArray reads the text form of a one-dimensional array, e.g.
+// {a,"b c","d\"e"}.
+func parseTextArray(literal string) ([]string, error) {
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return nil, fmt.Errorf("invalid text array %q", literal)
+	}
+	body := literal[1 : len(literal)-1]
+	items := []string{}
+	if body == "" {
+		return items, nil
+	}
+
+	for i := 0; ; i++ {
+		if i < len(body) && body[i] == '"' {
+			var elem strings.Builder
+			for i++; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				elem.WriteByte(body[i])
+			}
+			if i >= len(body) {
+				return nil, fmt.Errorf("invalid text array %q", literal)
+			}
+			items = append(items, elem.String())
+			i++
+		} else {
+			end := strings.IndexByte(body[i:], ',')
+			if end < 0 {
+				end = len(body) - i
+			}
+			items = append(items, body[i:i+end])
+			i += end
+		}
+
+		if i >= len(body) {
+			return items, nil
+		}
+		if body[i] != ',' {
+			return nil, fmt.Errorf("invalid text array %q", literal)
+		}
+	}
+}
+
+// ApplyPrice fills the computed price fields from the schedule active right
+// now, if any. The base price is shown as compare-at only during a sale, i.e.
+// a cheaper schedule with an end date.
+func (p *Product) ApplyPrice(active *PriceSchedule) {
+	p.CurrentPrice = p.Price
+	p.CompareAtPrice = nil
+	if active == nil {
+		return
+	}
+
+	p.CurrentPrice = active.Price
+	if active.EndsAt != nil && active.Price < p.Price {
+		base := p.Price
+		p.CompareAtPrice = &base
+	}
+}
+
+type PriceHistory struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	ProductID uuid.UUID  `gorm:"type:uuid;not null" json:"product_id"`
+	OldPrice  *int64     `json:"old_price"`
+	NewPrice  int64      `gorm:"not null" json:"new_price"`
+	ChangedBy *uuid.UUID `gorm:"type:uuid" json:"changed_by,omitempty"`
+	ChangedAt time.Time  `gorm:"type:timestamptz;not null" json:"changed_at"`
+}
+
+func (PriceHistory) TableName() string {
+	return "price_history"
+}
+
+type PriceSchedule struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	ProductID uuid.UUID  `gorm:"type:uuid;not null" json:"product_id"`
+	Price     int64      `gorm:"not null" json:"price"`
+	StartsAt  time.Time  `gorm:"type:timestamptz;not null" json:"starts_at"`
+	EndsAt    *time.Time `gorm:"type:timestamptz" json:"ends_at,omitempty"`
+	CreatedBy *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt time.Time  `gorm:"type:timestamptz;not null" json:"created_at"`
+}
+
+func (ps *PriceSchedule) BeforeCreate(tx *gorm.DB) error {
+	if ps.ID == uuid.Nil {
+		ps.ID = uuid.New()
+	}
+	return nil
+}
+
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "pending"
+	ReviewStatusApproved ReviewStatus = "approved"
+	ReviewStatusRejected ReviewStatus = "rejected"
+)
+
+type Review struct {
+	ID             uuid.UUID    `gorm:"type:uuid;primaryKey" json:"id"`
+	ProductID      uuid.UUID    `gorm:"type:uuid;not null" json:"product_id"`
+	UserID         uuid.UUID    `gorm:"type:uuid;not null" json:"user_id"`
+	Rating         int          `gorm:"not null" json:"rating"`
+	Title          string       `gorm:"type:text;not null;default:''" json:"title"`
+	Body           string       `gorm:"type:text;not null;default:''" json:"body"`
+	Status         ReviewStatus `gorm:"type:text;not null" json:"status"`
+	ModerationNote string       `gorm:"type:text;not null;default:''" json:"moderation_note,omitempty"`
+	ModeratedBy    *uuid.UUID   `gorm:"type:uuid" json:"moderated_by,omitempty"`
+	ModeratedAt    *time.Time   `gorm:"type:timestamptz" json:"moderated_at,omitempty"`
+	CreatedAt      time.Time    `gorm:"type:timestamptz;not null" json:"created_at"`
+	UpdatedAt      time.Time    `gorm:"type:timestamptz;not null" json:"updated_at"`
+}
+
+func (Review) TableName() string {
+	return "product_reviews"
+}
+
+func (r *Review) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	if r.Status == "" {
+		r.Status = ReviewStatusPending
+	}
+	return nil
+}
+
+type BoughtTogether struct {
+	ProductID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	RelatedID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Orders    int64     `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"type:timestamptz;not null"`
+}
+
+func (BoughtTogether) TableName() string {
+	return "product_bought_together"
+}
+
+type ImportStatus string
+
+const (
+	ImportStatusPending ImportStatus = "PENDING"
+	ImportStatusRunning ImportStatus = "RUNNING"
+	ImportStatusDone    ImportStatus = "DONE"
+	ImportStatusFailed  ImportStatus = "FAILED"
+)
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportRowErrors []ImportRowError
+
+func (e ImportRowErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (e *ImportRowErrors) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return fmt.Errorf("unsupported type %T for import errors", src)
+	}
+}
+
+type ImportJob struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	Format        string          `gorm:"type:text;not null" json:"format"`
+	Status        ImportStatus    `gorm:"type:text;not null" json:"status"`
+	TotalRows     int             `gorm:"not null;default:0" json:"total_rows"`
+	ProcessedRows int             `gorm:"not null;default:0" json:"processed_rows"`
+	CreatedRows   int             `gorm:"not null;default:0" json:"created_rows"`
+	UpdatedRows   int             `gorm:"not null;default:0" json:"updated_rows"`
+	FailedRows    int             `gorm:"not null;default:0" json:"failed_rows"`
+	Errors        ImportRowErrors `gorm:"type:jsonb;not null" json:"errors"`
+	Message       string          `gorm:"type:text;not null;default:''" json:"message,omitempty"`
+	CreatedBy     *uuid.UUID      `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt     time.Time       `gorm:"type:timestamptz;not null" json:"created_at"`
+	StartedAt     *time.Time      `gorm:"type:timestamptz" json:"started_at,omitempty"`
+	FinishedAt    *time.Time      `gorm:"type:timestamptz" json:"finished_at,omitempty"`
+}
+
+func (j *ImportJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	if j.Status == "" {
+		j.Status = ImportStatusPending
+	}
 	return nil
 }
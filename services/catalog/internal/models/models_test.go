@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestProduct_DeletedAtJSON(t *testing.T) {
+	t.Parallel()
+
+	deleted := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		deletedAt gorm.DeletedAt
+		want      any
+	}{
+		{name: "live", want: nil},
+		{name: "deleted", deletedAt: gorm.DeletedAt{Time: deleted, Valid: true}, want: "2025-05-01T10:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := json.Marshal(Product{DeletedAt: tt.deletedAt})
+			require.NoError(t, err)
+
+			var out map[string]any
+			require.NoError(t, json.Unmarshal(data, &out))
+			require.Contains(t, out, "deleted_at")
+			assert.Equal(t, tt.want, out["deleted_at"])
+		})
+	}
+}
+
+func TestParseTextArray(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		literal string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", literal: "{}", want: []string{}},
+		{name: "plain", literal: "{books,toys}", want: []string{"books", "toys"}},
+		{name: "quoted", literal: `{"home garden",tools}`, want: []string{"home garden", "tools"}},
+		{name: "escaped", literal: `{"say \"hi\"","a\\b"}`, want: []string{`say "hi"`, `a\b`}},
+		{name: "quoted comma", literal: `{"a,b",c}`, want: []string{"a,b", "c"}},
+		{name: "no braces", literal: "books,toys", wantErr: true},
+		{name: "unterminated quote", literal: `{"books}`, wantErr: true},
+		{name: "junk after quote", literal: `{"a"b}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseTextArray(tt.literal)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStringArray_Scan(t *testing.T) {
+	t.Parallel()
+
+	var a StringArray
+	require.NoError(t, a.Scan([]byte("{x,y}")))
+	assert.Equal(t, StringArray{"x", "y"}, a)
+
+	require.NoError(t, a.Scan(nil))
+	assert.Empty(t, a)
+
+	assert.Error(t, a.Scan(42))
+
+	in := StringArray{"plain", "with space", `quote"and\slash`, ""}
+	v, err := in.Value()
+	require.NoError(t, err)
+	require.NoError(t, a.Scan(v))
+	assert.Equal(t, in, a, "Value and Scan round trip")
+}
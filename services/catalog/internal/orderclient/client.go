@@ -0,0 +1,122 @@
+package orderclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/pkg/middleware/servicetoken"
+	"github.com/google/uuid"
+)
+
+type Client struct {
+	baseURL       string
+	internalToken string
+	httpClient    *http.Client
+}
+
+func NewClient(orderServiceURL, internalToken string) *Client {
+	return &Client{
+		baseURL:       orderServiceURL,
+		internalToken: internalToken,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+type purchasedResponse struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Purchased bool      `json:"purchased"`
+}
+
+// HasPurchased asks the order service whether the owner of accessToken has a
+// paid order containing the product.
+func (c *Client) HasPurchased(ctx context.Context, accessToken string, productID uuid.UUID) (bool, error) {
+	purchasedURL, err := url.JoinPath(c.baseURL, "orders", "purchased", productID.String())
+	if err != nil {
+		return false, fmt.Errorf("build purchased url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, purchasedURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	req.AddCookie(&http.Cookie{
+		Name:  "accessToken",
+		Value: accessToken,
+	})
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("purchased check failed with status: %d", resp.StatusCode)
+	}
+
+	var result purchasedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("decode response: %w", err)
+	}
+
+	return result.Purchased, nil
+}
+
+type CoPurchase struct {
+	ProductID uuid.UUID `json:"product_id"`
+	RelatedID uuid.UUID `json:"related_id"`
+	Orders    int64     `json:"orders"`
+}
+
+type coPurchasesResponse struct {
+	Data []CoPurchase `json:"data"`
+}
+
+// CoPurchases reads product pairs that appear in the same paid orders. It uses
+// the internal API, so the client must be created with an internal token.
+func (c *Client) CoPurchases(ctx context.Context, windowDays, minOrders, perProduct int) ([]CoPurchase, error) {
+	coURL, err := url.JoinPath(c.baseURL, "internal", "orders", "co-purchases")
+	if err != nil {
+		return nil, fmt.Errorf("build co-purchases url: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("window_days", strconv.Itoa(windowDays))
+	query.Set("min_orders", strconv.Itoa(minOrders))
+	query.Set("per_product", strconv.Itoa(perProduct))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, coURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set(servicetoken.HeaderName, c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("co-purchases failed with status: %d", resp.StatusCode)
+	}
+
+	var result coPurchasesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	return result.Data, nil
+}
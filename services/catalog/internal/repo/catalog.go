@@ -2,7 +2,10 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"slices"
 
+	"github.com/Skotchmaster/online_shop/pkg/util"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
 	"github.com/google/uuid"
@@ -10,22 +13,121 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+const (
+	SearchModeFTS  = "fts"
+	SearchModeTrgm = "trgm"
+
+	ftsQuerySQL  = `(websearch_to_tsquery('russian', unaccent(?)) || websearch_to_tsquery('english', unaccent(?)))`
+	ftsWhereSQL  = "search_vector @@ " + ftsQuerySQL
+	ftsScoreSQL  = "ts_rank_cd(search_vector, " + ftsQuerySQL + ")::float8"
+	trgmWhereSQL = "name % ? OR description % ?"
+	trgmScoreSQL = "GREATEST(similarity(name, ?), similarity(description, ?))::float8"
+
+	visibleSQL = `products.deleted_at IS NULL AND products.status = 'active'` +
+		` AND (products.publish_at IS NULL OR products.publish_at <= now())` +
+		` AND (products.unpublish_at IS NULL OR products.unpublish_at > now())`
+
+	SortRating = "rating"
+
+	DeletedExclude = "exclude"
+	DeletedInclude = "include"
+	DeletedOnly    = "only"
+)
+
+var (
+	ErrVersionMismatch   = errors.New("version mismatch")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrInvalidSchedule is returned when a patch would leave unpublish_at
+	// at or before publish_at.
+	ErrInvalidSchedule  = errors.New("invalid publish window")
+	ErrConcurrentUpdate = errors.New("concurrent update")
+)
+
+// visible limits a query to products the storefront may show.
+func visible(db *gorm.DB) *gorm.DB {
+	return db.Where(visibleSQL)
+}
+
 func (r *GormRepo) GetProduct(ctx context.Context, id uuid.UUID) (*models.Product, error) {
 	product := models.Product{}
-	if err := r.DB.WithContext(ctx).Where("ID=?", id).First(&product).Error; err != nil {
+	if err := r.DB.WithContext(ctx).Scopes(visible).Where("ID=?", id).First(&product).Error; err != nil {
 		return nil, err
 	}
 	return &product, nil
 }
 
-func(r *GormRepo) GetProducts(ctx context.Context, offset, limit int) (int64, *[]models.Product, error) {
+func (r *GormRepo) GetProductsByIDs(ctx context.Context, ids []uuid.UUID) ([]models.Product, error) {
+	var items []models.Product
+	if err := r.DB.WithContext(ctx).Scopes(visible).Where("id IN ?", ids).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ProductState is a product, deleted or not, with its storefront visibility.
+type ProductState struct {
+	models.Product `gorm:"embedded"`
+	Visible        bool
+}
+
+func (r *GormRepo) GetProductStates(ctx context.Context, ids []uuid.UUID) ([]ProductState, error) {
+	var items []ProductState
+	err := r.DB.WithContext(ctx).Unscoped().
+		Model(&models.Product{}).
+		Select("products.*, ("+visibleSQL+") AS visible").
+		Where("id IN ?", ids).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *GormRepo) GetProductUnscoped(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	product := models.Product{}
+	if err := r.DB.WithContext(ctx).Unscoped().Where("id = ?", id).First(&product).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *GormRepo) ListProductsAdmin(ctx context.Context, filter transport.AdminProductFilter, offset, limit int) (int64, []models.Product, error) {
+	q := r.DB.WithContext(ctx).Model(&models.Product{})
+	switch filter.Deleted {
+	case DeletedInclude:
+		q = q.Unscoped()
+	case DeletedOnly:
+		q = q.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+
+	items := make([]models.Product, 0, limit)
+	if err := q.Order("id ASC").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, items, nil
+}
+
+func(r *GormRepo) GetProducts(ctx context.Context, sort string, offset, limit int) (int64, *[]models.Product, error) {
 	var total int64
-	if err := r.DB.WithContext(ctx).Model(models.Product{}).Count(&total).Error; err != nil{
+	if err := r.DB.WithContext(ctx).Model(models.Product{}).Scopes(visible).Count(&total).Error; err != nil{
 		return 0, nil, err
 	}
 
+	order := "id ASC"
+	if sort == SortRating {
+		order = "rating_avg DESC, id DESC"
+	}
+
 	var items []models.Product
-	if err := r.DB.WithContext(ctx).Model(&models.Product{}).Order("id ASC").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+	if err := r.DB.WithContext(ctx).Model(&models.Product{}).Scopes(visible).Order(order).Offset(offset).Limit(limit).Find(&items).Error; err != nil {
 		return 0, nil, err
 	}
 
@@ -33,35 +135,99 @@ func(r *GormRepo) GetProducts(ctx context.Context, offset, limit int) (int64, *[
 }
 
 func(r *GormRepo) CreateProduct(ctx context.Context, prod *models.Product) (*models.Product, error) {
-	if err := r.DB.WithContext(ctx).Create(prod).Error; err != nil {
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := setActor(ctx, tx); err != nil {
+			return err
+		}
+		return tx.Create(prod).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return prod, nil
 }
 
-func(r *GormRepo) PatchProduct(ctx context.Context, req transport.PatchProductRequest, id uuid.UUID) (*models.Product, error) {
-	var prod models.Product
-	if err := r.DB.WithContext(ctx).First(&prod, id).Error; err != nil {
-		return nil, err
+// PatchProduct applies the patch in a single UPDATE guarded by the expected
+// versions (any version when empty), so concurrent edits cannot overwrite
+// each other and count_delta never drops stock below zero.
+func(r *GormRepo) PatchProduct(ctx context.Context, req transport.PatchProductRequest, id uuid.UUID, versions []int64) (*models.Product, error) {
+	updates := map[string]any{
+		"version": gorm.Expr("version + 1"),
+	}
+	if req.SKU != nil {
+		updates["sku"] = *req.SKU
 	}
-
 	if req.Name != nil {
-		prod.Name = *req.Name
+		updates["name"] = *req.Name
 	}
 	if req.Description != nil {
-		prod.Description = *req.Description
+		updates["description"] = *req.Description
 	}
 	if req.Price != nil {
-		prod.Price = *req.Price
+		updates["price"] = *req.Price
 	}
 	if req.Count != nil {
-		prod.Count = *req.Count
+		updates["count"] = *req.Count
+	}
+	if req.CountDelta != nil {
+		updates["count"] = gorm.Expr("count + ?", *req.CountDelta)
+	}
+	if req.Categories != nil {
+		updates["categories"] = models.StringArray(*req.Categories)
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
 	}
+	if req.PublishAt.Set {
+		updates["publish_at"] = req.PublishAt.Value
+	}
+	if req.UnpublishAt.Set {
+		updates["unpublish_at"] = req.UnpublishAt.Value
+	}
+
+	var prod models.Product
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := setActor(ctx, tx); err != nil {
+			return err
+		}
+
+		q := tx.Model(&prod).Clauses(clause.Returning{}).Where("id = ?", id)
+		if len(versions) > 0 {
+			q = q.Where("version IN ?", versions)
+		}
+		if req.CountDelta != nil && *req.CountDelta < 0 {
+			q = q.Where("count >= ?", -*req.CountDelta)
+		}
+
+		res := q.Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			// Only one end of the window may have been sent, so the check has
+			// to see the stored other end.
+			if prod.PublishAt != nil && prod.UnpublishAt != nil && !prod.UnpublishAt.After(*prod.PublishAt) {
+				return ErrInvalidSchedule
+			}
+			return nil
+		}
 
-	if err := r.DB.WithContext(ctx).Save(&prod).Error; err != nil {
+		var current models.Product
+		if err := tx.Where("id = ?", id).First(&current).Error; err != nil {
+			return err
+		}
+		if len(versions) > 0 && !slices.Contains(versions, current.Version) {
+			return ErrVersionMismatch
+		}
+		if req.CountDelta != nil && *req.CountDelta < 0 {
+			return ErrInsufficientStock
+		}
+		// The row changed between the update and the read above.
+		return ErrConcurrentUpdate
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return &prod, nil
 }
 
@@ -80,14 +246,26 @@ func(r *GormRepo) DeleteProduct(ctx context.Context, id uuid.UUID) error {
 
 }
 
-func (r *GormRepo) SearchProducts(ctx context.Context, q string, offset, limit int) (int64, *[]models.Product, error) {
-	ftsQuerySQL := `(websearch_to_tsquery('russian', unaccent(?)) || websearch_to_tsquery('english', unaccent(?)))`
-	ftsWhere := "search_vector @@ " + ftsQuerySQL
+func (r *GormRepo) RestoreProduct(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	res := r.DB.WithContext(ctx).Unscoped().
+		Model(&models.Product{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.GetProductUnscoped(ctx, id)
+}
 
+func (r *GormRepo) SearchProducts(ctx context.Context, q string, offset, limit int) (int64, *[]models.Product, error) {
 	var totalFTS int64
 	if err := r.DB.WithContext(ctx).
 		Model(&models.Product{}).
-		Where(ftsWhere, q, q).
+		Scopes(visible).
+		Where(ftsWhereSQL, q, q).
 		Count(&totalFTS).Error; err != nil {
 		return 0, nil, err
 	}
@@ -101,7 +279,8 @@ func (r *GormRepo) SearchProducts(ctx context.Context, q string, offset, limit i
 
 		if err := r.DB.WithContext(ctx).
 			Model(&models.Product{}).
-			Where(ftsWhere, q, q).
+			Scopes(visible).
+			Where(ftsWhereSQL, q, q).
 			Order(orderExpr).
 			Limit(limit).
 			Offset(offset).
@@ -115,7 +294,8 @@ func (r *GormRepo) SearchProducts(ctx context.Context, q string, offset, limit i
 	var totalTrgm int64
 	if err := r.DB.WithContext(ctx).
 		Model(&models.Product{}).
-		Where("name % ? OR description % ?", q, q).
+		Scopes(visible).
+		Where(trgmWhereSQL, q, q).
 		Count(&totalTrgm).Error; err != nil {
 		return 0, nil, err
 	}
@@ -123,7 +303,8 @@ func (r *GormRepo) SearchProducts(ctx context.Context, q string, offset, limit i
 	items := make([]models.Product, 0, limit)
 	if err := r.DB.WithContext(ctx).
 		Model(&models.Product{}).
-		Where("name % ? OR description % ?", q, q).
+		Scopes(visible).
+		Where(trgmWhereSQL, q, q).
 		Order(clause.Expr{
 			SQL:  "GREATEST(similarity(name, ?), similarity(description, ?)) DESC",
 			Vars: []any{q, q},
@@ -135,4 +316,111 @@ func (r *GormRepo) SearchProducts(ctx context.Context, q string, offset, limit i
 	}
 
 	return totalTrgm, &items, nil
-}
\ No newline at end of file
+}
+
+type ScoredProduct struct {
+	models.Product
+	Score float64 `gorm:"column:score"`
+}
+
+func (r *GormRepo) ListProductsAfter(ctx context.Context, cur *util.Cursor, sort string, limit int) ([]models.Product, error) {
+	q := r.DB.WithContext(ctx).Model(&models.Product{}).Scopes(visible)
+
+	if sort == SortRating {
+		switch {
+		case cur.Backward():
+			q = q.Where("(rating_avg, id) > (?, ?)", *cur.Score, cur.ID).Order("rating_avg ASC, id ASC")
+		case cur != nil:
+			q = q.Where("(rating_avg, id) < (?, ?)", *cur.Score, cur.ID).Order("rating_avg DESC, id DESC")
+		default:
+			q = q.Order("rating_avg DESC, id DESC")
+		}
+	} else {
+		switch {
+		case cur.Backward():
+			q = q.Where("id < ?", cur.ID).Order("id DESC")
+		case cur != nil:
+			q = q.Where("id > ?", cur.ID).Order("id ASC")
+		default:
+			q = q.Order("id ASC")
+		}
+	}
+
+	items := make([]models.Product, 0, limit+1)
+	if err := q.Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *GormRepo) CountProducts(ctx context.Context) (int64, error) {
+	var total int64
+	if err := r.DB.WithContext(ctx).Model(&models.Product{}).Scopes(visible).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (r *GormRepo) EstimateProducts(ctx context.Context) (int64, error) {
+	var estimate int64
+	if err := r.DB.WithContext(ctx).
+		Raw("SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = 'products'::regclass").
+		Scan(&estimate).Error; err != nil {
+		return 0, err
+	}
+	return estimate, nil
+}
+
+func (r *GormRepo) SearchMode(ctx context.Context, q string) (string, error) {
+	var found bool
+	if err := r.DB.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM products WHERE "+visibleSQL+" AND "+ftsWhereSQL+")", q, q).
+		Scan(&found).Error; err != nil {
+		return "", err
+	}
+	if found {
+		return SearchModeFTS, nil
+	}
+	return SearchModeTrgm, nil
+}
+
+func (r *GormRepo) SearchProductsAfter(ctx context.Context, q, mode string, cur *util.Cursor, limit int) ([]ScoredProduct, error) {
+	whereSQL, scoreSQL := ftsWhereSQL, ftsScoreSQL
+	if mode == SearchModeTrgm {
+		whereSQL, scoreSQL = trgmWhereSQL, trgmScoreSQL
+	}
+
+	tx := r.DB.WithContext(ctx).
+		Model(&models.Product{}).
+		Select("products.*, "+scoreSQL+" AS score", q, q).
+		Scopes(visible).
+		Where(whereSQL, q, q)
+
+	switch {
+	case cur.Backward():
+		tx = tx.Where("("+scoreSQL+", id) > (?, ?)", q, q, *cur.Score, cur.ID).Order("score ASC, id ASC")
+	case cur != nil:
+		tx = tx.Where("("+scoreSQL+", id) < (?, ?)", q, q, *cur.Score, cur.ID).Order("score DESC, id DESC")
+	default:
+		tx = tx.Order("score DESC, id DESC")
+	}
+
+	items := make([]ScoredProduct, 0, limit+1)
+	if err := tx.Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *GormRepo) CountSearch(ctx context.Context, q, mode string) (int64, error) {
+	whereSQL := ftsWhereSQL
+	if mode == SearchModeTrgm {
+		whereSQL = trgmWhereSQL
+	}
+
+	var total int64
+	if err := r.DB.WithContext(ctx).Model(&models.Product{}).Scopes(visible).Where(whereSQL, q, q).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
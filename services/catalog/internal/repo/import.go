@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const upsertProductSQL = `
+INSERT INTO products (id, sku, name, description, price, count)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (sku) DO UPDATE SET
+  name        = EXCLUDED.name,
+  description = EXCLUDED.description,
+  price       = EXCLUDED.price,
+  count       = EXCLUDED.count,
+  version     = products.version + 1
+WHERE products.deleted_at IS NULL
+RETURNING id, (xmax = 0) AS created`
+
+// ErrProductDeleted rejects an import row whose SKU belongs to a deleted
+// product: the import does not bring it back, POST /:id/restore does.
+var ErrProductDeleted = errors.New("product with this sku is deleted, restore it first")
+
+type UpsertResult struct {
+	ID      uuid.UUID
+	Created bool
+	Err     error
+}
+
+// UpsertProducts writes a batch in one transaction. Every row runs in its own
+// savepoint, so a row rejected by the database does not abort the batch.
+func (r *GormRepo) UpsertProducts(ctx context.Context, rows []transport.ProductImportRow) ([]UpsertResult, error) {
+	results := make([]UpsertResult, len(rows))
+
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := setActor(ctx, tx); err != nil {
+			return err
+		}
+		for i, row := range rows {
+			var res UpsertResult
+			err := tx.Transaction(func(sp *gorm.DB) error {
+				q := sp.Raw(upsertProductSQL, uuid.New(), row.SKU, row.Name, row.Description, row.Price, row.Count).
+					Scan(&res)
+				if q.Error == nil && q.RowsAffected == 0 {
+					return ErrProductDeleted
+				}
+				return q.Error
+			})
+			res.Err = err
+			results[i] = res
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *GormRepo) EachProduct(ctx context.Context, fn func(models.Product) error) error {
+	rows, err := r.DB.WithContext(ctx).Model(&models.Product{}).Order("id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var prod models.Product
+		if err := r.DB.ScanRows(rows, &prod); err != nil {
+			return err
+		}
+		if err := fn(prod); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *GormRepo) CreateImportJob(ctx context.Context, job *models.ImportJob) error {
+	return r.DB.WithContext(ctx).Create(job).Error
+}
+
+func (r *GormRepo) SaveImportJob(ctx context.Context, job *models.ImportJob) error {
+	return r.DB.WithContext(ctx).Save(job).Error
+}
+
+func (r *GormRepo) GetImportJob(ctx context.Context, id uuid.UUID) (*models.ImportJob, error) {
+	var job models.ImportJob
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
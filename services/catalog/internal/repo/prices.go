@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/actor"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrScheduleOverlap = errors.New("price schedule overlaps")
+
+// setActor exposes the acting user to the price history trigger for the rest
+// of the transaction.
+func setActor(ctx context.Context, tx *gorm.DB) error {
+	id := actor.FromContext(ctx)
+	if id == nil {
+		return nil
+	}
+	return tx.Exec("SELECT set_config('app.actor_id', ?, true)", id.String()).Error
+}
+
+func (r *GormRepo) ActivePriceSchedules(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.PriceSchedule, error) {
+	active := make(map[uuid.UUID]models.PriceSchedule, len(ids))
+	if len(ids) == 0 {
+		return active, nil
+	}
+
+	var rows []models.PriceSchedule
+	if err := r.DB.WithContext(ctx).Raw(`
+		SELECT DISTINCT ON (product_id) *
+		FROM price_schedules
+		WHERE product_id IN ? AND starts_at <= now() AND (ends_at IS NULL OR ends_at > now())
+		ORDER BY product_id, starts_at DESC`, ids).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		active[row.ProductID] = row
+	}
+	return active, nil
+}
+
+func (r *GormRepo) ListPriceHistory(ctx context.Context, productID uuid.UUID, offset, limit int) (int64, []models.PriceHistory, error) {
+	var total int64
+	if err := r.DB.WithContext(ctx).Model(&models.PriceHistory{}).Where("product_id = ?", productID).Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+
+	items := make([]models.PriceHistory, 0, limit)
+	if err := r.DB.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("changed_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&items).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, items, nil
+}
+
+func (r *GormRepo) ListPriceSchedules(ctx context.Context, productID uuid.UUID) ([]models.PriceSchedule, error) {
+	var items []models.PriceSchedule
+	if err := r.DB.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("starts_at ASC, id ASC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CreatePriceSchedule locks the product row, so two concurrent requests cannot
+// both pass the overlap check.
+func (r *GormRepo) CreatePriceSchedule(ctx context.Context, ps *models.PriceSchedule) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var prod models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", ps.ProductID).
+			First(&prod).Error; err != nil {
+			return err
+		}
+
+		var overlaps bool
+		if err := tx.Raw(`SELECT EXISTS (
+			SELECT 1 FROM price_schedules
+			WHERE product_id = ? AND tstzrange(starts_at, ends_at) && tstzrange(?::timestamptz, ?::timestamptz)
+		)`, ps.ProductID, ps.StartsAt, ps.EndsAt).Scan(&overlaps).Error; err != nil {
+			return err
+		}
+		if overlaps {
+			return ErrScheduleOverlap
+		}
+
+		ps.CreatedBy = actor.FromContext(ctx)
+		ps.CreatedAt = time.Now().UTC()
+		return tx.Create(ps).Error
+	})
+}
+
+func (r *GormRepo) DeletePriceSchedule(ctx context.Context, productID, scheduleID uuid.UUID) error {
+	res := r.DB.WithContext(ctx).
+		Where("id = ? AND product_id = ?", scheduleID, productID).
+		Delete(&models.PriceSchedule{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
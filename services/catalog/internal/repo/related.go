@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// boughtTogetherLockKey serializes refreshes between catalog replicas.
+const boughtTogetherLockKey = 7310032
+
+// relatedProductsSQL scores candidates by the number of shared categories plus
+// the text rank against an OR-query built from the target's own lexemes.
+const relatedProductsSQL = `
+WITH target AS (
+  SELECT t.categories,
+         (SELECT to_tsquery('simple', string_agg(quote_literal(l.lexeme), ' | '))
+          FROM (
+            SELECT lexeme FROM unnest(t.search_vector)
+            WHERE position(E'\\' IN lexeme) = 0
+            LIMIT 64
+          ) l) AS query
+  FROM products t
+  WHERE t.id = ?
+)
+SELECT products.*,
+  (cardinality(ARRAY(SELECT unnest(products.categories) INTERSECT SELECT unnest(target.categories)))
+   + COALESCE(ts_rank_cd(products.search_vector, target.query), 0))::float8 AS score
+FROM products, target
+WHERE products.id <> ? AND ` + visibleSQL + `
+  AND (products.categories && target.categories OR products.search_vector @@ target.query)
+ORDER BY score DESC, products.id
+LIMIT ?`
+
+func (r *GormRepo) RelatedProducts(ctx context.Context, id uuid.UUID, limit int) ([]ScoredProduct, error) {
+	items := make([]ScoredProduct, 0, limit)
+	if err := r.DB.WithContext(ctx).Raw(relatedProductsSQL, id, id, limit).Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *GormRepo) BoughtTogether(ctx context.Context, id uuid.UUID, limit int) ([]models.Product, error) {
+	items := make([]models.Product, 0, limit)
+	if err := r.DB.WithContext(ctx).
+		Model(&models.Product{}).
+		Joins("JOIN product_bought_together bt ON bt.related_id = products.id").
+		Where("bt.product_id = ?", id).
+		Scopes(visible).
+		Order("bt.orders DESC, products.id").
+		Limit(limit).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ReplaceBoughtTogether swaps the whole table in one transaction. It returns
+// false without writing when another replica holds the refresh lock.
+func (r *GormRepo) ReplaceBoughtTogether(ctx context.Context, rows []models.BoughtTogether) (bool, error) {
+	var locked bool
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", boughtTogetherLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		if err := tx.Exec("DELETE FROM product_bought_together").Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 1000).Error
+	})
+	return locked, err
+}
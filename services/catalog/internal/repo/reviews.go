@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/actor"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const recalcRatingSQL = `
+UPDATE products p SET
+  rating_avg   = COALESCE(s.avg, 0),
+  rating_count = s.cnt
+FROM (
+  SELECT round(avg(rating)::numeric, 2)::float8 AS avg, count(*) AS cnt
+  FROM product_reviews
+  WHERE product_id = ? AND status = 'approved'
+) s
+WHERE p.id = ?`
+
+// recalcRating refreshes the denormalized rating of a product from its approved
+// reviews. It runs in the same transaction as the review change.
+func recalcRating(tx *gorm.DB, productID uuid.UUID) error {
+	return tx.Exec(recalcRatingSQL, productID, productID).Error
+}
+
+func (r *GormRepo) CreateReview(ctx context.Context, review *models.Review) error {
+	return r.DB.WithContext(ctx).Create(review).Error
+}
+
+// UpdateUserReview edits the caller's review and sends it back to moderation.
+func (r *GormRepo) UpdateUserReview(ctx context.Context, productID, userID uuid.UUID, updates map[string]any) (*models.Review, error) {
+	updates["status"] = models.ReviewStatusPending
+	updates["moderation_note"] = ""
+	updates["moderated_by"] = nil
+	updates["moderated_at"] = nil
+	updates["updated_at"] = time.Now().UTC()
+
+	var review models.Review
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&review).
+			Clauses(clause.Returning{}).
+			Where("product_id = ? AND user_id = ?", productID, userID).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recalcRating(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *GormRepo) DeleteUserReview(ctx context.Context, productID, userID uuid.UUID) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("product_id = ? AND user_id = ?", productID, userID).Delete(&models.Review{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recalcRating(tx, productID)
+	})
+}
+
+func (r *GormRepo) DeleteReview(ctx context.Context, id uuid.UUID) (*models.Review, error) {
+	var review models.Review
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.Returning{}).Where("id = ?", id).Delete(&review)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recalcRating(tx, review.ProductID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *GormRepo) ModerateReview(ctx context.Context, id uuid.UUID, status models.ReviewStatus, note string) (*models.Review, error) {
+	now := time.Now().UTC()
+
+	var review models.Review
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&review).
+			Clauses(clause.Returning{}).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"status":          status,
+				"moderation_note": note,
+				"moderated_by":    actor.FromContext(ctx),
+				"moderated_at":    now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recalcRating(tx, review.ProductID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *GormRepo) ListProductReviews(ctx context.Context, productID uuid.UUID, offset, limit int) (int64, []models.Review, error) {
+	q := r.DB.WithContext(ctx).
+		Model(&models.Review{}).
+		Where("product_id = ? AND status = ?", productID, models.ReviewStatusApproved)
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+
+	items := make([]models.Review, 0, limit)
+	if err := q.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, items, nil
+}
+
+func (r *GormRepo) ListReviewsAdmin(ctx context.Context, status string, offset, limit int) (int64, []models.Review, error) {
+	q := r.DB.WithContext(ctx).Model(&models.Review{})
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+
+	items := make([]models.Review, 0, limit)
+	if err := q.Order("created_at ASC, id ASC").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, items, nil
+}
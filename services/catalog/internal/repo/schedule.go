@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// scheduleEventsLockKey serializes schedule runs between catalog replicas.
+	scheduleEventsLockKey = 7310033
+	scheduleEventsJob     = "schedule_events"
+)
+
+// RunScheduleTransitions passes fn the products whose publish window or price
+// schedule started or ended after the previous run and no later than until,
+// then records until as the start of the next run. A failing fn rolls the run
+// back so the next one sees the same products. It returns false without
+// calling fn when another replica holds the lock; the first run ever only
+// records until.
+func (r *GormRepo) RunScheduleTransitions(ctx context.Context, until time.Time, fn func(ids []uuid.UUID) error) (bool, error) {
+	var locked bool
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", scheduleEventsLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		var since []time.Time
+		if err := tx.Raw("SELECT ran_until FROM catalog_job_state WHERE name = ?", scheduleEventsJob).
+			Scan(&since).Error; err != nil {
+			return err
+		}
+
+		if len(since) > 0 && since[0].Before(until) {
+			var ids []uuid.UUID
+			err := tx.Raw(`
+				SELECT id FROM products
+				WHERE deleted_at IS NULL
+				  AND ((publish_at > @since AND publish_at <= @until)
+				    OR (unpublish_at > @since AND unpublish_at <= @until))
+				UNION
+				SELECT product_id FROM price_schedules
+				WHERE (starts_at > @since AND starts_at <= @until)
+				   OR (ends_at > @since AND ends_at <= @until)`,
+				map[string]any{"since": since[0], "until": until},
+			).Scan(&ids).Error
+			if err != nil {
+				return err
+			}
+			if len(ids) > 0 {
+				if err := fn(ids); err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Exec(`
+			INSERT INTO catalog_job_state (name, ran_until) VALUES (?, ?)
+			ON CONFLICT (name) DO UPDATE
+			SET ran_until = GREATEST(catalog_job_state.ran_until, EXCLUDED.ran_until)`,
+			scheduleEventsJob, until,
+		).Error
+	})
+	return locked, err
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/pkg/util"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/google/uuid"
+)
+
+// Public reads go through the cache. Product entries are deleted on writes;
+// listing and search entries are keyed by the cache generation, which every
+// write bumps. Time-based changes (publish windows, scheduled prices) are
+// invalidated by the schedule events job within its interval.
+
+type productPage struct {
+	Total int64            `json:"total"`
+	Items []models.Product `json:"items"`
+}
+
+func productCacheKey(id uuid.UUID) string {
+	return "product:" + id.String()
+}
+
+// listCacheKey returns "" when the generation is unknown, which disables
+// caching for the request rather than risking a stale key.
+func (s *CatalogService) listCacheKey(ctx context.Context, kind string, parts ...any) string {
+	if s.Cache == nil {
+		return ""
+	}
+	gen, err := s.Cache.Generation(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("cache_generation_failed", "error", err)
+		return ""
+	}
+	return fmt.Sprintf("%s:g%d:%s", kind, gen, fmt.Sprint(parts...))
+}
+
+func cachedLoad[T any](ctx context.Context, s *CatalogService, key string, load func() (T, error)) (T, error) {
+	if s.Cache == nil || key == "" {
+		return load()
+	}
+	l := logging.FromContext(ctx)
+
+	data, ok, err := s.Cache.Get(ctx, key)
+	if err != nil {
+		l.Warn("cache_get_failed", "key", key, "error", err)
+	}
+	if ok {
+		var v T
+		if err := json.Unmarshal(data, &v); err == nil {
+			return v, nil
+		}
+	}
+
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+
+	if data, err := json.Marshal(v); err == nil {
+		if err := s.Cache.Set(ctx, key, data, s.CacheTTL); err != nil {
+			l.Warn("cache_set_failed", "key", key, "error", err)
+		}
+	}
+	return v, nil
+}
+
+// cachedProducts splits ids into products found in the cache and ids that
+// have to be loaded.
+func (s *CatalogService) cachedProducts(ctx context.Context, ids []uuid.UUID) ([]models.Product, []uuid.UUID) {
+	if s.Cache == nil {
+		return nil, ids
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = productCacheKey(id)
+	}
+	found, err := s.Cache.GetMany(ctx, keys...)
+	if err != nil {
+		logging.FromContext(ctx).Warn("cache_get_failed", "keys", len(keys), "error", err)
+		return nil, ids
+	}
+
+	items := make([]models.Product, 0, len(found))
+	var missing []uuid.UUID
+	for i, id := range ids {
+		var p models.Product
+		if data, ok := found[keys[i]]; ok && json.Unmarshal(data, &p) == nil {
+			items = append(items, p)
+			continue
+		}
+		missing = append(missing, id)
+	}
+	return items, missing
+}
+
+// cacheProducts stores products under the keys GetProduct reads.
+func (s *CatalogService) cacheProducts(ctx context.Context, items []models.Product) {
+	if s.Cache == nil {
+		return
+	}
+	for i := range items {
+		data, err := json.Marshal(&items[i])
+		if err != nil {
+			continue
+		}
+		if err := s.Cache.Set(ctx, productCacheKey(items[i].ID), data, s.CacheTTL); err != nil {
+			logging.FromContext(ctx).Warn("cache_set_failed", "product_id", items[i].ID, "error", err)
+			return
+		}
+	}
+}
+
+// invalidate drops cached products and retires every cached listing.
+func (s *CatalogService) invalidate(ctx context.Context, ids ...uuid.UUID) {
+	if s.Cache == nil {
+		return
+	}
+	l := logging.FromContext(ctx)
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, productCacheKey(id))
+	}
+	if err := s.Cache.Delete(ctx, keys...); err != nil {
+		l.Warn("cache_delete_failed", "error", err)
+	}
+	if err := s.Cache.BumpGeneration(ctx); err != nil {
+		l.Warn("cache_bump_generation_failed", "error", err)
+	}
+}
+
+func (s *CatalogService) GetProduct(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	return cachedLoad(ctx, s, productCacheKey(id), func() (*models.Product, error) {
+		return s.getProduct(ctx, id)
+	})
+}
+
+func (s *CatalogService) GetProducts(ctx context.Context, sort string, offset, limit int) (int64, *[]models.Product, error) {
+	key := s.listCacheKey(ctx, "products", sort, "|", offset, "|", limit)
+	page, err := cachedLoad(ctx, s, key, func() (productPage, error) {
+		total, items, err := s.getProducts(ctx, sort, offset, limit)
+		if err != nil {
+			return productPage{}, err
+		}
+		return productPage{Total: total, Items: *items}, nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return page.Total, &page.Items, nil
+}
+
+func (s *CatalogService) SearchProducts(ctx context.Context, rawQ string, offset, limit int) (int64, *[]models.Product, error) {
+	key := s.listCacheKey(ctx, "search", offset, "|", limit, "|", rawQ)
+	page, err := cachedLoad(ctx, s, key, func() (productPage, error) {
+		total, items, err := s.searchProducts(ctx, rawQ, offset, limit)
+		if err != nil {
+			return productPage{}, err
+		}
+		return productPage{Total: total, Items: *items}, nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return page.Total, &page.Items, nil
+}
+
+func (s *CatalogService) ListProductsCursor(ctx context.Context, rawCursor, sort string, limit int, withTotal bool) (*util.CursorPage[models.Product], error) {
+	key := s.listCacheKey(ctx, "products_cursor", sort, "|", limit, "|", withTotal, "|", rawCursor)
+	return cachedLoad(ctx, s, key, func() (*util.CursorPage[models.Product], error) {
+		return s.listProductsCursor(ctx, rawCursor, sort, limit, withTotal)
+	})
+}
+
+func (s *CatalogService) SearchProductsCursor(ctx context.Context, rawQ, rawCursor string, limit int, withTotal bool) (*util.CursorPage[models.Product], error) {
+	key := s.listCacheKey(ctx, "search_cursor", limit, "|", withTotal, "|", rawCursor, "|", rawQ)
+	return cachedLoad(ctx, s, key, func() (*util.CursorPage[models.Product], error) {
+		return s.searchProductsCursor(ctx, rawQ, rawCursor, limit, withTotal)
+	})
+}
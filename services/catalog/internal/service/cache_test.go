@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/cache"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+)
+
+func TestCachedProducts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	svc := &CatalogService{Cache: cache.NewLRU(10), CacheTTL: time.Minute}
+
+	cached := []models.Product{
+		{ID: uuid.New(), Name: "Lamp", Price: 100, CurrentPrice: 80},
+		{ID: uuid.New(), Name: "Desk", Price: 500, CurrentPrice: 500},
+	}
+	svc.cacheProducts(ctx, cached)
+	other := uuid.New()
+
+	items, missing := svc.cachedProducts(ctx, []uuid.UUID{cached[0].ID, other, cached[1].ID})
+	assert.Equal(t, []uuid.UUID{other}, missing)
+	require.Len(t, items, 2)
+	assert.Equal(t, int64(80), items[0].CurrentPrice, "current price survives the cache")
+
+	single, err := svc.GetProduct(ctx, cached[1].ID)
+	require.NoError(t, err, "single reads see entries written by the batch")
+	assert.Equal(t, "Desk", single.Name)
+
+	svc.invalidate(ctx, cached[0].ID)
+	_, missing = svc.cachedProducts(ctx, []uuid.UUID{cached[0].ID})
+	assert.Equal(t, []uuid.UUID{cached[0].ID}, missing)
+}
+
+func TestGetProductsBatch_AllCached(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// No repository: a fully cached batch must not reach the database.
+	svc := &CatalogService{Cache: cache.NewLRU(10), CacheTTL: time.Minute}
+
+	p := models.Product{ID: uuid.New(), Name: "Mug"}
+	svc.cacheProducts(ctx, []models.Product{p})
+
+	items, err := svc.GetProductsBatch(ctx, []uuid.UUID{p.ID, p.ID})
+	require.NoError(t, err)
+	require.Len(t, items, 1, "duplicate ids are collapsed")
+	assert.Equal(t, p.ID, items[0].ID)
+
+	_, err = svc.GetProductsBatch(ctx, make([]uuid.UUID, maxBatchIDs+1))
+	assert.ErrorIs(t, err, ErrValidation)
+}
@@ -1,12 +1,19 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/Skotchmaster/online_shop/pkg/util"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/cache"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/orderclient"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/repo"
 	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
 	"github.com/google/uuid"
@@ -16,13 +23,35 @@ import (
 var(
 	ErrValidation = errors.New("validation")
 	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrConcurrentUpdate = errors.New("concurrent update")
+	ErrForbidden = errors.New("forbidden")
+	ErrUnavailable = errors.New("dependency unavailable")
 ) 
 
+const (
+	maxCategories  = 20
+	maxBatchIDs    = 100
+	maxCategoryLen = 64
+)
+
 type CatalogService struct {
-	Repo *repo.GormRepo
+	Repo     *repo.GormRepo
+	Orders   *orderclient.Client
+	Cache    cache.Cache
+	CacheTTL time.Duration
+	// Events receives product state changes; nil disables publishing.
+	Events EventPublisher
+	// JobsCtx is cancelled on shutdown and stops background work started by
+	// requests, such as imports. Nil means it is never cancelled.
+	JobsCtx context.Context
+
+	jobs sync.WaitGroup
 }
 
-func (s *CatalogService) GetProduct(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+func (s *CatalogService) getProduct(ctx context.Context, id uuid.UUID) (*models.Product, error) {
 	item, err := s.Repo.GetProduct(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -31,32 +60,158 @@ func (s *CatalogService) GetProduct(ctx context.Context, id uuid.UUID) (*models.
 			return nil, err
 		}
 	}
+	if err := s.applyPrice(ctx, item); err != nil {
+		return nil, err
+	}
 	return item, nil
 }
 
-func (s *CatalogService) GetProducts(ctx context.Context, offset, limit int) (int64, *[]models.Product, error) {
-	return s.Repo.GetProducts(ctx, offset, limit)
+// GetProductsBatch returns the visible products among ids, in no particular
+// order. Missing ids are simply absent from the result. Products are read
+// through the same cache entries as GetProduct, so the batch and a single read
+// never disagree for longer than a write takes to invalidate them.
+func (s *CatalogService) GetProductsBatch(ctx context.Context, ids []uuid.UUID) ([]models.Product, error) {
+	if len(ids) == 0 {
+		return []models.Product{}, nil
+	}
+	if len(ids) > maxBatchIDs {
+		return nil, fmt.Errorf("at most %d ids allowed: %w", maxBatchIDs, ErrValidation)
+	}
+	ids = slices.Clone(ids)
+	slices.SortFunc(ids, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	ids = slices.Compact(ids)
+
+	items, missing := s.cachedProducts(ctx, ids)
+	if len(missing) == 0 {
+		return items, nil
+	}
+
+	loaded, err := s.Repo.GetProductsByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPrices(ctx, loaded); err != nil {
+		return nil, err
+	}
+	s.cacheProducts(ctx, loaded)
+	return append(items, loaded...), nil
+}
+
+func (s *CatalogService) getProducts(ctx context.Context, sort string, offset, limit int) (int64, *[]models.Product, error) {
+	if err := validateSort(sort); err != nil {
+		return 0, nil, err
+	}
+
+	total, items, err := s.Repo.GetProducts(ctx, sort, offset, limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.applyPrices(ctx, *items); err != nil {
+		return 0, nil, err
+	}
+	return total, items, nil
 }
 
 func (s *CatalogService) CreateProduct(ctx context.Context, req transport.CreateProductRequest) (*models.Product, error) {
-    if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Description) == "" {
-        return nil, fmt.Errorf("name and description are required: %w", ErrValidation)
-    }
-    if req.Price < 0 {
-        return nil, fmt.Errorf("price must be >= 0: %w", ErrValidation)
-    }
+	if err := validateProduct(req.Name, req.Description, req.Price); err != nil {
+		return nil, err
+	}
+	status := models.ProductStatusActive
+	if req.Status != "" {
+		status = models.ProductStatus(req.Status)
+	}
+	if !status.Valid() {
+		return nil, fmt.Errorf("unknown status %q: %w", req.Status, ErrValidation)
+	}
+	if err := validateSchedule(req.PublishAt, req.UnpublishAt); err != nil {
+		return nil, err
+	}
+	categories, err := normalizeCategories(req.Categories)
+	if err != nil {
+		return nil, err
+	}
 
 	prod := models.Product{
+        SKU: normalizeSKU(req.SKU),
         Name: req.Name,
         Description: req.Description,
         Price: req.Price,
         Count: req.Count,
+        Categories: categories,
+        Status: status,
+        PublishAt: req.PublishAt,
+        UnpublishAt: req.UnpublishAt,
     }
 
-    return s.Repo.CreateProduct(ctx, &prod)
+	created, err := s.Repo.CreateProduct(ctx, &prod)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fmt.Errorf("sku already exists: %w", ErrConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, created.ID)
+	if err := s.applyPrice(ctx, created); err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
-func (s *CatalogService) PatchProduct(ctx context.Context, req transport.PatchProductRequest, id uuid.UUID) (*models.Product, error) {
+func validateProduct(name, description string, price int64) error {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(description) == "" {
+		return fmt.Errorf("name and description are required: %w", ErrValidation)
+	}
+	if price < 0 {
+		return fmt.Errorf("price must be >= 0: %w", ErrValidation)
+	}
+	return nil
+}
+
+func validateSort(sort string) error {
+	if sort != "" && sort != repo.SortRating {
+		return fmt.Errorf("unknown sort %q: %w", sort, ErrValidation)
+	}
+	return nil
+}
+
+func validateSchedule(publishAt, unpublishAt *time.Time) error {
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return fmt.Errorf("unpublish_at must be after publish_at: %w", ErrValidation)
+	}
+	return nil
+}
+
+// normalizeCategories lowercases and deduplicates category slugs.
+func normalizeCategories(raw []string) ([]string, error) {
+	if len(raw) > maxCategories {
+		return nil, fmt.Errorf("at most %d categories allowed: %w", maxCategories, ErrValidation)
+	}
+
+	out := make([]string, 0, len(raw))
+	for _, c := range raw {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" || len(c) > maxCategoryLen {
+			return nil, fmt.Errorf("invalid category %q: %w", c, ErrValidation)
+		}
+		if !slices.Contains(out, c) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func normalizeSKU(sku *string) *string {
+	if sku == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*sku)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func (s *CatalogService) PatchProduct(ctx context.Context, req transport.PatchProductRequest, id uuid.UUID, versions []int64) (*models.Product, error) {
 
     if req.Price != nil && *req.Price < 0 {
 		return nil, fmt.Errorf("price must be >= 0: %w", ErrValidation)
@@ -67,16 +222,62 @@ func (s *CatalogService) PatchProduct(ctx context.Context, req transport.PatchPr
 	if req.Description != nil && strings.TrimSpace(*req.Description) == "" {
 		return nil, fmt.Errorf("description cannot be empty: %w", ErrValidation)
 	}
-	if req.Name == nil && req.Description == nil && req.Price == nil && req.Count == nil {
+	if req.SKU != nil && strings.TrimSpace(*req.SKU) == "" {
+		return nil, fmt.Errorf("sku cannot be empty: %w", ErrValidation)
+	}
+	if req.Count != nil && req.CountDelta != nil {
+		return nil, fmt.Errorf("count and count_delta are mutually exclusive: %w", ErrValidation)
+	}
+	if req.Status != nil && !models.ProductStatus(*req.Status).Valid() {
+		return nil, fmt.Errorf("unknown status %q: %w", *req.Status, ErrValidation)
+	}
+	if req.PublishAt.Set && req.UnpublishAt.Set {
+		if err := validateSchedule(req.PublishAt.Value, req.UnpublishAt.Value); err != nil {
+			return nil, err
+		}
+	}
+	if req.SKU == nil && req.Name == nil && req.Description == nil && req.Price == nil && req.Count == nil &&
+		req.CountDelta == nil && req.Categories == nil && req.Status == nil && !req.PublishAt.Set && !req.UnpublishAt.Set {
 		return nil, fmt.Errorf("empty patch: %w", ErrValidation)
 	}
+	req.SKU = normalizeSKU(req.SKU)
+	if req.Categories != nil {
+		categories, err := normalizeCategories(*req.Categories)
+		if err != nil {
+			return nil, err
+		}
+		req.Categories = &categories
+	}
 
-    item, err := s.Repo.PatchProduct(ctx, req, id)
+    item, err := s.Repo.PatchProduct(ctx, req, id, versions)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrNotFound
 	}
+	if errors.Is(err, repo.ErrVersionMismatch) {
+		return nil, fmt.Errorf("product was modified: %w", ErrPreconditionFailed)
+	}
+	if errors.Is(err, repo.ErrInsufficientStock) {
+		return nil, fmt.Errorf("count_delta exceeds stock: %w", ErrInsufficientStock)
+	}
+	if errors.Is(err, repo.ErrConcurrentUpdate) {
+		return nil, fmt.Errorf("product changed during the update: %w", ErrConcurrentUpdate)
+	}
+	if errors.Is(err, repo.ErrInvalidSchedule) {
+		return nil, fmt.Errorf("unpublish_at must be after publish_at: %w", ErrValidation)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fmt.Errorf("sku already exists: %w", ErrConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, id)
+	s.publishProducts(ctx, id)
+	if err := s.applyPrice(ctx, item); err != nil {
+		return nil, err
+	}
 
-	return item, err
+	return item, nil
 }
 
 
@@ -85,10 +286,67 @@ func(s *CatalogService) DeleteProduct(ctx context.Context, id uuid.UUID) error {
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return ErrNotFound
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, id)
+	s.publishProducts(ctx, id)
+	return nil
+}
+
+func (s *CatalogService) RestoreProduct(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	item, err := s.Repo.RestoreProduct(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("deleted product not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, id)
+	s.publishProducts(ctx, id)
+	if err := s.applyPrice(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *CatalogService) GetProductAdmin(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	item, err := s.Repo.GetProductUnscoped(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("item not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPrice(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *CatalogService) ListProductsAdmin(ctx context.Context, filter transport.AdminProductFilter, offset, limit int) (int64, []models.Product, error) {
+	if filter.Status != "" && !models.ProductStatus(filter.Status).Valid() {
+		return 0, nil, fmt.Errorf("unknown status %q: %w", filter.Status, ErrValidation)
+	}
+	switch filter.Deleted {
+	case "":
+		filter.Deleted = repo.DeletedExclude
+	case repo.DeletedExclude, repo.DeletedInclude, repo.DeletedOnly:
+	default:
+		return 0, nil, fmt.Errorf("unknown deleted filter %q: %w", filter.Deleted, ErrValidation)
+	}
+
+	total, items, err := s.Repo.ListProductsAdmin(ctx, filter, offset, limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.applyPrices(ctx, items); err != nil {
+		return 0, nil, err
+	}
+	return total, items, nil
 }
 
-func (s *CatalogService) SearchProducts(ctx context.Context, rawQ string, offset, limit int) (int64, *[]models.Product, error) {
+func (s *CatalogService) searchProducts(ctx context.Context, rawQ string, offset, limit int) (int64, *[]models.Product, error) {
 	q := strings.TrimSpace(rawQ)
 	if q == "" {
 		return 0, &[]models.Product{},  nil
@@ -104,5 +362,111 @@ func (s *CatalogService) SearchProducts(ctx context.Context, rawQ string, offset
 		offset = 0
 	}
 
-	return s.Repo.SearchProducts(ctx, q, offset, limit)
+	total, items, err := s.Repo.SearchProducts(ctx, q, offset, limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := s.applyPrices(ctx, *items); err != nil {
+		return 0, nil, err
+	}
+	return total, items, nil
+}
+
+func (s *CatalogService) listProductsCursor(ctx context.Context, rawCursor, sort string, limit int, withTotal bool) (*util.CursorPage[models.Product], error) {
+	if err := validateSort(sort); err != nil {
+		return nil, err
+	}
+	cur, err := util.DecodeCursor(rawCursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if cur != nil && (cur.Mode != sort || (sort == repo.SortRating && cur.Score == nil)) {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, util.ErrInvalidCursor)
+	}
+
+	rows, err := s.Repo.ListProductsAfter(ctx, cur, sort, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := util.NewCursorPage(rows, limit, cur, func(p models.Product) util.Cursor {
+		if sort == repo.SortRating {
+			return util.Cursor{ID: p.ID, Score: &p.RatingAvg, Mode: sort}
+		}
+		return util.Cursor{ID: p.ID}
+	})
+	if err := s.applyPrices(ctx, page.Items); err != nil {
+		return nil, err
+	}
+
+	if withTotal {
+		total, err := s.Repo.CountProducts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	} else {
+		estimate, err := s.Repo.EstimateProducts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalEstimate = &estimate
+	}
+
+	return page, nil
+}
+
+func (s *CatalogService) searchProductsCursor(ctx context.Context, rawQ, rawCursor string, limit int, withTotal bool) (*util.CursorPage[models.Product], error) {
+	cur, err := util.DecodeCursor(rawCursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if cur != nil && (cur.Score == nil || (cur.Mode != repo.SearchModeFTS && cur.Mode != repo.SearchModeTrgm)) {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, util.ErrInvalidCursor)
+	}
+
+	q := strings.TrimSpace(rawQ)
+	if q == "" {
+		return &util.CursorPage[models.Product]{Items: []models.Product{}}, nil
+	}
+
+	mode := ""
+	if cur != nil {
+		mode = cur.Mode
+	} else if mode, err = s.Repo.SearchMode(ctx, q); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Repo.SearchProductsAfter(ctx, q, mode, cur, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	scored := util.NewCursorPage(rows, limit, cur, func(p repo.ScoredProduct) util.Cursor {
+		return util.Cursor{ID: p.ID, Score: &p.Score, Mode: mode}
+	})
+
+	page := &util.CursorPage[models.Product]{
+		Items:      make([]models.Product, 0, len(scored.Items)),
+		NextCursor: scored.NextCursor,
+		PrevCursor: scored.PrevCursor,
+		HasNext:    scored.HasNext,
+		HasPrev:    scored.HasPrev,
+	}
+	for _, row := range scored.Items {
+		page.Items = append(page.Items, row.Product)
+	}
+	if err := s.applyPrices(ctx, page.Items); err != nil {
+		return nil, err
+	}
+
+	if withTotal {
+		total, err := s.Repo.CountSearch(ctx, q, mode)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	return page, nil
 }
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/pkg/events"
+	"github.com/Skotchmaster/online_shop/pkg/logging"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/google/uuid"
+)
+
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, topic, key string, event any) error
+}
+
+// publishProducts emits the current state of the given products. It runs
+// synchronously, after the write that triggered it, so events for one
+// product reach its partition in the order of the writes; a failure is logged
+// and does not fail the write.
+func (s *CatalogService) publishProducts(ctx context.Context, ids ...uuid.UUID) {
+	if s.Events == nil || len(ids) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	if err := s.publishProductStates(ctx, ids); err != nil {
+		logging.FromContext(ctx).Error("product_events_failed", "products", len(ids), "error", err)
+	}
+}
+
+func (s *CatalogService) publishProductStates(ctx context.Context, ids []uuid.UUID) error {
+	states, err := s.Repo.GetProductStates(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	products := make([]models.Product, len(states))
+	for i, st := range states {
+		products[i] = st.Product
+	}
+	if err := s.applyPrices(ctx, products); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	found := make(map[uuid.UUID]struct{}, len(states))
+	for i, st := range states {
+		found[st.ID] = struct{}{}
+
+		event := events.ProductEvent{
+			EventID:    uuid.New(),
+			Type:       events.ProductUpdated,
+			ProductID:  st.ID,
+			Price:      products[i].CurrentPrice,
+			Count:      st.Count,
+			Available:  st.Visible,
+			OccurredAt: now,
+		}
+		if st.DeletedAt.Valid {
+			event.Type = events.ProductDeleted
+		}
+		if err := s.Events.PublishEvent(ctx, events.ProductsTopic, st.ID.String(), event); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range ids {
+		if _, ok := found[id]; ok {
+			continue
+		}
+		event := events.ProductEvent{
+			EventID:    uuid.New(),
+			Type:       events.ProductDeleted,
+			ProductID:  id,
+			OccurredAt: now,
+		}
+		if err := s.Events.PublishEvent(ctx, events.ProductsTopic, id.String(), event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleEventsBatch bounds how many products one publish call loads.
+const scheduleEventsBatch = 500
+
+// StartScheduleEvents watches publish windows and price schedules, checking
+// every interval until ctx is cancelled. Products whose window or schedule
+// started or ended since the last check are dropped from the cache and, when
+// Events is set, published like any other change.
+func (s *CatalogService) StartScheduleEvents(ctx context.Context, interval time.Duration) {
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+
+		l := slog.Default().With("job", "schedule_events")
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			var changed int
+			_, err := s.Repo.RunScheduleTransitions(ctx, time.Now().UTC(), func(ids []uuid.UUID) error {
+				changed = len(ids)
+				s.invalidate(ctx, ids...)
+				if s.Events == nil {
+					return nil
+				}
+				for batch := range slices.Chunk(ids, scheduleEventsBatch) {
+					if err := s.publishProductStates(ctx, batch); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			switch {
+			case err != nil && !errors.Is(err, context.Canceled):
+				l.Error("schedule_events_failed", "error", err)
+			case err == nil && changed > 0:
+				l.Info("schedule_events_published", "products", changed)
+			}
+		}
+	}()
+}
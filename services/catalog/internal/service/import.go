@@ -0,0 +1,322 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/actor"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+
+	importBatchSize = 500
+	maxImportErrors = 1000
+)
+
+var csvColumns = []string{"sku", "name", "description", "price", "count"}
+
+type parsedRow struct {
+	line int
+	row  transport.ProductImportRow
+	err  error
+}
+
+func (s *CatalogService) StartImport(ctx context.Context, format string, data []byte) (*models.ImportJob, error) {
+	if format != ImportFormatCSV && format != ImportFormatJSONL {
+		return nil, fmt.Errorf("unsupported import format %q: %w", format, ErrValidation)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("import body is empty: %w", ErrValidation)
+	}
+
+	job := &models.ImportJob{
+		Format:    format,
+		Status:    models.ImportStatusPending,
+		CreatedBy: actor.FromContext(ctx),
+	}
+	if err := s.Repo.CreateImportJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		jobCtx := s.JobsCtx
+		if jobCtx == nil {
+			jobCtx = context.Background()
+		}
+		if job.CreatedBy != nil {
+			jobCtx = actor.IntoContext(jobCtx, *job.CreatedBy)
+		}
+		s.runImport(jobCtx, job, data)
+	}()
+
+	return job, nil
+}
+
+func (s *CatalogService) GetImportJob(ctx context.Context, id uuid.UUID) (*models.ImportJob, error) {
+	job, err := s.Repo.GetImportJob(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("import job not found: %w", ErrNotFound)
+	}
+	return job, err
+}
+
+// Wait blocks until running background jobs finish or ctx is done.
+func (s *CatalogService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *CatalogService) runImport(ctx context.Context, job *models.ImportJob, data []byte) {
+	l := slog.Default().With("job", "product_import", "import_id", job.ID)
+
+	started := time.Now().UTC()
+	job.Status = models.ImportStatusRunning
+	job.StartedAt = &started
+	if err := s.Repo.SaveImportJob(ctx, job); err != nil {
+		l.Error("import_failed", "reason", "cannot update job", "error", err)
+		return
+	}
+
+	var rows []parsedRow
+	var err error
+	if job.Format == ImportFormatCSV {
+		rows, err = parseCSVImport(data)
+	} else {
+		rows, err = parseJSONLImport(data)
+	}
+	if err != nil {
+		s.finishImport(ctx, l, job, err)
+		return
+	}
+	job.TotalRows = len(rows)
+
+	for start := 0; start < len(rows); start += importBatchSize {
+		if err := ctx.Err(); err != nil {
+			s.finishImport(ctx, l, job, fmt.Errorf("import interrupted: %w", err))
+			return
+		}
+		end := min(start+importBatchSize, len(rows))
+		if err := s.importBatch(ctx, job, rows[start:end]); err != nil {
+			s.finishImport(ctx, l, job, err)
+			return
+		}
+		if err := s.Repo.SaveImportJob(ctx, job); err != nil {
+			l.Error("import_progress_failed", "reason", "cannot update job", "error", err)
+		}
+	}
+
+	s.finishImport(ctx, l, job, nil)
+}
+
+func (s *CatalogService) importBatch(ctx context.Context, job *models.ImportJob, rows []parsedRow) error {
+	valid := make([]transport.ProductImportRow, 0, len(rows))
+	lines := make([]int, 0, len(rows))
+
+	for _, r := range rows {
+		if r.err == nil {
+			r.err = validateImportRow(r.row)
+		}
+		if r.err != nil {
+			addRowError(job, r.line, r.row.SKU, r.err)
+			continue
+		}
+		valid = append(valid, r.row)
+		lines = append(lines, r.line)
+	}
+
+	if len(valid) > 0 {
+		results, err := s.Repo.UpsertProducts(ctx, valid)
+		if err != nil {
+			return err
+		}
+		changed := make([]uuid.UUID, 0, len(results))
+		for i, res := range results {
+			switch {
+			case res.Err != nil:
+				addRowError(job, lines[i], valid[i].SKU, res.Err)
+				continue
+			case res.Created:
+				job.CreatedRows++
+			default:
+				job.UpdatedRows++
+			}
+			changed = append(changed, res.ID)
+		}
+		if len(changed) > 0 {
+			s.invalidate(ctx, changed...)
+			s.publishProducts(ctx, changed...)
+		}
+	}
+
+	job.ProcessedRows += len(rows)
+	return nil
+}
+
+func (s *CatalogService) finishImport(ctx context.Context, l *slog.Logger, job *models.ImportJob, err error) {
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	job.Status = models.ImportStatusDone
+	if err != nil {
+		job.Status = models.ImportStatusFailed
+		job.Message = err.Error()
+	}
+
+	// The job is recorded as failed even when shutdown cancelled ctx.
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if saveErr := s.Repo.SaveImportJob(saveCtx, job); saveErr != nil {
+		l.Error("import_failed", "reason", "cannot update job", "error", saveErr)
+		return
+	}
+
+	if err != nil {
+		l.Error("import_failed", "processed", job.ProcessedRows, "error", err)
+		return
+	}
+	l.Info("import_finished", "total", job.TotalRows, "created", job.CreatedRows, "updated", job.UpdatedRows, "failed", job.FailedRows)
+}
+
+func addRowError(job *models.ImportJob, line int, sku string, err error) {
+	job.FailedRows++
+	if len(job.Errors) < maxImportErrors {
+		job.Errors = append(job.Errors, models.ImportRowError{Row: line, SKU: sku, Error: err.Error()})
+	}
+}
+
+func validateImportRow(row transport.ProductImportRow) error {
+	if strings.TrimSpace(row.SKU) == "" {
+		return fmt.Errorf("sku is required: %w", ErrValidation)
+	}
+	return validateProduct(row.Name, row.Description, row.Price)
+}
+
+func parseCSVImport(data []byte) ([]parsedRow, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read csv header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, col := range csvColumns {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("csv header must contain column %q", col)
+		}
+	}
+
+	var rows []parsedRow
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, parsedRow{line: line, err: err})
+				continue
+			}
+			return nil, err
+		}
+		rows = append(rows, csvRow(line, record, index))
+	}
+	return rows, nil
+}
+
+func csvRow(line int, record []string, index map[string]int) parsedRow {
+	field := func(name string) string {
+		i := index[name]
+		if i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	res := parsedRow{line: line}
+	res.row.SKU = field("sku")
+	res.row.Name = field("name")
+	res.row.Description = field("description")
+
+	price, err := strconv.ParseInt(field("price"), 10, 64)
+	if err != nil {
+		res.err = fmt.Errorf("invalid price: %w", ErrValidation)
+		return res
+	}
+	res.row.Price = price
+
+	if raw := field("count"); raw != "" {
+		count, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			res.err = fmt.Errorf("invalid count: %w", ErrValidation)
+			return res
+		}
+		res.row.Count = uint(count)
+	}
+
+	return res
+}
+
+func parseJSONLImport(data []byte) ([]parsedRow, error) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []parsedRow
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		res := parsedRow{line: line}
+		if err := json.Unmarshal(text, &res.row); err != nil {
+			res.err = fmt.Errorf("invalid json: %w", ErrValidation)
+		}
+		res.row.SKU = strings.TrimSpace(res.row.SKU)
+		rows = append(rows, res)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *CatalogService) ExportProducts(ctx context.Context, fn func(models.Product) error) error {
+	return s.Repo.EachProduct(ctx, func(p models.Product) error {
+		p.ApplyPrice(nil)
+		return fn(p)
+	})
+}
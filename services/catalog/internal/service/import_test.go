@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
+)
+
+func TestParseCSVImport(t *testing.T) {
+	t.Parallel()
+
+	data := "SKU, name ,description,price,count\n" +
+		"A-1,Lamp,Desk lamp,1999,5\n" +
+		"A-2,Chair,Office chair,abc,1\n" +
+		"A-3,Table,Oak table,5000,-1\n" +
+		"A-4,Rug,Wool rug,700\n" +
+		"A-5,\"Broken\n"
+
+	rows, err := parseCSVImport([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+
+	assert.Equal(t, parsedRow{line: 1, row: transport.ProductImportRow{
+		SKU: "A-1", Name: "Lamp", Description: "Desk lamp", Price: 1999, Count: 5,
+	}}, rows[0])
+	assert.ErrorIs(t, rows[1].err, ErrValidation, "invalid price")
+	assert.ErrorIs(t, rows[2].err, ErrValidation, "negative count")
+	require.NoError(t, rows[3].err, "count column may be short")
+	assert.Equal(t, uint(0), rows[3].row.Count)
+	assert.Error(t, rows[4].err, "malformed quoting")
+}
+
+func TestParseCSVImport_Header(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "missing column", data: "sku,name,description,price\nA-1,Lamp,Desk lamp,10\n"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseCSVImport([]byte(tt.data))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseJSONLImport(t *testing.T) {
+	t.Parallel()
+
+	data := `{"sku":" B-1 ","name":"Mug","description":"Tea mug","price":350,"count":12}
+
+{"sku":"B-2","name":
+{"sku":"B-3","name":"Cup","description":"Espresso cup","price":-1}
+`
+	rows, err := parseJSONLImport([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, rows, 3, "blank lines are skipped")
+
+	assert.Equal(t, 1, rows[0].line)
+	assert.Equal(t, "B-1", rows[0].row.SKU)
+	assert.Equal(t, uint(12), rows[0].row.Count)
+
+	assert.Equal(t, 3, rows[1].line)
+	assert.ErrorIs(t, rows[1].err, ErrValidation)
+
+	require.NoError(t, rows[2].err)
+	assert.ErrorIs(t, validateImportRow(rows[2].row), ErrValidation, "negative price")
+}
+
+func TestValidateImportRow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		row     transport.ProductImportRow
+		wantErr bool
+	}{
+		{name: "valid", row: transport.ProductImportRow{SKU: "C-1", Name: "Pen", Description: "Blue pen", Price: 0}},
+		{name: "blank sku", row: transport.ProductImportRow{SKU: "  ", Name: "Pen", Description: "Blue pen"}, wantErr: true},
+		{name: "no name", row: transport.ProductImportRow{SKU: "C-1", Description: "Blue pen"}, wantErr: true},
+		{name: "negative price", row: transport.ProductImportRow{SKU: "C-1", Name: "Pen", Description: "Blue pen", Price: -5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateImportRow(tt.row)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrValidation)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/repo"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (s *CatalogService) applyPrices(ctx context.Context, items []models.Product) error {
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, p := range items {
+		ids = append(ids, p.ID)
+	}
+
+	active, err := s.Repo.ActivePriceSchedules(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		var schedule *models.PriceSchedule
+		if ps, ok := active[items[i].ID]; ok {
+			schedule = &ps
+		}
+		items[i].ApplyPrice(schedule)
+	}
+	return nil
+}
+
+func (s *CatalogService) applyPrice(ctx context.Context, p *models.Product) error {
+	items := []models.Product{*p}
+	if err := s.applyPrices(ctx, items); err != nil {
+		return err
+	}
+	*p = items[0]
+	return nil
+}
+
+func (s *CatalogService) ListPriceHistory(ctx context.Context, productID uuid.UUID, offset, limit int) (int64, []models.PriceHistory, error) {
+	if _, err := s.GetProductAdmin(ctx, productID); err != nil {
+		return 0, nil, err
+	}
+	return s.Repo.ListPriceHistory(ctx, productID, offset, limit)
+}
+
+func (s *CatalogService) ListPriceSchedules(ctx context.Context, productID uuid.UUID) ([]models.PriceSchedule, error) {
+	if _, err := s.GetProductAdmin(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListPriceSchedules(ctx, productID)
+}
+
+func (s *CatalogService) CreatePriceSchedule(ctx context.Context, productID uuid.UUID, req transport.CreatePriceScheduleRequest) (*models.PriceSchedule, error) {
+	if req.Price == nil || *req.Price < 0 {
+		return nil, fmt.Errorf("price must be >= 0: %w", ErrValidation)
+	}
+	if req.StartsAt == nil {
+		return nil, fmt.Errorf("starts_at is required: %w", ErrValidation)
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return nil, fmt.Errorf("ends_at must be after starts_at: %w", ErrValidation)
+	}
+
+	ps := models.PriceSchedule{
+		ProductID: productID,
+		Price:     *req.Price,
+		StartsAt:  req.StartsAt.UTC(),
+		EndsAt:    req.EndsAt,
+	}
+
+	err := s.Repo.CreatePriceSchedule(ctx, &ps)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("product not found: %w", ErrNotFound)
+	}
+	if errors.Is(err, repo.ErrScheduleOverlap) {
+		return nil, fmt.Errorf("schedule overlaps an existing one: %w", ErrConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, productID)
+	s.publishProducts(ctx, productID)
+	return &ps, nil
+}
+
+func (s *CatalogService) DeletePriceSchedule(ctx context.Context, productID, scheduleID uuid.UUID) error {
+	err := s.Repo.DeletePriceSchedule(ctx, productID, scheduleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("price schedule not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, productID)
+	s.publishProducts(ctx, productID)
+	return nil
+}
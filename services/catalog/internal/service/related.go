@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/google/uuid"
+)
+
+const maxRelatedLimit = 50
+
+type BoughtTogetherConfig struct {
+	Interval   time.Duration
+	WindowDays int
+	MinOrders  int
+	PerProduct int
+}
+
+func clampRelatedLimit(limit int) int {
+	if limit <= 0 {
+		return 10
+	}
+	return min(limit, maxRelatedLimit)
+}
+
+func (s *CatalogService) RelatedProducts(ctx context.Context, id uuid.UUID, limit int) ([]models.Product, error) {
+	if _, err := s.GetProduct(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Repo.RelatedProducts(ctx, id, clampRelatedLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.Product, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.Product)
+	}
+	if err := s.applyPrices(ctx, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *CatalogService) BoughtTogether(ctx context.Context, id uuid.UUID, limit int) ([]models.Product, error) {
+	if _, err := s.GetProduct(ctx, id); err != nil {
+		return nil, err
+	}
+
+	items, err := s.Repo.BoughtTogether(ctx, id, clampRelatedLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPrices(ctx, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// RefreshBoughtTogether rebuilds the precomputed "bought together" table from
+// order co-occurrence.
+func (s *CatalogService) RefreshBoughtTogether(ctx context.Context, cfg BoughtTogetherConfig) error {
+	pairs, err := s.Orders.CoPurchases(ctx, cfg.WindowDays, cfg.MinOrders, cfg.PerProduct)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	now := time.Now().UTC()
+	rows := make([]models.BoughtTogether, 0, len(pairs))
+	for _, p := range pairs {
+		rows = append(rows, models.BoughtTogether{
+			ProductID: p.ProductID,
+			RelatedID: p.RelatedID,
+			Orders:    p.Orders,
+			UpdatedAt: now,
+		})
+	}
+
+	locked, err := s.Repo.ReplaceBoughtTogether(ctx, rows)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		slog.Default().Info("bought_together_skipped", "reason", "refresh running elsewhere")
+		return nil
+	}
+	slog.Default().Info("bought_together_refreshed", "pairs", len(rows))
+	return nil
+}
+
+// StartBoughtTogetherJob refreshes the table right away and then every
+// cfg.Interval until ctx is cancelled.
+func (s *CatalogService) StartBoughtTogetherJob(ctx context.Context, cfg BoughtTogetherConfig) {
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+
+		l := slog.Default().With("job", "bought_together")
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.RefreshBoughtTogether(ctx, cfg); err != nil && !errors.Is(err, context.Canceled) {
+				l.Error("bought_together_failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
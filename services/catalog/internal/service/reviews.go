@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/models"
+	"github.com/Skotchmaster/online_shop/services/catalog/internal/transport"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxReviewTitle = 200
+	maxReviewBody  = 5000
+)
+
+func validateReview(req transport.ReviewRequest) error {
+	if req.Rating != nil && (*req.Rating < 1 || *req.Rating > 5) {
+		return fmt.Errorf("rating must be between 1 and 5: %w", ErrValidation)
+	}
+	if req.Title != nil && utf8.RuneCountInString(*req.Title) > maxReviewTitle {
+		return fmt.Errorf("title is too long: %w", ErrValidation)
+	}
+	if req.Body != nil && utf8.RuneCountInString(*req.Body) > maxReviewBody {
+		return fmt.Errorf("body is too long: %w", ErrValidation)
+	}
+	return nil
+}
+
+// CreateReview accepts a review only from users with a paid order for the
+// product; the order service is asked on behalf of the caller's token.
+func (s *CatalogService) CreateReview(ctx context.Context, productID, userID uuid.UUID, accessToken string, req transport.ReviewRequest) (*models.Review, error) {
+	if req.Rating == nil {
+		return nil, fmt.Errorf("rating is required: %w", ErrValidation)
+	}
+	if err := validateReview(req); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Repo.GetProduct(ctx, productID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("product not found: %w", ErrNotFound)
+		}
+		return nil, err
+	}
+
+	purchased, err := s.Orders.HasPurchased(ctx, accessToken, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	if !purchased {
+		return nil, fmt.Errorf("product was not purchased: %w", ErrForbidden)
+	}
+
+	review := models.Review{
+		ProductID: productID,
+		UserID:    userID,
+		Rating:    *req.Rating,
+		Status:    models.ReviewStatusPending,
+	}
+	if req.Title != nil {
+		review.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Body != nil {
+		review.Body = strings.TrimSpace(*req.Body)
+	}
+
+	err = s.Repo.CreateReview(ctx, &review)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fmt.Errorf("review already exists: %w", ErrConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (s *CatalogService) UpdateMyReview(ctx context.Context, productID, userID uuid.UUID, req transport.ReviewRequest) (*models.Review, error) {
+	if err := validateReview(req); err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{}
+	if req.Rating != nil {
+		updates["rating"] = *req.Rating
+	}
+	if req.Title != nil {
+		updates["title"] = strings.TrimSpace(*req.Title)
+	}
+	if req.Body != nil {
+		updates["body"] = strings.TrimSpace(*req.Body)
+	}
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("empty patch: %w", ErrValidation)
+	}
+
+	review, err := s.Repo.UpdateUserReview(ctx, productID, userID, updates)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("review not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, productID)
+	return review, nil
+}
+
+func (s *CatalogService) DeleteMyReview(ctx context.Context, productID, userID uuid.UUID) error {
+	err := s.Repo.DeleteUserReview(ctx, productID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("review not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, productID)
+	return nil
+}
+
+func (s *CatalogService) ListProductReviews(ctx context.Context, productID uuid.UUID, offset, limit int) (int64, []models.Review, error) {
+	if _, err := s.Repo.GetProduct(ctx, productID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil, fmt.Errorf("product not found: %w", ErrNotFound)
+		}
+		return 0, nil, err
+	}
+	return s.Repo.ListProductReviews(ctx, productID, offset, limit)
+}
+
+func (s *CatalogService) ListReviewsAdmin(ctx context.Context, status string, offset, limit int) (int64, []models.Review, error) {
+	switch models.ReviewStatus(status) {
+	case "", models.ReviewStatusPending, models.ReviewStatusApproved, models.ReviewStatusRejected:
+	default:
+		return 0, nil, fmt.Errorf("unknown review status %q: %w", status, ErrValidation)
+	}
+	return s.Repo.ListReviewsAdmin(ctx, status, offset, limit)
+}
+
+func (s *CatalogService) ModerateReview(ctx context.Context, id uuid.UUID, req transport.ModerateReviewRequest) (*models.Review, error) {
+	status := models.ReviewStatus(req.Status)
+	if status != models.ReviewStatusApproved && status != models.ReviewStatusRejected {
+		return nil, fmt.Errorf("status must be approved or rejected: %w", ErrValidation)
+	}
+
+	review, err := s.Repo.ModerateReview(ctx, id, status, strings.TrimSpace(req.Note))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("review not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, review.ProductID)
+	return review, nil
+}
+
+func (s *CatalogService) DeleteReview(ctx context.Context, id uuid.UUID) error {
+	review, err := s.Repo.DeleteReview(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("review not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, review.ProductID)
+	return nil
+}
@@ -1,15 +1,73 @@
 package transport
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// OptionalTime tells an absent field apart from an explicit null, so PATCH can
+// clear a schedule with {"publish_at": null}.
+type OptionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (t *OptionalTime) UnmarshalJSON(data []byte) error {
+	t.Set = true
+	return json.Unmarshal(data, &t.Value)
+}
+
 type PatchProductRequest struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
-	Price       *int64  `json:"price"`
-	Count       *uint   `json:"count"`
+	SKU         *string      `json:"sku"`
+	Name        *string      `json:"name"`
+	Description *string      `json:"description"`
+	Price       *int64       `json:"price"`
+	Count       *uint        `json:"count"`
+	CountDelta  *int64       `json:"count_delta"`
+	Categories  *[]string    `json:"categories"`
+	Status      *string      `json:"status"`
+	PublishAt   OptionalTime `json:"publish_at"`
+	UnpublishAt OptionalTime `json:"unpublish_at"`
 }
 
 type CreateProductRequest struct {
+	SKU         *string    `json:"sku"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Price       int64      `json:"price"`
+	Count       uint       `json:"count"`
+	Categories  []string   `json:"categories"`
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+}
+
+type AdminProductFilter struct {
+	Status  string
+	Deleted string
+}
+
+type ProductImportRow struct {
+	SKU         string `json:"sku"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Price       int64  `json:"price"`
 	Count       uint   `json:"count"`
 }
+
+type CreatePriceScheduleRequest struct {
+	Price    *int64     `json:"price"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+type ReviewRequest struct {
+	Rating *int    `json:"rating"`
+	Title  *string `json:"title"`
+	Body   *string `json:"body"`
+}
+
+type ModerateReviewRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
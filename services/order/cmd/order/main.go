@@ -18,6 +18,8 @@ import (
 	pkgdb "github.com/Skotchmaster/online_shop/pkg/db"
 	"github.com/Skotchmaster/online_shop/pkg/logging"
 	loggingmw "github.com/Skotchmaster/online_shop/pkg/middleware/logging"
+	"github.com/Skotchmaster/online_shop/pkg/revocation"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
 
 	"github.com/Skotchmaster/online_shop/services/order/internal/config"
 	"github.com/Skotchmaster/online_shop/services/order/internal/httpserver"
@@ -54,10 +56,21 @@ func main() {
 
 	authclient := authclient.NewClient(cfg.AuthHTTPURL)
 
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+
+	verifier := tokens.NewVerifier(tokens.NewJWKSClient(cfg.JWKSURL, cfg.JWKSCacheTTL), cfg.JWTAccessSecret)
+	follower, err := revocation.Attach(eventsCtx, verifier, cfg.KafkaBrokers)
+	if err != nil {
+		log.Fatalf("token revocation: %v", err)
+	}
+	defer follower.Close()
+
 	httpserver.Register(e, &httpserver.Deps{
 		OrderHandler: handler,
-		JWTSecret:      cfg.JWTAccessSecret,
+		Verifier:       verifier,
 		AuthClient:     authclient,
+		InternalToken:  cfg.InternalAPIToken,
 	})
 
 	srv := &http.Server{
@@ -3,6 +3,7 @@ package httpserver
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/Skotchmaster/online_shop/pkg/logging"
 	"github.com/Skotchmaster/online_shop/pkg/util"
@@ -74,6 +75,27 @@ func(h *OrderHTTP) GetOrders(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 	}
 
+	if c.QueryParams().Has("cursor") {
+		limit := util.CursorLimit(util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize))
+		withTotal := util.ParseBoolDefault(c.QueryParam("with_total"), false)
+
+		page, err := h.Svc.ListOrdersCursor(ctx, userID, c.QueryParam("cursor"), limit, withTotal)
+		if err != nil {
+			if errors.Is(err, service.ErrValidation) {
+				l.Warn("get_orders_error", "status", 400, "reason", "invalid cursor", "error", err)
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+			}
+			l.Error("get_orders_error", "status", 500, "reason", "internal server error", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal server error")
+		}
+
+		l.Info("get_orders_success")
+		return c.JSON(http.StatusOK, map[string]any{
+			"data": page.Items,
+			"meta": page.Meta(limit),
+		})
+	}
+
 	page := util.ParseIntDefault(c.QueryParam("page"), 1)
     size := util.ParseIntDefault(c.QueryParam("size"), util.DefaultPageSize)
 
@@ -198,4 +220,54 @@ func (h *OrderHTTP) CancelOrder(c echo.Context) error {
 
 	l.Info("cancel_order_success")
 	return c.JSON(http.StatusOK, order)
-}
\ No newline at end of file
+}
+
+func (h *OrderHTTP) GetPurchased(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "order.get_purchased")
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		l.Warn("get_purchased_error", "status", 400, "reason", "invalid product id", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid product id")
+	}
+
+	userID, err := h.GetID(c)
+	if err != nil {
+		l.Warn("get_purchased_error", "status", 401, "reason", "unauthorized", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	purchased, err := h.Svc.HasPurchased(ctx, userID, productID)
+	if err != nil {
+		l.Error("get_purchased_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"product_id": productID,
+		"purchased":  purchased,
+	})
+}
+
+func (h *OrderHTTP) GetCoPurchases(c echo.Context) error {
+	ctx := c.Request().Context()
+	l := logging.FromContext(ctx).With("handler", "order.get_co_purchases")
+
+	days := util.ParseIntDefault(c.QueryParam("window_days"), 90)
+	minOrders := util.ParseIntDefault(c.QueryParam("min_orders"), 2)
+	perProduct := util.ParseIntDefault(c.QueryParam("per_product"), 20)
+
+	pairs, err := h.Svc.CoPurchases(ctx, time.Duration(days)*24*time.Hour, int64(minOrders), perProduct)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			l.Warn("get_co_purchases_error", "status", 400, "reason", "invalid params", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid params")
+		}
+		l.Error("get_co_purchases_error", "status", 500, "reason", "internal error", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	l.Info("get_co_purchases_success", "pairs", len(pairs))
+	return c.JSON(http.StatusOK, map[string]any{"data": pairs})
+}
@@ -5,27 +5,35 @@ import (
 
 	"github.com/Skotchmaster/online_shop/pkg/authclient"
 	middleware "github.com/Skotchmaster/online_shop/pkg/middleware/auth"
+	"github.com/Skotchmaster/online_shop/pkg/middleware/servicetoken"
+	"github.com/Skotchmaster/online_shop/pkg/permissions"
+	"github.com/Skotchmaster/online_shop/pkg/tokens"
 	"github.com/labstack/echo/v4"
 )
 
 type Deps struct {
 	OrderHandler *OrderHTTP
-	JWTSecret      []byte
+	Verifier       *tokens.Verifier
 	AuthClient     *authclient.Client
+	InternalToken  string
 }
 
 func Register(e *echo.Echo, d *Deps) {
 	e.GET("/health/live", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
 	e.GET("/health/ready", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
 
-	authMW := middleware.NewAutoRefreshMiddleware(d.JWTSecret, d.AuthClient)
+	authMW := middleware.NewAutoRefreshMiddleware(d.Verifier, d.AuthClient)
 
 	orders := e.Group("/orders", authMW.RequireAuth)
 	orders.GET("", d.OrderHandler.GetOrders)
+	orders.GET("/purchased/:product_id", d.OrderHandler.GetPurchased)
 	orders.GET("/:id", d.OrderHandler.GetOrder)
 	orders.POST("", d.OrderHandler.CreateOrder)
 	orders.POST("/:id/cancel", d.OrderHandler.CancelOrder)
 
-	admin := orders.Group("", authMW.RequireAdmin)
+	admin := orders.Group("", authMW.RequirePermission(permissions.OrdersUpdateStatus))
 	admin.PATCH("/:id", d.OrderHandler.UpdateOrder)
+
+	internal := e.Group("/internal/orders", servicetoken.Require(d.InternalToken))
+	internal.GET("/co-purchases", d.OrderHandler.GetCoPurchases)
 }
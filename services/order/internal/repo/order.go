@@ -3,7 +3,9 @@ package repo
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/Skotchmaster/online_shop/pkg/util"
 	"github.com/Skotchmaster/online_shop/services/order/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -32,6 +34,33 @@ func (r *GormRepo) ListOrders(ctx context.Context, userID uuid.UUID, limit, offs
 	return orders, nil
 }
 
+func (r *GormRepo) ListOrdersAfter(ctx context.Context, userID uuid.UUID, cur *util.Cursor, limit int) ([]models.Order, error) {
+	q := r.DB.WithContext(ctx).Model(&models.Order{}).Where("user_id = ?", userID)
+
+	switch {
+	case cur.Backward():
+		q = q.Where("(created_at, id) > (?, ?)", *cur.Time, cur.ID).Order("created_at ASC, id ASC")
+	case cur != nil:
+		q = q.Where("(created_at, id) < (?, ?)", *cur.Time, cur.ID).Order("created_at DESC, id DESC")
+	default:
+		q = q.Order("created_at DESC, id DESC")
+	}
+
+	orders := make([]models.Order, 0, limit+1)
+	if err := q.Limit(limit + 1).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *GormRepo) CountOrders(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var total int64
+	if err := r.DB.WithContext(ctx).Model(&models.Order{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
 func(r *GormRepo) GetOrder(ctx context.Context, id uuid.UUID) (*models.Order, error) {
 	var order models.Order
 	if err := r.DB.WithContext(ctx).Preload("Items").Where("ID = ?", id).First(&order).Error; err != nil{
@@ -85,4 +114,47 @@ func(r *GormRepo) CancelOrder(ctx context.Context, id uuid.UUID, status models.O
 	}
 
 	return r.GetOrder(ctx, id)
-}
\ No newline at end of file
+}
+
+func (r *GormRepo) HasPurchased(ctx context.Context, userID, productID uuid.UUID, statuses []models.OrderStatus) (bool, error) {
+	var found bool
+	err := r.DB.WithContext(ctx).Raw(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM orders o
+			JOIN order_items i ON i.order_id = o.id
+			WHERE o.user_id = ? AND i.product_id = ? AND o.status IN ?
+		)`, userID, productID, statuses).Scan(&found).Error
+	return found, err
+}
+
+type CoPurchase struct {
+	ProductID uuid.UUID `json:"product_id"`
+	RelatedID uuid.UUID `json:"related_id"`
+	Orders    int64     `json:"orders"`
+}
+
+// CoPurchases counts, for every product, the orders it shares with other
+// products and keeps the perProduct strongest pairs.
+func (r *GormRepo) CoPurchases(ctx context.Context, statuses []models.OrderStatus, since time.Time, minOrders int64, perProduct int) ([]CoPurchase, error) {
+	var pairs []CoPurchase
+	err := r.DB.WithContext(ctx).Raw(`
+		SELECT product_id, related_id, orders
+		FROM (
+			SELECT a.product_id, b.product_id AS related_id, count(DISTINCT a.order_id) AS orders,
+			       row_number() OVER (
+			         PARTITION BY a.product_id
+			         ORDER BY count(DISTINCT a.order_id) DESC, b.product_id
+			       ) AS rn
+			FROM order_items a
+			JOIN order_items b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+			JOIN orders o ON o.id = a.order_id
+			WHERE o.status IN ? AND o.created_at >= ?
+			GROUP BY a.product_id, b.product_id
+			HAVING count(DISTINCT a.order_id) >= ?
+		) ranked
+		WHERE rn <= ?
+		ORDER BY product_id, orders DESC`, statuses, since, minOrders, perProduct).
+		Scan(&pairs).Error
+	return pairs, err
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/Skotchmaster/online_shop/pkg/util"
 	"github.com/Skotchmaster/online_shop/services/order/internal/models"
 	"github.com/Skotchmaster/online_shop/services/order/internal/repo"
 	"github.com/Skotchmaster/online_shop/services/order/internal/transport"
@@ -88,6 +90,35 @@ func (svc *OrderService) ListOrders(ctx context.Context, userID uuid.UUID, limit
 	return svc.Repo.ListOrders(ctx, userID, limit, offset)
 }
 
+func (svc *OrderService) ListOrdersCursor(ctx context.Context, userID uuid.UUID, rawCursor string, limit int, withTotal bool) (*util.CursorPage[models.Order], error) {
+	cur, err := util.DecodeCursor(rawCursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if cur != nil && cur.Time == nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, util.ErrInvalidCursor)
+	}
+
+	rows, err := svc.Repo.ListOrdersAfter(ctx, userID, cur, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := util.NewCursorPage(rows, limit, cur, func(o models.Order) util.Cursor {
+		return util.Cursor{ID: o.ID, Time: &o.CreatedAt}
+	})
+
+	if withTotal {
+		total, err := svc.Repo.CountOrders(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
 func (svc *OrderService) GetOrder(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*models.Order, error) {
 	order, err := svc.Repo.GetOrder(ctx, id)
 	if err != nil {
@@ -161,3 +192,34 @@ func (svc *OrderService) CancelOrder(ctx context.Context, id uuid.UUID, userID u
 
 	return updated, err
 }
+
+// purchasedStatuses are the order states that prove the user paid for an item.
+var purchasedStatuses = []models.OrderStatus{
+	models.OrderStatusPaid,
+	models.OrderStatusShipped,
+	models.OrderStatusDone,
+}
+
+func (svc *OrderService) HasPurchased(ctx context.Context, userID, productID uuid.UUID) (bool, error) {
+	return svc.Repo.HasPurchased(ctx, userID, productID, purchasedStatuses)
+}
+
+const (
+	maxCoPurchaseWindow     = 365 * 24 * time.Hour
+	maxCoPurchasePerProduct = 50
+)
+
+func (svc *OrderService) CoPurchases(ctx context.Context, window time.Duration, minOrders int64, perProduct int) ([]repo.CoPurchase, error) {
+	if window < 24*time.Hour || window > maxCoPurchaseWindow {
+		return nil, fmt.Errorf("%w: window must be between 1 and 365 days", ErrValidation)
+	}
+	if minOrders < 1 {
+		minOrders = 1
+	}
+	if perProduct < 1 || perProduct > maxCoPurchasePerProduct {
+		perProduct = maxCoPurchasePerProduct
+	}
+
+	since := time.Now().UTC().Add(-window)
+	return svc.Repo.CoPurchases(ctx, purchasedStatuses, since, minOrders, perProduct)
+}